@@ -2,9 +2,13 @@ package all
 
 import (
 	_ "github.com/akmistry/cloud-util/aws"
+	_ "github.com/akmistry/cloud-util/blob_util"
+	_ "github.com/akmistry/cloud-util/cache"
+	_ "github.com/akmistry/cloud-util/crypto"
 	_ "github.com/akmistry/cloud-util/gcp"
 	_ "github.com/akmistry/cloud-util/local"
 	_ "github.com/akmistry/cloud-util/rpc"
+	_ "github.com/akmistry/cloud-util/store_util"
 )
 
-// Meta-package to import all cloud implementations
+// Meta-package to import all cloud implementations and store wrappers
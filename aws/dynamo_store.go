@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -19,6 +21,27 @@ const (
 	scheme = "aws"
 
 	queryParamRegion = "r"
+
+	// DynamoDB limits on the number of items in a single batch request.
+	maxBatchGetItems   = 100
+	maxBatchWriteItems = 25
+	// DynamoDB limit on the number of items in a TransactWriteItems request.
+	maxTxnItems = 100
+
+	// Unprocessed batch items are retried with exponential backoff, as
+	// recommended by AWS.
+	unprocessedRetryDelay    = 50 * time.Millisecond
+	maxUnprocessedRetryDelay = 5 * time.Second
+	maxUnprocessedRetries    = 10
+
+	// DynamoDB limits on item and partition key sizes.
+	maxItemSize = 400 * 1024
+	maxKeySize  = 2048
+
+	// Attribute holding the expiry time of an item, in seconds since the Unix
+	// epoch. Enabling DynamoDB TTL on this attribute lets DynamoDB delete
+	// expired items. Until then, they are hidden from reads.
+	ttlAttribute = "ttl"
 )
 
 type DynamoStore struct {
@@ -27,6 +50,12 @@ type DynamoStore struct {
 }
 
 var _ = (cloud.UnorderedStore)((*DynamoStore)(nil))
+var _ = (cloud.AtomicUnorderedStore)((*DynamoStore)(nil))
+var _ = (cloud.AtomicContextStore)((*DynamoStore)(nil))
+var _ = (cloud.BatchStore)((*DynamoStore)(nil))
+var _ = (cloud.ContextBatchStore)((*DynamoStore)(nil))
+var _ = (cloud.TxnStore)((*DynamoStore)(nil))
+var _ = (cloud.CapabilitiesProvider)((*DynamoStore)(nil))
 
 func init() {
 	cloud.RegisterStoreScheme(scheme, openStore)
@@ -80,6 +109,20 @@ func NewDynamoStore(cfg aws.Config, tableName string) (*DynamoStore, error) {
 	return s, nil
 }
 
+func (s *DynamoStore) Capabilities() cloud.Capabilities {
+	return cloud.Capabilities{
+		Atomic: true,
+		TTL:    true,
+		Batch:  true,
+		Txn:    true,
+		// The item also contains the key, version, expiry and attribute names.
+		MaxValueSize: maxItemSize - maxKeySize - 128,
+		MaxKeySize:   maxKeySize,
+		// All reads are consistent reads.
+		Consistency: cloud.ConsistencyStrong,
+	}
+}
+
 func (s *DynamoStore) makeKey(key string) map[string]types.AttributeValue {
 	v, err := attributevalue.Marshal(key)
 	if err != nil {
@@ -90,8 +133,8 @@ func (s *DynamoStore) makeKey(key string) map[string]types.AttributeValue {
 	}
 }
 
-func (s *DynamoStore) Get(key string) (*cloud.KVPair, error) {
-	out, err := s.client.GetItem(context.TODO(),
+func (s *DynamoStore) GetContext(ctx context.Context, key string) (*cloud.KVPair, error) {
+	out, err := s.client.GetItem(ctx,
 		&dynamodb.GetItemInput{
 			TableName:      aws.String(s.tableName),
 			ConsistentRead: aws.Bool(true),
@@ -102,20 +145,84 @@ func (s *DynamoStore) Get(key string) (*cloud.KVPair, error) {
 		if errors.As(err, &notFoundErr) {
 			return nil, cloud.ErrKeyNotFound
 		}
-		return nil, err
-	} else if out.Item == nil {
+		return nil, wrapError("get", key, err)
+	} else if out.Item == nil || itemExpired(out.Item, time.Now()) {
 		return nil, cloud.ErrKeyNotFound
 	}
-	valAttr := out.Item["value"]
-	if val, ok := valAttr.(*types.AttributeValueMemberB); ok {
-		return &cloud.KVPair{Key: key, Value: val.Value}, nil
+	return itemToPair(key, out.Item)
+}
+
+func itemExpired(item map[string]types.AttributeValue, now time.Time) bool {
+	ttlAttr, ok := item[ttlAttribute]
+	if !ok {
+		return false
 	}
-	return nil, fmt.Errorf("Unable to get value")
+	var expiry int64
+	err := attributevalue.Unmarshal(ttlAttr, &expiry)
+	if err != nil {
+		// Not written by this package. Treat as never expiring.
+		return false
+	}
+	return now.Unix() >= expiry
 }
 
-func (s *DynamoStore) Exists(key string) (bool, error) {
+// Returns the expiry attribute value for a write with options, rounded up to
+// the next second so that items never expire early. Returns 0 if the write
+// does not expire.
+func expirySeconds(options *cloud.WriteOptions) int64 {
+	expiry := cloud.Expiry(options)
+	if expiry.IsZero() {
+		return 0
+	}
+	return expiry.Add(time.Second - 1).Unix()
+}
+
+func itemToPair(key string, item map[string]types.AttributeValue) (*cloud.KVPair, error) {
+	val, ok := item["value"].(*types.AttributeValueMemberB)
+	if !ok {
+		return nil, fmt.Errorf("cloud/aws: item %s has no binary value", key)
+	}
+	pair := &cloud.KVPair{Key: key, Value: val.Value}
+	// Items written before versioning was introduced have no version.
+	if verAttr, ok := item["version"]; ok {
+		err := attributevalue.Unmarshal(verAttr, &pair.LastIndex)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pair, nil
+}
+
+// Returns a version greater than prev. Versions are time-based so that writes
+// which don't read the previous version (i.e. Put), and keys which are deleted
+// and re-created, are still very likely to get a new version.
+func nextVersion(prev uint64) uint64 {
+	return max(prev+1, uint64(time.Now().UnixNano()))
+}
+
+func (s *DynamoStore) makeItem(key string, value []byte, version uint64, expiry int64) map[string]types.AttributeValue {
+	item := map[string]interface{}{
+		"key":     key,
+		"value":   value,
+		"version": version,
+	}
+	if expiry != 0 {
+		item[ttlAttribute] = expiry
+	}
+	itemAttr, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		panic(err)
+	}
+	return itemAttr
+}
+
+func (s *DynamoStore) Get(key string) (*cloud.KVPair, error) {
+	return s.GetContext(context.Background(), key)
+}
+
+func (s *DynamoStore) ExistsContext(ctx context.Context, key string) (bool, error) {
 	// TODO: Use an actual "exists" method.
-	_, err := s.Get(key)
+	_, err := s.GetContext(ctx, key)
 	if err == cloud.ErrKeyNotFound {
 		return false, nil
 	} else if err != nil {
@@ -124,27 +231,435 @@ func (s *DynamoStore) Exists(key string) (bool, error) {
 	return true, nil
 }
 
+func (s *DynamoStore) Exists(key string) (bool, error) {
+	return s.ExistsContext(context.Background(), key)
+}
+
+func (s *DynamoStore) PutContext(ctx context.Context, key string, value []byte, options *cloud.WriteOptions) error {
+	_, err := s.client.PutItem(ctx,
+		&dynamodb.PutItemInput{
+			TableName: aws.String(s.tableName),
+			Item:      s.makeItem(key, value, nextVersion(0), expirySeconds(options)),
+		})
+	return wrapError("put", key, err)
+}
+
 func (s *DynamoStore) Put(key string, value []byte, options *cloud.WriteOptions) error {
-	item := map[string]interface{}{
-		"key":   key,
-		"value": value,
+	return s.PutContext(context.Background(), key, value, options)
+}
+
+func (s *DynamoStore) DeleteContext(ctx context.Context, key string) error {
+	_, err := s.client.DeleteItem(ctx,
+		&dynamodb.DeleteItemInput{
+			TableName: aws.String(s.tableName),
+			Key:       s.makeKey(key),
+		})
+	return wrapError("delete", key, err)
+}
+
+func (s *DynamoStore) Delete(key string) error {
+	return s.DeleteContext(context.Background(), key)
+}
+
+func (s *DynamoStore) GetMulti(keys []string) ([]*cloud.KVPair, error) {
+	return s.GetMultiContext(context.Background(), keys)
+}
+
+func (s *DynamoStore) GetMultiContext(ctx context.Context, keys []string) ([]*cloud.KVPair, error) {
+	// BatchGetItem rejects requests containing duplicate keys.
+	uniqueKeys := make([]string, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if !seen[k] {
+			seen[k] = true
+			uniqueKeys = append(uniqueKeys, k)
+		}
 	}
-	itemAttr, err := attributevalue.MarshalMap(item)
+
+	now := time.Now()
+	found := make(map[string]*cloud.KVPair, len(uniqueKeys))
+	for start := 0; start < len(uniqueKeys); start += maxBatchGetItems {
+		chunk := uniqueKeys[start:min(start+maxBatchGetItems, len(uniqueKeys))]
+		reqKeys := make([]map[string]types.AttributeValue, len(chunk))
+		for i, k := range chunk {
+			reqKeys[i] = s.makeKey(k)
+		}
+		requestItems := map[string]types.KeysAndAttributes{
+			s.tableName: {
+				Keys:           reqKeys,
+				ConsistentRead: aws.Bool(true),
+			},
+		}
+
+		for attempt := 0; len(requestItems) > 0; attempt++ {
+			if attempt > 0 {
+				err := waitUnprocessed(ctx, "get", attempt)
+				if err != nil {
+					return nil, err
+				}
+			}
+			out, err := s.client.BatchGetItem(ctx,
+				&dynamodb.BatchGetItemInput{
+					RequestItems: requestItems,
+				})
+			if err != nil {
+				return nil, wrapError("get", "", err)
+			}
+			for _, item := range out.Responses[s.tableName] {
+				if itemExpired(item, now) {
+					continue
+				}
+				var key string
+				err = attributevalue.Unmarshal(item["key"], &key)
+				if err != nil {
+					return nil, err
+				}
+				pair, err := itemToPair(key, item)
+				if err != nil {
+					return nil, err
+				}
+				found[key] = pair
+			}
+
+			requestItems = out.UnprocessedKeys
+		}
+	}
+
+	pairs := make([]*cloud.KVPair, len(keys))
+	for i, k := range keys {
+		if p, ok := found[k]; ok {
+			pairs[i] = &cloud.KVPair{Key: k, Value: p.Value, LastIndex: p.LastIndex}
+		}
+	}
+	return pairs, nil
+}
+
+// Waits before retrying unprocessed batch items. Returns an error if ctx is
+// done, or the retries are exhausted.
+func waitUnprocessed(ctx context.Context, op string, attempt int) error {
+	if attempt > maxUnprocessedRetries {
+		return cloud.NewError(op, "", cloud.KindThrottled,
+			fmt.Errorf("batch items unprocessed after %d retries", maxUnprocessedRetries))
+	}
+	t := time.NewTimer(min(unprocessedRetryDelay<<(attempt-1), maxUnprocessedRetryDelay))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (s *DynamoStore) batchWrite(ctx context.Context, reqs []types.WriteRequest) error {
+	for start := 0; start < len(reqs); start += maxBatchWriteItems {
+		requestItems := map[string][]types.WriteRequest{
+			s.tableName: reqs[start:min(start+maxBatchWriteItems, len(reqs))],
+		}
+		for attempt := 0; len(requestItems) > 0; attempt++ {
+			if attempt > 0 {
+				err := waitUnprocessed(ctx, "write", attempt)
+				if err != nil {
+					return err
+				}
+			}
+			out, err := s.client.BatchWriteItem(ctx,
+				&dynamodb.BatchWriteItemInput{
+					RequestItems: requestItems,
+				})
+			if err != nil {
+				return wrapError("write", "", err)
+			}
+
+			requestItems = out.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+func (s *DynamoStore) PutMulti(pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	return s.PutMultiContext(context.Background(), pairs, options)
+}
+
+func (s *DynamoStore) PutMultiContext(ctx context.Context, pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	// BatchWriteItem rejects requests which write the same key more than once,
+	// so only write the last value for each key.
+	expiry := expirySeconds(options)
+	last := make(map[string]int, len(pairs))
+	for i, p := range pairs {
+		last[p.Key] = i
+	}
+	reqs := make([]types.WriteRequest, 0, len(last))
+	for i, p := range pairs {
+		if last[p.Key] != i {
+			continue
+		}
+		reqs = append(reqs, types.WriteRequest{
+			PutRequest: &types.PutRequest{Item: s.makeItem(p.Key, p.Value, nextVersion(0), expiry)},
+		})
+	}
+	return s.batchWrite(ctx, reqs)
+}
+
+func (s *DynamoStore) DeleteMulti(keys []string) error {
+	return s.DeleteMultiContext(context.Background(), keys)
+}
+
+func (s *DynamoStore) DeleteMultiContext(ctx context.Context, keys []string) error {
+	seen := make(map[string]bool, len(keys))
+	reqs := make([]types.WriteRequest, 0, len(keys))
+	for _, k := range keys {
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		reqs = append(reqs, types.WriteRequest{
+			DeleteRequest: &types.DeleteRequest{Key: s.makeKey(k)},
+		})
+	}
+	return s.batchWrite(ctx, reqs)
+}
+
+// Returns a condition expression, and its names and values, which checks
+// that the stored item matches previous.
+func previousCondition(previous *cloud.KVPair) (string, map[string]string, map[string]types.AttributeValue) {
+	if previous.LastIndex != 0 {
+		ver, err := attributevalue.Marshal(previous.LastIndex)
+		if err != nil {
+			panic(err)
+		}
+		return "#ver = :prev",
+			map[string]string{"#ver": "version"},
+			map[string]types.AttributeValue{":prev": ver}
+	}
+	return "#val = :prev",
+		map[string]string{"#val": "value"},
+		map[string]types.AttributeValue{":prev": &types.AttributeValueMemberB{Value: previous.Value}}
+}
+
+// Adds the names and values used by absentCondition and liveCondition.
+func addExistenceNames(names map[string]string, values map[string]types.AttributeValue) {
+	names["#key"] = "key"
+	names["#ttl"] = ttlAttribute
+	values[":now"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)}
+}
+
+// Returns a condition expression which is true if the item does not exist or
+// has expired.
+func absentCondition(names map[string]string, values map[string]types.AttributeValue) string {
+	addExistenceNames(names, values)
+	return "(attribute_not_exists(#key) OR #ttl <= :now)"
+}
+
+// Returns a condition expression which is true if the item exists and has not
+// expired.
+func liveCondition(names map[string]string, values map[string]types.AttributeValue) string {
+	addExistenceNames(names, values)
+	return "(attribute_exists(#key) AND (attribute_not_exists(#ttl) OR #ttl > :now))"
+}
+
+// Translates a failed condition check into ErrKeyNotFound or ErrKeyModified,
+// based on the old item returned by DynamoDB.
+func conditionError(op, key string, err error) error {
+	var condErr *types.ConditionalCheckFailedException
+	if !errors.As(err, &condErr) {
+		return wrapError(op, key, err)
+	} else if condErr.Item == nil || itemExpired(condErr.Item, time.Now()) {
+		return cloud.ErrKeyNotFound
+	}
+	return cloud.ErrKeyModified
+}
+
+func (s *DynamoStore) AtomicPutContext(ctx context.Context, key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+	input := &dynamodb.PutItemInput{
+		TableName:                           aws.String(s.tableName),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}
+
+	var version uint64
+	if previous == nil {
+		version = nextVersion(0)
+		names := make(map[string]string)
+		values := make(map[string]types.AttributeValue)
+		input.ConditionExpression = aws.String(absentCondition(names, values))
+		input.ExpressionAttributeNames = names
+		input.ExpressionAttributeValues = values
+	} else {
+		version = nextVersion(previous.LastIndex)
+		cond, names, values := previousCondition(previous)
+		input.ConditionExpression = aws.String(liveCondition(names, values) + " AND " + cond)
+		input.ExpressionAttributeNames = names
+		input.ExpressionAttributeValues = values
+	}
+	input.Item = s.makeItem(key, value, version, expirySeconds(options))
+
+	_, err := s.client.PutItem(ctx, input)
 	if err != nil {
-		panic(err)
+		err = conditionError("put", key, err)
+		if previous == nil && err == cloud.ErrKeyModified {
+			err = cloud.ErrKeyExists
+		}
+		return false, nil, err
 	}
-	_, err = s.client.PutItem(context.TODO(),
-		&dynamodb.PutItemInput{
-			TableName: aws.String(s.tableName),
-			Item:      itemAttr,
+
+	updated := &cloud.KVPair{
+		Key:       key,
+		Value:     value,
+		LastIndex: version,
+	}
+	return true, updated, nil
+}
+
+func (s *DynamoStore) AtomicPut(key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+	return s.AtomicPutContext(context.Background(), key, value, previous, options)
+}
+
+func (s *DynamoStore) AtomicDeleteContext(ctx context.Context, key string, previous *cloud.KVPair) (bool, error) {
+	if previous == nil {
+		return false, cloud.ErrPreviousNotSpecified
+	}
+
+	cond, names, values := previousCondition(previous)
+	_, err := s.client.DeleteItem(ctx,
+		&dynamodb.DeleteItemInput{
+			TableName:                           aws.String(s.tableName),
+			Key:                                 s.makeKey(key),
+			ConditionExpression:                 aws.String(liveCondition(names, values) + " AND " + cond),
+			ExpressionAttributeNames:            names,
+			ExpressionAttributeValues:           values,
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
 		})
 	if err != nil {
+		return false, conditionError("delete", key, err)
+	}
+	return true, nil
+}
+
+func (s *DynamoStore) AtomicDelete(key string, previous *cloud.KVPair) (bool, error) {
+	return s.AtomicDeleteContext(context.Background(), key, previous)
+}
+
+// Returns a condition expression, and its names and values, which checks that
+// the stored item matches c.
+func compareCondition(c *cloud.TxnCompare) (string, map[string]string, map[string]types.AttributeValue) {
+	if c.Previous == nil {
+		names := make(map[string]string)
+		values := make(map[string]types.AttributeValue)
+		return absentCondition(names, values), names, values
+	}
+	cond, names, values := previousCondition(c.Previous)
+	return liveCondition(names, values) + " AND " + cond, names, values
+}
+
+// Returns true if a transaction was cancelled because a condition failed, or
+// because it conflicted with another transaction.
+func txnConditionFailed(err error) bool {
+	var cancelErr *types.TransactionCanceledException
+	if !errors.As(err, &cancelErr) {
+		return false
+	}
+	for _, r := range cancelErr.CancellationReasons {
+		code := aws.ToString(r.Code)
+		if code == "ConditionalCheckFailed" || code == "TransactionConflict" {
+			return true
+		}
+	}
+	return false
+}
+
+// Comparisons of keys which are also written are made conditions of the
+// write, since DynamoDB does not allow multiple actions on the same item in a
+// transaction.
+func (s *DynamoStore) TxnContext(ctx context.Context, txn *cloud.Txn) error {
+	if err := txn.Validate(); err != nil {
 		return err
 	}
-	return nil
+
+	compares := make(map[string]*cloud.TxnCompare, len(txn.Compares))
+	for i := range txn.Compares {
+		compares[txn.Compares[i].Key] = &txn.Compares[i]
+	}
+	// Each key written or compared is a separate item.
+	numItems := len(compares)
+	for _, op := range txn.Ops {
+		if compares[op.Key] == nil {
+			numItems++
+		}
+	}
+	if numItems > maxTxnItems {
+		return fmt.Errorf("cloud/aws: txn with %d keys exceeds DynamoDB limit of %d", numItems, maxTxnItems)
+	}
+
+	var items []types.TransactWriteItem
+	for _, op := range txn.Ops {
+		c := compares[op.Key]
+		delete(compares, op.Key)
+
+		var cond *string
+		var names map[string]string
+		var values map[string]types.AttributeValue
+		if c != nil {
+			var expr string
+			expr, names, values = compareCondition(c)
+			cond = aws.String(expr)
+		}
+
+		if op.Delete {
+			items = append(items, types.TransactWriteItem{
+				Delete: &types.Delete{
+					TableName:                 aws.String(s.tableName),
+					Key:                       s.makeKey(op.Key),
+					ConditionExpression:       cond,
+					ExpressionAttributeNames:  names,
+					ExpressionAttributeValues: values,
+				},
+			})
+			continue
+		}
+
+		version := nextVersion(0)
+		if c != nil && c.Previous != nil {
+			version = nextVersion(c.Previous.LastIndex)
+		}
+		items = append(items, types.TransactWriteItem{
+			Put: &types.Put{
+				TableName:                 aws.String(s.tableName),
+				Item:                      s.makeItem(op.Key, op.Value, version, expirySeconds(op.Options)),
+				ConditionExpression:       cond,
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+			},
+		})
+	}
+	for _, c := range txn.Compares {
+		if compares[c.Key] == nil {
+			// Made a condition of a write.
+			continue
+		}
+		cond, names, values := compareCondition(&c)
+		items = append(items, types.TransactWriteItem{
+			ConditionCheck: &types.ConditionCheck{
+				TableName:                 aws.String(s.tableName),
+				Key:                       s.makeKey(c.Key),
+				ConditionExpression:       aws.String(cond),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+			},
+		})
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	_, err := s.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	if txnConditionFailed(err) {
+		return cloud.ErrKeyModified
+	}
+	return wrapError("txn", "", err)
 }
 
-func (s *DynamoStore) Delete(key string) error {
-	panic("Unimplemented")
-	return nil
+func (s *DynamoStore) Txn(txn *cloud.Txn) error {
+	return s.TxnContext(context.Background(), txn)
 }
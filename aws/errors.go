@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"gocloud.dev/gcerrors"
+
+	"github.com/akmistry/cloud-util"
+)
+
+// Classifies errors from the AWS SDK, and from gocloud.
+func classifyError(err error) cloud.ErrorKind {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return cloud.KindCanceled
+	}
+
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		switch ae.ErrorCode() {
+		case "NoSuchKey", "NotFound", "NoSuchBucket", "ResourceNotFoundException":
+			return cloud.KindNotFound
+		case "BucketAlreadyExists", "BucketAlreadyOwnedByYou", "ResourceInUseException":
+			return cloud.KindAlreadyExists
+		case "PreconditionFailed", "ConditionalRequestConflict", "ConditionalCheckFailedException":
+			return cloud.KindPreconditionFailed
+		case "SlowDown", "Throttling", "ThrottlingException", "RequestLimitExceeded",
+			"ProvisionedThroughputExceededException", "TooManyRequestsException":
+			return cloud.KindThrottled
+		case "ServiceUnavailable", "InternalError", "InternalServerError", "RequestTimeout":
+			return cloud.KindUnavailable
+		case "AccessDenied", "AccessDeniedException", "Forbidden", "InvalidAccessKeyId",
+			"SignatureDoesNotMatch", "UnrecognizedClientException":
+			return cloud.KindPermissionDenied
+		}
+	}
+
+	var re *smithyhttp.ResponseError
+	if errors.As(err, &re) {
+		switch re.HTTPStatusCode() {
+		case http.StatusNotFound:
+			return cloud.KindNotFound
+		case http.StatusPreconditionFailed:
+			return cloud.KindPreconditionFailed
+		case http.StatusTooManyRequests:
+			return cloud.KindThrottled
+		case http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return cloud.KindUnavailable
+		case http.StatusForbidden, http.StatusUnauthorized:
+			return cloud.KindPermissionDenied
+		}
+	}
+
+	switch gcerrors.Code(err) {
+	case gcerrors.NotFound:
+		return cloud.KindNotFound
+	case gcerrors.AlreadyExists:
+		return cloud.KindAlreadyExists
+	case gcerrors.FailedPrecondition:
+		return cloud.KindPreconditionFailed
+	case gcerrors.ResourceExhausted:
+		return cloud.KindThrottled
+	case gcerrors.PermissionDenied:
+		return cloud.KindPermissionDenied
+	case gcerrors.Canceled, gcerrors.DeadlineExceeded:
+		return cloud.KindCanceled
+	}
+	return cloud.KindUnknown
+}
+
+func wrapError(op, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return cloud.NewError(op, key, classifyError(err), err)
+}
+
+// Returns true if a failed blob operation should be retried. Errors which
+// can't be classified are retried, since they're often network errors.
+func shouldRetry(err error) bool {
+	switch classifyError(err) {
+	case cloud.KindUnknown, cloud.KindThrottled, cloud.KindUnavailable:
+		return true
+	}
+	return false
+}
@@ -7,17 +7,19 @@ import (
 	"io"
 	"log"
 	"net/url"
-	"os"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 
 	"gocloud.dev/blob"
 	"gocloud.dev/blob/s3blob"
-	"gocloud.dev/gcerrors"
 	"golang.org/x/sync/semaphore"
 
 	"github.com/akmistry/cloud-util"
@@ -30,6 +32,7 @@ var (
 
 const (
 	maxPendingFetches = 512
+	maxObjectSize     = 5 << 40
 
 	s3Scheme = "s3"
 )
@@ -106,19 +109,82 @@ func NewS3Store(endpoint, accessKey, secret, region, bucket string) *S3Store {
 	}
 }
 
-func (s *S3Store) Size(name string) (int64, error) {
+var _ = (cloud.BlobStore)((*S3Store)(nil))
+var _ = (cloud.ContextBlobStore)((*S3Store)(nil))
+var _ = (cloud.Lister)((*S3Store)(nil))
+var _ = (cloud.BlobCapabilitiesProvider)((*S3Store)(nil))
+var _ = (cloud.AttributesBlobStore)((*S3Store)(nil))
+var _ = (cloud.PageLister)((*S3Store)(nil))
+var _ = (cloud.Copier)((*S3Store)(nil))
+
+func (s *S3Store) Capabilities() cloud.BlobCapabilities {
+	return cloud.BlobCapabilities{
+		List:          true,
+		Attributes:    true,
+		Preconditions: true,
+		MaxBlobSize:   maxObjectSize,
+		Consistency:   cloud.ConsistencyStrong,
+	}
+}
+
+// Sleeps for the retry interval, returning early with an error if the
+// context is done.
+func retrySleep(ctx context.Context) error {
+	t := time.NewTimer(time.Second)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *S3Store) SizeContext(ctx context.Context, name string) (int64, error) {
 	for {
-		attr, err := s.bucket.Attributes(context.TODO(), name)
+		attr, err := s.bucket.Attributes(ctx, name)
 		if err == nil {
 			return attr.Size, nil
-		} else if gcerrors.Code(err) == gcerrors.NotFound {
-			return 0, os.ErrNotExist
+		} else if !shouldRetry(err) {
+			return 0, wrapError("size", name, err)
 		}
 		log.Printf("cloud-s3: error sizing blob %s: %v", name, err)
-		time.Sleep(time.Second)
+		if err := retrySleep(ctx); err != nil {
+			return 0, err
+		}
 	}
 }
 
+func (s *S3Store) Size(name string) (int64, error) {
+	return s.SizeContext(context.Background(), name)
+}
+
+func (s *S3Store) AttributesContext(ctx context.Context, name string) (*cloud.BlobAttributes, error) {
+	for {
+		attr, err := s.bucket.Attributes(ctx, name)
+		if err == nil {
+			return &cloud.BlobAttributes{
+				Size:        attr.Size,
+				ContentType: attr.ContentType,
+				ETag:        attr.ETag,
+				ModTime:     attr.ModTime,
+				MD5:         attr.MD5,
+				Metadata:    attr.Metadata,
+			}, nil
+		} else if !shouldRetry(err) {
+			return nil, wrapError("attributes", name, err)
+		}
+		log.Printf("cloud-s3: error getting attributes of blob %s: %v", name, err)
+		if err := retrySleep(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *S3Store) Attributes(name string) (*cloud.BlobAttributes, error) {
+	return s.AttributesContext(context.Background(), name)
+}
+
 type s3GetReader struct {
 	s    *S3Store
 	name string
@@ -129,7 +195,7 @@ func (r *s3GetReader) Size() int64 {
 	return r.size
 }
 
-func (r *s3GetReader) ReadAt(b []byte, off int64) (int, error) {
+func (r *s3GetReader) ReadAtContext(ctx context.Context, b []byte, off int64) (int, error) {
 	if off >= r.size {
 		return 0, io.EOF
 	}
@@ -138,23 +204,30 @@ func (r *s3GetReader) ReadAt(b []byte, off int64) (int, error) {
 		b = b[:int(r.size-off)]
 	}
 
-	r.s.pendingSema.Acquire(context.Background(), 1)
+	err := r.s.pendingSema.Acquire(ctx, 1)
+	if err != nil {
+		return 0, err
+	}
 	defer r.s.pendingSema.Release(1)
 
 	for {
-		rr, err := r.s.bucket.NewRangeReader(context.TODO(), r.name, off, int64(len(b)), nil)
-		if gcerrors.Code(err) == gcerrors.NotFound {
-			return 0, os.ErrNotExist
+		rr, err := r.s.bucket.NewRangeReader(ctx, r.name, off, int64(len(b)), nil)
+		if err != nil && !shouldRetry(err) {
+			return 0, wrapError("read", r.name, err)
 		} else if err != nil {
 			log.Printf("cloud-s3: error attempting to read blob %s: %v", r.name, err)
-			time.Sleep(time.Second)
+			if err := retrySleep(ctx); err != nil {
+				return 0, err
+			}
 			continue
 		}
 		n, err := io.ReadFull(rr, b)
 		rr.Close()
 		if err != nil {
 			log.Printf("cloud-s3: error reading blob %s: %v", r.name, err)
-			time.Sleep(time.Second)
+			if err := retrySleep(ctx); err != nil {
+				return 0, err
+			}
 			continue
 		}
 		if n < oldLen {
@@ -164,12 +237,16 @@ func (r *s3GetReader) ReadAt(b []byte, off int64) (int, error) {
 	}
 }
 
+func (r *s3GetReader) ReadAt(b []byte, off int64) (int, error) {
+	return r.ReadAtContext(context.Background(), b, off)
+}
+
 func (r *s3GetReader) Close() error {
 	return nil
 }
 
-func (s *S3Store) Get(name string) (cloud.GetReader, error) {
-	size, err := s.Size(name)
+func (s *S3Store) GetContext(ctx context.Context, name string) (cloud.GetReader, error) {
+	size, err := s.SizeContext(ctx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -177,6 +254,10 @@ func (s *S3Store) Get(name string) (cloud.GetReader, error) {
 	return r, nil
 }
 
+func (s *S3Store) Get(name string) (cloud.GetReader, error) {
+	return s.GetContext(context.Background(), name)
+}
+
 type result struct {
 	n   int64
 	err error
@@ -225,10 +306,62 @@ func (w *s3PutWriter) Cancel() error {
 	return nil
 }
 
-func (s *S3Store) Put(name string) (cloud.PutWriter, error) {
-	s.pendingSema.Acquire(context.Background(), 1)
+func (s *S3Store) PutContext(ctx context.Context, name string) (cloud.PutWriter, error) {
+	return s.PutWithOptionsContext(ctx, name, nil)
+}
+
+// Adds a conditional header to the requests which create the object. The
+// SDK version in use predates native support for conditional writes.
+func conditionalHeader(name, value string) func(*s3.Options) {
+	mw := middleware.BuildMiddlewareFunc("CloudUtilConditionalWrite",
+		func(ctx context.Context, in middleware.BuildInput, next middleware.BuildHandler) (middleware.BuildOutput, middleware.Metadata, error) {
+			switch awsmiddleware.GetOperationName(ctx) {
+			case "PutObject", "CompleteMultipartUpload":
+				if req, ok := in.Request.(*smithyhttp.Request); ok {
+					req.Header.Set(name, value)
+				}
+			}
+			return next.HandleBuild(ctx, in)
+		})
+	return func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Build.Add(mw, middleware.After)
+		})
+	}
+}
+
+func (s *S3Store) PutWithOptionsContext(ctx context.Context, name string, options *cloud.PutOptions) (cloud.PutWriter, error) {
+	var wopts *blob.WriterOptions
+	if options != nil {
+		wopts = &blob.WriterOptions{
+			ContentType: options.ContentType,
+			Metadata:    options.Metadata,
+		}
+		var cond func(*s3.Options)
+		if options.IfNotExist {
+			cond = conditionalHeader("If-None-Match", "*")
+		} else if options.IfMatch != "" {
+			cond = conditionalHeader("If-Match", options.IfMatch)
+		}
+		if cond != nil {
+			wopts.BeforeWrite = func(as func(interface{}) bool) error {
+				var u *manager.Uploader
+				if !as(&u) {
+					return cloud.ErrCallNotSupported
+				}
+				u.ClientOptions = append(u.ClientOptions, cond)
+				return nil
+			}
+		}
+	}
+
+	err := s.pendingSema.Acquire(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
 	buf, err := util.NewStagingBuffer("")
 	if err != nil {
+		s.pendingSema.Release(1)
 		return nil, err
 	}
 	writer := &s3PutWriter{s: s, result: make(chan result, 1), buf: buf}
@@ -244,8 +377,8 @@ func (s *S3Store) Put(name string) (cloud.PutWriter, error) {
 		for {
 			r.Reset()
 
-			ctx, cf := context.WithCancel(context.TODO())
-			w, err = s.bucket.NewWriter(ctx, name, nil)
+			wctx, cf := context.WithCancel(ctx)
+			w, err = s.bucket.NewWriter(wctx, name, wopts)
 			if err == nil {
 				n, err = io.Copy(w, r)
 				if err != nil {
@@ -258,9 +391,18 @@ func (s *S3Store) Put(name string) (cloud.PutWriter, error) {
 			cf()
 			if err == nil || err == ErrWriteCanceled {
 				break
+			} else if classifyError(err) == cloud.KindPreconditionFailed {
+				err = cloud.ErrPreconditionFailed
+				break
+			} else if !shouldRetry(err) {
+				err = wrapError("put", name, err)
+				break
 			}
 			log.Printf("cloud-s3: error putting blob %s: %v", name, err)
-			time.Sleep(time.Second)
+			if sleepErr := retrySleep(ctx); sleepErr != nil {
+				err = sleepErr
+				break
+			}
 		}
 
 		writer.result <- result{n: n, err: err}
@@ -268,17 +410,64 @@ func (s *S3Store) Put(name string) (cloud.PutWriter, error) {
 	return writer, nil
 }
 
-func (s *S3Store) Delete(name string) error {
+func (s *S3Store) Put(name string) (cloud.PutWriter, error) {
+	return s.PutContext(context.Background(), name)
+}
+
+func (s *S3Store) PutWithOptions(name string, options *cloud.PutOptions) (cloud.PutWriter, error) {
+	return s.PutWithOptionsContext(context.Background(), name, options)
+}
+
+func (s *S3Store) DeleteContext(ctx context.Context, name string) error {
 	for {
-		err := s.bucket.Delete(context.TODO(), name)
+		err := s.bucket.Delete(ctx, name)
 		if err == nil {
 			return nil
-		} else if gcerrors.Code(err) == gcerrors.NotFound {
-			return os.ErrNotExist
+		} else if !shouldRetry(err) {
+			return wrapError("delete", name, err)
 		}
 		log.Printf("cloud-s3: error deleting blob %s: %v", name, err)
-		time.Sleep(time.Second)
+		if err := retrySleep(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *S3Store) Delete(name string) error {
+	return s.DeleteContext(context.Background(), name)
+}
+
+// Uses CopyObject, which is limited to objects up to 5GiB.
+func (s *S3Store) CopyContext(ctx context.Context, src, dst string) error {
+	for {
+		err := s.bucket.Copy(ctx, dst, src, nil)
+		if err == nil {
+			return nil
+		} else if !shouldRetry(err) {
+			return wrapError("copy", src, err)
+		}
+		log.Printf("cloud-s3: error copying blob %s to %s: %v", src, dst, err)
+		if err := retrySleep(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *S3Store) Copy(src, dst string) error {
+	return s.CopyContext(context.Background(), src, dst)
+}
+
+// S3 has no rename operation, so this is a copy followed by a delete.
+func (s *S3Store) RenameContext(ctx context.Context, src, dst string) error {
+	err := s.CopyContext(ctx, src, dst)
+	if err != nil {
+		return err
 	}
+	return s.DeleteContext(ctx, src)
+}
+
+func (s *S3Store) Rename(src, dst string) error {
+	return s.RenameContext(context.Background(), src, dst)
 }
 
 func (s *S3Store) List() ([]string, error) {
@@ -290,9 +479,52 @@ func (s *S3Store) List() ([]string, error) {
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			return nil, err
+			return nil, wrapError("list", "", err)
 		}
 		names = append(names, obj.Key)
 	}
 	return names, nil
 }
+
+func (s *S3Store) ListPage(ctx context.Context, options *cloud.ListOptions, pageToken string) ([]cloud.BlobEntry, string, error) {
+	var o cloud.ListOptions
+	if options != nil {
+		o = *options
+	}
+	pageSize := o.PageSize
+	if pageSize <= 0 {
+		pageSize = cloud.DefaultListPageSize
+	}
+	token := blob.FirstPageToken
+	if pageToken != "" {
+		token = []byte(pageToken)
+	}
+	lopts := &blob.ListOptions{
+		Prefix:    o.Prefix,
+		Delimiter: o.Delimiter,
+	}
+	if o.StartAfter != "" {
+		lopts.BeforeList = func(as func(interface{}) bool) error {
+			var in *s3.ListObjectsV2Input
+			if as(&in) {
+				in.StartAfter = aws.String(o.StartAfter)
+			}
+			return nil
+		}
+	}
+
+	objs, next, err := s.bucket.ListPage(ctx, token, pageSize, lopts)
+	if err != nil {
+		return nil, "", wrapError("list", o.Prefix, err)
+	}
+	entries := make([]cloud.BlobEntry, 0, len(objs))
+	for _, obj := range objs {
+		entries = append(entries, cloud.BlobEntry{
+			Key:     obj.Key,
+			Size:    obj.Size,
+			ModTime: obj.ModTime,
+			IsDir:   obj.IsDir,
+		})
+	}
+	return entries, string(next), nil
+}
@@ -0,0 +1,21 @@
+package cloud
+
+import "context"
+
+// Optionally implemented by stores which natively support batched
+// operations. Batches are not atomic. On error, some operations in the batch
+// may have been applied.
+type BatchStore interface {
+	// Returns a slice the same length as keys, with the pair for keys[i] at
+	// index i. Entries for keys which do not exist are nil.
+	GetMulti(keys []string) ([]*KVPair, error)
+	PutMulti(pairs []*KVPair, options *WriteOptions) error
+	DeleteMulti(keys []string) error
+}
+
+// Context-aware variant of BatchStore.
+type ContextBatchStore interface {
+	GetMultiContext(ctx context.Context, keys []string) ([]*KVPair, error)
+	PutMultiContext(ctx context.Context, pairs []*KVPair, options *WriteOptions) error
+	DeleteMultiContext(ctx context.Context, keys []string) error
+}
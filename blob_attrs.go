@@ -0,0 +1,89 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// Returned when a PutOptions precondition is not met.
+	ErrPreconditionFailed = errors.New("cloud: blob precondition failed")
+)
+
+// Attributes of a stored blob. Fields which a store doesn't support are left
+// as their zero value.
+type BlobAttributes struct {
+	Size        int64
+	ContentType string
+	// Opaque version identifier, which changes whenever the blob is rewritten.
+	// i.e. the S3 ETag, or the GCS generation.
+	ETag    string
+	ModTime time.Time
+
+	// Checksums of the blob contents. CRC32C is the big-endian encoding of the
+	// Castagnoli CRC. nil if unknown.
+	MD5    []byte
+	CRC32C []byte
+
+	// User metadata, as passed to PutWithOptions.
+	Metadata map[string]string
+}
+
+// Options for writing a blob.
+type PutOptions struct {
+	ContentType string
+	// Keys should be lower-case, since some stores don't preserve key case.
+	Metadata map[string]string
+
+	// Preconditions, checked when the writer is closed. Close returns
+	// ErrPreconditionFailed if not met. By default, existing blobs are
+	// overwritten.
+	//
+	// Only write the blob if it doesn't exist.
+	IfNotExist bool
+	// Only overwrite the blob if its ETag matches, as returned by Attributes.
+	IfMatch string
+}
+
+func (o *PutOptions) isEmpty() bool {
+	return o == nil || (o.ContentType == "" && len(o.Metadata) == 0 && !o.HasPreconditions())
+}
+
+func (o *PutOptions) HasPreconditions() bool {
+	return o != nil && (o.IfNotExist || o.IfMatch != "")
+}
+
+// Optionally implemented by blob stores which store attributes and user
+// metadata with blobs.
+type AttributesBlobStore interface {
+	Attributes(key string) (*BlobAttributes, error)
+	AttributesContext(ctx context.Context, key string) (*BlobAttributes, error)
+
+	PutWithOptions(key string, options *PutOptions) (PutWriter, error)
+	PutWithOptionsContext(ctx context.Context, key string, options *PutOptions) (PutWriter, error)
+}
+
+// AttributesContext returns the attributes of blob key. If bs doesn't
+// implement AttributesBlobStore, only the size is returned.
+func AttributesContext(ctx context.Context, bs BlobStore, key string) (*BlobAttributes, error) {
+	if as, ok := bs.(AttributesBlobStore); ok {
+		return as.AttributesContext(ctx, key)
+	}
+	size, err := AsContextBlobStore(bs).SizeContext(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &BlobAttributes{Size: size}, nil
+}
+
+// PutWithOptionsContext writes blob key with options. If bs doesn't implement
+// AttributesBlobStore, returns ErrCallNotSupported unless options is empty.
+func PutWithOptionsContext(ctx context.Context, bs BlobStore, key string, options *PutOptions) (PutWriter, error) {
+	if as, ok := bs.(AttributesBlobStore); ok {
+		return as.PutWithOptionsContext(ctx, key, options)
+	} else if !options.isEmpty() {
+		return nil, ErrCallNotSupported
+	}
+	return AsContextBlobStore(bs).PutContext(ctx, key)
+}
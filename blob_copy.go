@@ -0,0 +1,92 @@
+package cloud
+
+import (
+	"context"
+	"io"
+)
+
+// Optionally implemented by blob stores which can copy blobs without
+// transferring the contents through the client. Attributes and user metadata
+// are copied with the blob, and dst is overwritten if it exists. Returns
+// os.ErrNotExist if src doesn't exist.
+type Copier interface {
+	Copy(src, dst string) error
+	CopyContext(ctx context.Context, src, dst string) error
+
+	// Rename is not necessarily atomic. If it fails, both src and dst may
+	// exist.
+	Rename(src, dst string) error
+	RenameContext(ctx context.Context, src, dst string) error
+}
+
+// CopyContext copies blob src to dst. If bs doesn't implement Copier, the
+// blob is streamed through the client.
+func CopyContext(ctx context.Context, bs BlobStore, src, dst string) error {
+	if c, ok := bs.(Copier); ok {
+		return c.CopyContext(ctx, src, dst)
+	}
+	return streamCopy(ctx, bs, src, dst)
+}
+
+// RenameContext renames blob src to dst. If bs doesn't implement Copier, the
+// blob is streamed through the client, and then src is deleted.
+func RenameContext(ctx context.Context, bs BlobStore, src, dst string) error {
+	if c, ok := bs.(Copier); ok {
+		return c.RenameContext(ctx, src, dst)
+	}
+	err := streamCopy(ctx, bs, src, dst)
+	if err != nil {
+		return err
+	}
+	return AsContextBlobStore(bs).DeleteContext(ctx, src)
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   GetReader
+	off int64
+}
+
+func (r *contextReader) Read(b []byte) (int, error) {
+	if r.off >= r.r.Size() {
+		return 0, io.EOF
+	}
+	n, err := ReadAtContext(r.ctx, r.r, b, r.off)
+	r.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func streamCopy(ctx context.Context, bs BlobStore, src, dst string) error {
+	attrs, err := AttributesContext(ctx, bs, src)
+	if err != nil {
+		return err
+	}
+	var options *PutOptions
+	if attrs.ContentType != "" || len(attrs.Metadata) > 0 {
+		options = &PutOptions{
+			ContentType: attrs.ContentType,
+			Metadata:    attrs.Metadata,
+		}
+	}
+
+	cbs := AsContextBlobStore(bs)
+	r, err := cbs.GetContext(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := PutWithOptionsContext(ctx, bs, dst, options)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, &contextReader{ctx: ctx, r: r})
+	if err != nil {
+		w.Cancel()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,22 @@
+package cloud_test
+
+import (
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
+	"github.com/akmistry/cloud-util/test_util"
+)
+
+// Hides optional interfaces, to test the streaming fallback.
+type plainBlobStore struct {
+	cloud.BlobStore
+}
+
+func TestCopy_Streaming(t *testing.T) {
+	ds, err := local.NewDirBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	test_util.TestBlobCopy(t, &plainBlobStore{ds})
+}
@@ -0,0 +1,179 @@
+package cloud
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultListPageSize = 1000
+)
+
+type ListOptions struct {
+	// Only list keys starting with Prefix.
+	Prefix string
+	// Only list keys after StartAfter.
+	StartAfter string
+	// If non-empty, keys containing Delimiter after Prefix are grouped into a
+	// single directory entry, whose key is truncated after the delimiter.
+	Delimiter string
+	// Maximum number of entries per page. Defaults to DefaultListPageSize.
+	// Stores may return fewer entries, even if more entries exist.
+	PageSize int
+}
+
+func (o *ListOptions) pageSize() int {
+	if o == nil || o.PageSize <= 0 {
+		return DefaultListPageSize
+	}
+	return o.PageSize
+}
+
+type BlobEntry struct {
+	Key string
+	// Size and ModTime are zero for directories.
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// Optionally implemented by blob stores which support paginated listing.
+// Entries are returned in key order. pageToken is "" for the first page, and
+// an empty nextPageToken is returned with the last page.
+type PageLister interface {
+	ListPage(ctx context.Context, options *ListOptions, pageToken string) (entries []BlobEntry, nextPageToken string, err error)
+}
+
+// ListPage lists one page of blobs in bs. If bs doesn't implement PageLister,
+// the full listing from Lister is paginated in memory.
+func ListPage(ctx context.Context, bs BlobStore, options *ListOptions, pageToken string) ([]BlobEntry, string, error) {
+	if pl, ok := bs.(PageLister); ok {
+		return pl.ListPage(ctx, options, pageToken)
+	}
+	l, ok := bs.(Lister)
+	if !ok {
+		return nil, "", ErrCallNotSupported
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	keys, err := l.List()
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Strings(keys)
+	entries, next := PageKeys(keys, options, pageToken)
+	cbs := AsContextBlobStore(bs)
+	for i := range entries {
+		if entries[i].IsDir {
+			continue
+		}
+		entries[i].Size, err = cbs.SizeContext(ctx, entries[i].Key)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	return entries, next, nil
+}
+
+// DirEntryKey returns the key of the entry containing key. If the delimiter
+// appears in key after the prefix, returns the key truncated after the
+// delimiter, and true.
+func DirEntryKey(key, prefix, delimiter string) (string, bool) {
+	if delimiter == "" {
+		return key, false
+	}
+	i := strings.Index(key[len(prefix):], delimiter)
+	if i < 0 {
+		return key, false
+	}
+	return key[:len(prefix)+i+len(delimiter)], true
+}
+
+// PageKeys paginates a sorted list of keys, for stores which implement
+// ListPage in memory. The page token is the key of the last returned entry.
+// Only Key and IsDir are set in the returned entries.
+func PageKeys(keys []string, options *ListOptions, pageToken string) ([]BlobEntry, string) {
+	var o ListOptions
+	if options != nil {
+		o = *options
+	}
+	pageSize := options.pageSize()
+
+	start := max(o.StartAfter, o.Prefix)
+	i := sort.SearchStrings(keys, start)
+	var entries []BlobEntry
+	for ; i < len(keys); i++ {
+		key := keys[i]
+		if !strings.HasPrefix(key, o.Prefix) {
+			break
+		} else if key <= o.StartAfter {
+			continue
+		}
+		entryKey, isDir := DirEntryKey(key, o.Prefix, o.Delimiter)
+		if entryKey <= pageToken || (len(entries) > 0 && entries[len(entries)-1].Key == entryKey) {
+			continue
+		}
+		if len(entries) == pageSize {
+			return entries, entries[len(entries)-1].Key
+		}
+		entries = append(entries, BlobEntry{Key: entryKey, IsDir: isDir})
+	}
+	return entries, ""
+}
+
+// Iterates over all entries of a listing, fetching pages as needed.
+type ListIterator struct {
+	ctx     context.Context
+	bs      BlobStore
+	options *ListOptions
+
+	page  []BlobEntry
+	token string
+	done  bool
+}
+
+func NewListIterator(ctx context.Context, bs BlobStore, options *ListOptions) *ListIterator {
+	return &ListIterator{
+		ctx:     ctx,
+		bs:      bs,
+		options: options,
+	}
+}
+
+// Next returns the next entry, or io.EOF when the listing is complete.
+func (it *ListIterator) Next() (*BlobEntry, error) {
+	for len(it.page) == 0 {
+		if it.done {
+			return nil, io.EOF
+		}
+		var err error
+		it.page, it.token, err = ListPage(it.ctx, it.bs, it.options, it.token)
+		if err != nil {
+			return nil, err
+		}
+		it.done = it.token == ""
+	}
+	e := &it.page[0]
+	it.page = it.page[1:]
+	return e, nil
+}
+
+// ListAll returns the keys of all blobs in bs with prefix, using ListPage.
+func ListAll(ctx context.Context, bs BlobStore, prefix string) ([]string, error) {
+	var keys []string
+	it := NewListIterator(ctx, bs, &ListOptions{Prefix: prefix})
+	for {
+		e, err := it.Next()
+		if err == io.EOF {
+			return keys, nil
+		} else if err != nil {
+			return nil, err
+		}
+		keys = append(keys, e.Key)
+	}
+}
@@ -15,7 +15,7 @@ type PutWriter interface {
 }
 
 type Lister interface {
-  List() ([]string, error)
+	List() ([]string, error)
 }
 
 type BlobStore interface {
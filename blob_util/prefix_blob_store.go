@@ -1,6 +1,9 @@
 package blob_util
 
 import (
+	"context"
+	"strings"
+
 	"github.com/akmistry/cloud-util"
 )
 
@@ -10,6 +13,12 @@ type PrefixBlobStore struct {
 }
 
 var _ = (cloud.BlobStore)((*PrefixBlobStore)(nil))
+var _ = (cloud.ContextBlobStore)((*PrefixBlobStore)(nil))
+var _ = (cloud.BlobCapabilitiesProvider)((*PrefixBlobStore)(nil))
+var _ = (cloud.AttributesBlobStore)((*PrefixBlobStore)(nil))
+var _ = (cloud.Lister)((*PrefixBlobStore)(nil))
+var _ = (cloud.PageLister)((*PrefixBlobStore)(nil))
+var _ = (cloud.Copier)((*PrefixBlobStore)(nil))
 
 func NewPrefixBlobStore(bs cloud.BlobStore, prefix string) *PrefixBlobStore {
 	return &PrefixBlobStore{
@@ -18,6 +27,10 @@ func NewPrefixBlobStore(bs cloud.BlobStore, prefix string) *PrefixBlobStore {
 	}
 }
 
+func (s *PrefixBlobStore) Capabilities() cloud.BlobCapabilities {
+	return cloud.GetBlobCapabilities(s.bs)
+}
+
 func (s *PrefixBlobStore) makeKey(key string) string {
 	return s.prefix + key
 }
@@ -37,3 +50,75 @@ func (s *PrefixBlobStore) Put(key string) (cloud.PutWriter, error) {
 func (s *PrefixBlobStore) Delete(key string) error {
 	return s.bs.Delete(s.makeKey(key))
 }
+
+func (s *PrefixBlobStore) SizeContext(ctx context.Context, key string) (int64, error) {
+	return cloud.AsContextBlobStore(s.bs).SizeContext(ctx, s.makeKey(key))
+}
+
+func (s *PrefixBlobStore) GetContext(ctx context.Context, key string) (cloud.GetReader, error) {
+	return cloud.AsContextBlobStore(s.bs).GetContext(ctx, s.makeKey(key))
+}
+
+func (s *PrefixBlobStore) PutContext(ctx context.Context, key string) (cloud.PutWriter, error) {
+	return cloud.AsContextBlobStore(s.bs).PutContext(ctx, s.makeKey(key))
+}
+
+func (s *PrefixBlobStore) DeleteContext(ctx context.Context, key string) error {
+	return cloud.AsContextBlobStore(s.bs).DeleteContext(ctx, s.makeKey(key))
+}
+
+func (s *PrefixBlobStore) Attributes(key string) (*cloud.BlobAttributes, error) {
+	return s.AttributesContext(context.Background(), key)
+}
+
+func (s *PrefixBlobStore) AttributesContext(ctx context.Context, key string) (*cloud.BlobAttributes, error) {
+	return cloud.AttributesContext(ctx, s.bs, s.makeKey(key))
+}
+
+func (s *PrefixBlobStore) PutWithOptions(key string, options *cloud.PutOptions) (cloud.PutWriter, error) {
+	return s.PutWithOptionsContext(context.Background(), key, options)
+}
+
+func (s *PrefixBlobStore) PutWithOptionsContext(ctx context.Context, key string, options *cloud.PutOptions) (cloud.PutWriter, error) {
+	return cloud.PutWithOptionsContext(ctx, s.bs, s.makeKey(key), options)
+}
+
+func (s *PrefixBlobStore) ListPage(ctx context.Context, options *cloud.ListOptions, pageToken string) ([]cloud.BlobEntry, string, error) {
+	var o cloud.ListOptions
+	if options != nil {
+		o = *options
+	}
+	o.Prefix = s.makeKey(o.Prefix)
+	if o.StartAfter != "" {
+		o.StartAfter = s.makeKey(o.StartAfter)
+	}
+
+	entries, next, err := cloud.ListPage(ctx, s.bs, &o, pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	for i := range entries {
+		entries[i].Key = strings.TrimPrefix(entries[i].Key, s.prefix)
+	}
+	return entries, next, nil
+}
+
+func (s *PrefixBlobStore) List() ([]string, error) {
+	return cloud.ListAll(context.Background(), s, "")
+}
+
+func (s *PrefixBlobStore) Copy(src, dst string) error {
+	return s.CopyContext(context.Background(), src, dst)
+}
+
+func (s *PrefixBlobStore) CopyContext(ctx context.Context, src, dst string) error {
+	return cloud.CopyContext(ctx, s.bs, s.makeKey(src), s.makeKey(dst))
+}
+
+func (s *PrefixBlobStore) Rename(src, dst string) error {
+	return s.RenameContext(context.Background(), src, dst)
+}
+
+func (s *PrefixBlobStore) RenameContext(ctx context.Context, src, dst string) error {
+	return cloud.RenameContext(ctx, s.bs, s.makeKey(src), s.makeKey(dst))
+}
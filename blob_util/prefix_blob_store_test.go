@@ -0,0 +1,29 @@
+package blob_util
+
+import (
+	"testing"
+
+	"github.com/akmistry/cloud-util/local"
+	"github.com/akmistry/cloud-util/test_util"
+)
+
+func TestPrefixBlobStore(t *testing.T) {
+	ds, err := local.NewDirBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Blobs outside the prefix should not be visible.
+	other := NewPrefixBlobStore(ds, "other")
+	w, err := other.Put("a.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	s := NewPrefixBlobStore(ds, "prefix")
+	test_util.TestBlobStore(t, s)
+	test_util.TestBlobAttributes(t, s)
+	test_util.TestBlobPreconditions(t, s)
+	test_util.TestBlobCopy(t, s)
+	test_util.TestBlobList(t, s)
+}
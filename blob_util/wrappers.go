@@ -0,0 +1,19 @@
+package blob_util
+
+import (
+	"fmt"
+
+	"github.com/akmistry/cloud-util"
+)
+
+func init() {
+	cloud.RegisterBlobStoreWrapper("prefix", wrapPrefixBlobStore)
+}
+
+// Path format: prefix(<prefix>)|<inner blob store>
+func wrapPrefixBlobStore(args []string, bs cloud.BlobStore) (cloud.BlobStore, error) {
+	if len(args) != 1 || args[0] == "" {
+		return nil, fmt.Errorf("cloud/blob_util: prefix wrapper expects a prefix")
+	}
+	return NewPrefixBlobStore(bs, args[0]), nil
+}
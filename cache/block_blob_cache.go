@@ -1,7 +1,9 @@
 package cache
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"log"
@@ -10,20 +12,35 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/golang-lru/v2"
+	"golang.org/x/sync/semaphore"
 
 	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/util"
 )
 
 const (
-	blockSize = 1024 * 1024
+	DefaultBlockSize = 1024 * 1024
+
+	blockTempPrefix = "block-temp"
+	// Records the block size of cached blocks, which are discarded if the
+	// block size changes.
+	blockSizeFileName = "block-size"
+
+	// Maximum number of blocks being fetched from backing stores at once, by
+	// all caches.
+	maxConcurrentBlockFetches = 16
+
+	// Number of times Get retries if the blob is modified while being opened.
+	maxOpenAttempts = 3
+
+	DefaultValidationTTL = 30 * time.Second
 )
 
 var (
-	blockBufPool = sync.Pool{New: func() interface{} {
-		return make([]byte, blockSize)
-	}}
+	blockFetchSem = semaphore.NewWeighted(maxConcurrentBlockFetches)
 )
 
 var openFileCache *OpenFileCache
@@ -32,19 +49,53 @@ func init() {
 	openFileCache = NewOpenFileCache(1000)
 }
 
+type BlockBlobCacheOptions struct {
+	// Size of cached blocks, and the unit of reads from the backing store.
+	// Defaults to DefaultBlockSize.
+	BlockSize int64
+
+	// Number of blocks to fetch in the background ahead of sequential reads.
+	// Reads are sequential if each starts where the previous read from the
+	// same reader ended. Zero disables readahead.
+	ReadaheadBlocks int
+
+	// How long a blob's size and version are trusted before being revalidated
+	// against the backing store by Get or Size. Cached blocks are discarded if
+	// the blob has been modified. Defaults to DefaultValidationTTL. Negative
+	// revalidates on every call.
+	ValidationTTL time.Duration
+}
+
 type BlockBlobCache struct {
 	dir     string
 	backing cloud.BlobStore
 
-	blobReaderCache map[string]cloud.GetReader
+	blockSize       int64
+	readaheadBlocks int
+	validationTTL   time.Duration
+	blockBufPool    sync.Pool
+
+	index         blockIndex
+	indexSaveLock sync.Mutex
+
+	blobReaderCache map[string]*cachedBlob
 
 	blockCacheLru *lru.Cache[blockCacheKey, *blockCacheEntry]
 
 	lock sync.Mutex
 }
 
+var _ = (cloud.BlobStore)((*BlockBlobCache)(nil))
+var _ = (cloud.ContextBlobStore)((*BlockBlobCache)(nil))
+var _ = (cloud.BlobCapabilitiesProvider)((*BlockBlobCache)(nil))
+var _ = (cloud.AttributesBlobStore)((*BlockBlobCache)(nil))
+var _ = (cloud.Lister)((*BlockBlobCache)(nil))
+var _ = (cloud.PageLister)((*BlockBlobCache)(nil))
+var _ = (cloud.Copier)((*BlockBlobCache)(nil))
+
 type blockCacheKey struct {
 	blobKey string
+	id      uint64
 	block   int64
 }
 
@@ -54,7 +105,32 @@ type blockCacheEntry struct {
 	downloadDone chan struct{}
 }
 
+// A version of a blob being read through the cache.
+type cachedBlob struct {
+	key  string
+	id   uint64
+	size int64
+	br   cloud.GetReader
+}
+
 func NewBlockBlobCache(bs cloud.BlobStore, dir string, cacheSize int64) (*BlockBlobCache, error) {
+	return NewBlockBlobCacheWithOptions(bs, dir, cacheSize, nil)
+}
+
+func NewBlockBlobCacheWithOptions(bs cloud.BlobStore, dir string, cacheSize int64, options *BlockBlobCacheOptions) (*BlockBlobCache, error) {
+	var opts BlockBlobCacheOptions
+	if options != nil {
+		opts = *options
+	}
+	if opts.BlockSize == 0 {
+		opts.BlockSize = DefaultBlockSize
+	} else if opts.BlockSize < 0 {
+		return nil, fmt.Errorf("cloud/cache: invalid block size: %d", opts.BlockSize)
+	}
+	if opts.ValidationTTL == 0 {
+		opts.ValidationTTL = DefaultValidationTTL
+	}
+
 	err := os.MkdirAll(dir, 0755)
 	if err != nil {
 		return nil, err
@@ -63,15 +139,37 @@ func NewBlockBlobCache(bs cloud.BlobStore, dir string, cacheSize int64) (*BlockB
 	c := &BlockBlobCache{
 		dir:             dir,
 		backing:         bs,
-		blobReaderCache: make(map[string]cloud.GetReader),
+		blockSize:       opts.BlockSize,
+		readaheadBlocks: opts.ReadaheadBlocks,
+		validationTTL:   opts.ValidationTTL,
+		blobReaderCache: make(map[string]*cachedBlob),
+	}
+	c.blockBufPool.New = func() interface{} {
+		return make([]byte, c.blockSize)
 	}
 
 	c.blockCacheLru, err = lru.NewWithEvict[blockCacheKey, *blockCacheEntry](
-		int(cacheSize/blockSize), c.blockEvictFunc)
+		int(cacheSize/c.blockSize), c.blockEvictFunc)
 	if err != nil {
 		return nil, err
 	}
 
+	stats, err := util.RemoveStaleTempFiles(dir, blockTempPrefix, util.DefaultTempFileMaxAge)
+	if err != nil {
+		return nil, err
+	} else if stats.Files > 0 {
+		log.Printf("Removed %d stale temp files (%d bytes)", stats.Files, stats.Bytes)
+	}
+	err = c.checkBlockSize()
+	if err != nil {
+		return nil, err
+	}
+	err = c.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	cachedBlobs := make(map[string]bool)
+
 	// Populate the cache
 	filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
 		if path == c.dir {
@@ -84,19 +182,29 @@ func NewBlockBlobCache(bs cloud.BlobStore, dir string, cacheSize int64) (*BlockB
 		}
 
 		basename := d.Name()
-		split := strings.LastIndex(basename, "-")
-		if split < 0 {
+		if strings.HasPrefix(basename, blockTempPrefix) || basename == blockSizeFileName ||
+			basename == blockIndexFileName {
+			// Temp files are in use by another process, or not yet stale.
+			return nil
+		}
+		key, id, block, ok := parseBlockFileName(basename)
+		if !ok {
 			log.Printf("Error parsing cache file name %s", basename)
 			return nil
 		}
-		key := basename[:split]
-		block, err := strconv.ParseUint(basename[split+1:], 10, 64)
-		if err != nil {
-			log.Printf("Error parsing cache file block offset %s: %v", basename, err)
+
+		if block%c.blockSize != 0 {
+			log.Printf("Cache file block offset %s not a multiple of block size", basename)
+			return nil
+		}
+		if e := c.index.Blobs[key]; e == nil || e.ID != id {
+			// Block of a different version of the blob.
+			os.Remove(path)
 			return nil
 		}
+		cachedBlobs[key] = true
 
-		cacheKey := blockCacheKey{blobKey: key, block: int64(block)}
+		cacheKey := blockCacheKey{blobKey: key, id: id, block: block}
 		entry := &blockCacheEntry{
 			fname:        path,
 			downloadDone: make(chan struct{}),
@@ -107,23 +215,116 @@ func NewBlockBlobCache(bs cloud.BlobStore, dir string, cacheSize int64) (*BlockB
 		return nil
 	})
 
+	// Forget blobs with no cached blocks, to keep the index small.
+	pruned := false
+	for key := range c.index.Blobs {
+		if !cachedBlobs[key] {
+			delete(c.index.Blobs, key)
+			pruned = true
+		}
+	}
+	if pruned {
+		c.saveIndex()
+	}
+
 	return c, nil
 }
 
+// Removes all cached blocks if they were cached with a different block size.
+// Caches created before the block size was recorded used DefaultBlockSize.
+func (c *BlockBlobCache) checkBlockSize() error {
+	path := filepath.Join(c.dir, blockSizeFileName)
+	cachedSize := int64(DefaultBlockSize)
+	buf, err := os.ReadFile(path)
+	if err == nil {
+		cachedSize, err = strconv.ParseInt(strings.TrimSpace(string(buf)), 10, 64)
+		if err != nil {
+			log.Printf("Invalid cached block size %q", buf)
+			cachedSize = 0
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if cachedSize != c.blockSize {
+		log.Printf("Block size changed from %d to %d, discarding cached blocks", cachedSize, c.blockSize)
+		err = c.discardBlocks()
+		if err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, []byte(strconv.FormatInt(c.blockSize, 10)), 0644)
+}
+
+// Removes all cached block files.
+func (c *BlockBlobCache) discardBlocks() error {
+	dirents, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	for _, d := range dirents {
+		if d.IsDir() || strings.HasPrefix(d.Name(), blockTempPrefix) ||
+			d.Name() == blockSizeFileName || d.Name() == blockIndexFileName {
+			continue
+		}
+		err = os.Remove(filepath.Join(c.dir, d.Name()))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *BlockBlobCache) blockEvictFunc(key blockCacheKey, e *blockCacheEntry) {
 	os.Remove(e.fname)
 	openFileCache.Remove(e.fname)
 }
 
+func (c *BlockBlobCache) Capabilities() cloud.BlobCapabilities {
+	return cloud.GetBlobCapabilities(c.backing)
+}
+
 func (c *BlockBlobCache) Size(key string) (int64, error) {
-	// Pass-through
-	// TODO: Cache this. Frequently called.
-	return c.backing.Size(key)
+	return c.SizeContext(context.Background(), key)
+}
+
+// Sizes are served from the cache's metadata while it is within the
+// validation TTL.
+func (c *BlockBlobCache) SizeContext(ctx context.Context, key string) (int64, error) {
+	entry, err := c.validate(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Version.Size, nil
 }
 
 func (c *BlockBlobCache) Put(key string) (cloud.PutWriter, error) {
+	return c.PutContext(context.Background(), key)
+}
+
+func (c *BlockBlobCache) PutContext(ctx context.Context, key string) (cloud.PutWriter, error) {
+	return c.PutWithOptionsContext(ctx, key, nil)
+}
+
+func (c *BlockBlobCache) PutWithOptions(key string, options *cloud.PutOptions) (cloud.PutWriter, error) {
+	return c.PutWithOptionsContext(context.Background(), key, options)
+}
+
+func (c *BlockBlobCache) PutWithOptionsContext(ctx context.Context, key string, options *cloud.PutOptions) (cloud.PutWriter, error) {
 	// Pass-through
-	return c.backing.Put(key)
+	w, err := cloud.PutWithOptionsContext(ctx, c.backing, key, options)
+	if err != nil {
+		return nil, err
+	}
+	return &invalidatingWriter{PutWriter: w, c: c, key: key}, nil
+}
+
+func (c *BlockBlobCache) Attributes(key string) (*cloud.BlobAttributes, error) {
+	return c.AttributesContext(context.Background(), key)
+}
+
+func (c *BlockBlobCache) AttributesContext(ctx context.Context, key string) (*cloud.BlobAttributes, error) {
+	// Pass-through
+	return cloud.AttributesContext(ctx, c.backing, key)
 }
 
 func (c *BlockBlobCache) List() ([]string, error) {
@@ -135,16 +336,13 @@ func (c *BlockBlobCache) List() ([]string, error) {
 	return l.List()
 }
 
-func (c *BlockBlobCache) makeBlockFilePath(key string, block int64) string {
-	if block%blockSize != 0 {
-		log.Fatalf("block %d %% blockSize %d != 0", block, blockSize)
-	}
-	basename := key + "-" + strconv.FormatInt(block, 10)
-	return filepath.Join(c.dir, basename)
+func (c *BlockBlobCache) ListPage(ctx context.Context, options *cloud.ListOptions, pageToken string) ([]cloud.BlobEntry, string, error) {
+	// Pass-through
+	return cloud.ListPage(ctx, c.backing, options, pageToken)
 }
 
-func (c *BlockBlobCache) getBlockReader(key string, blobSize int64, block int64, br cloud.GetReader) (ReaderAtCloser, error) {
-	cacheKey := blockCacheKey{blobKey: key, block: block}
+func (c *BlockBlobCache) getBlockReader(ctx context.Context, b *cachedBlob, block int64) (ReaderAtCloser, error) {
+	cacheKey := blockCacheKey{blobKey: b.key, id: b.id, block: block}
 
 	for {
 		c.lock.Lock()
@@ -157,13 +355,14 @@ func (c *BlockBlobCache) getBlockReader(key string, blobSize int64, block int64,
 			r, err := openFileCache.Open(entry.fname)
 			if err != nil {
 				log.Printf("Error opening cache file %s: %v", entry.fname, err)
-				// Retry
+				// Retry, fetching the block again if it has been removed.
+				c.removeBlockEntry(cacheKey, entry)
 				continue
 			}
 			return r, nil
 		}
 
-		name := c.makeBlockFilePath(key, block)
+		name := c.makeBlockFilePath(b.key, b.id, block)
 		entry = &blockCacheEntry{
 			fname:        name,
 			downloadDone: make(chan struct{}),
@@ -173,31 +372,46 @@ func (c *BlockBlobCache) getBlockReader(key string, blobSize int64, block int64,
 
 		defer close(entry.downloadDone)
 
-		buf := blockBufPool.Get().([]byte)
-		defer blockBufPool.Put(buf)
-		if blockSize > blobSize-block {
-			buf = buf[:int(blobSize-block)]
+		buf := c.blockBufPool.Get().([]byte)
+		defer c.blockBufPool.Put(buf)
+		if c.blockSize > b.size-block {
+			buf = buf[:int(b.size-block)]
+		}
+		err := blockFetchSem.Acquire(ctx, 1)
+		if err != nil {
+			c.removeBlockEntry(cacheKey, entry)
+			return nil, err
 		}
-		n, err := br.ReadAt(buf, block)
+		n, err := cloud.ReadAtContext(ctx, b.br, buf, block)
+		blockFetchSem.Release(1)
 		if err != nil && err != io.EOF {
+			c.removeBlockEntry(cacheKey, entry)
 			return nil, err
 		}
 		buf = buf[:n]
 
-		f, err := os.CreateTemp(c.dir, "block-temp*")
+		f, err := util.CreateTempFile(c.dir, blockTempPrefix+"*")
 		if err != nil {
+			c.removeBlockEntry(cacheKey, entry)
 			return nil, err
 		}
 		_, err = f.Write(buf)
 		if err != nil {
 			f.Close()
 			os.Remove(f.Name())
+			c.removeBlockEntry(cacheKey, entry)
 			return nil, err
 		}
 		err = os.Rename(f.Name(), name)
 		if err != nil {
 			log.Printf("Unable to rename %s to %s: %v", f.Name(), name, err)
 		}
+		c.lock.Lock()
+		if e, ok := c.blockCacheLru.Peek(cacheKey); !ok || e != entry {
+			// The blob was invalidated during the download.
+			os.Remove(name)
+		}
+		c.lock.Unlock()
 		fi, err := f.Stat()
 		if err == nil {
 			err = f.Chmod(fi.Mode() | 0644)
@@ -210,114 +424,340 @@ func (c *BlockBlobCache) getBlockReader(key string, blobSize int64, block int64,
 	}
 }
 
+// Fetches a block in the background, unless it is already cached or being
+// fetched.
+func (c *BlockBlobCache) prefetch(b *cachedBlob, block int64) {
+	c.lock.Lock()
+	cached := c.blockCacheLru.Contains(blockCacheKey{blobKey: b.key, id: b.id, block: block})
+	c.lock.Unlock()
+	if cached {
+		return
+	}
+
+	go func() {
+		r, err := c.getBlockReader(context.Background(), b, block)
+		if err != nil {
+			log.Printf("Error reading ahead block %d of %s: %v", block, b.key, err)
+			return
+		}
+		r.Close()
+	}()
+}
+
+// Removes a cache entry whose download failed, so that waiters and future
+// readers retry the download instead of trying to open a non-existent file.
+func (c *BlockBlobCache) removeBlockEntry(key blockCacheKey, entry *blockCacheEntry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e, ok := c.blockCacheLru.Peek(key); ok && e == entry {
+		c.blockCacheLru.Remove(key)
+	}
+}
+
 type cacheReader struct {
-	c   *BlockBlobCache
-	key string
-	br  cloud.GetReader
+	c *BlockBlobCache
+	b *cachedBlob
+
+	mmaps util.Mappings
+
+	readaheadLock sync.Mutex
+	// End of the previous read, to detect sequential reads.
+	lastReadEnd int64
+	// Blocks before this offset have already been read ahead.
+	readaheadEnd int64
 }
 
+var _ = (cloud.MemMapper)((*cacheReader)(nil))
+
 func (r *cacheReader) Size() int64 {
-	return r.br.Size()
+	return r.b.size
 }
 
 func (r *cacheReader) Close() error {
-	return nil
+	return r.mmaps.Unmap()
 }
 
-func (r *cacheReader) ReadAt(p []byte, off int64) (int, error) {
-	bytesRead := 0
-	for len(p) > 0 {
-		blockOff := off % blockSize
-		blockRem := blockSize - blockOff
-		block := off - blockOff
+// Returns the file underlying a block reader returned by getBlockReader.
+func blockFile(r ReaderAtCloser) (*os.File, bool) {
+	switch r := r.(type) {
+	case *os.File:
+		return r, true
+	case *openFileReader:
+		f, ok := r.e.r.(*os.File)
+		return f, ok
+	}
+	return nil, false
+}
 
-		blockReader, err := r.c.getBlockReader(r.key, r.br.Size(), block, r.br)
+// Ranges within a single block are mapped from the cached block file, which is
+// never modified in place. Ranges spanning blocks are copied.
+func (r *cacheReader) MemMap(offset int64, length int) ([]byte, error) {
+	if offset < 0 || length < 0 || offset+int64(length) > r.b.size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	blockOff := offset % r.c.blockSize
+	if blockOff+int64(length) > r.c.blockSize {
+		buf := make([]byte, length)
+		_, err := r.ReadAt(buf, offset)
 		if err != nil {
-			return bytesRead, err
-		}
-		readLen := len(p)
-		if int64(readLen) > blockRem {
-			readLen = int(blockRem)
+			return nil, err
 		}
-		n, err := blockReader.ReadAt(p[:readLen], blockOff)
-		blockReader.Close()
-		bytesRead += n
-		off += int64(n)
-		p = p[n:]
-		if err == io.EOF && n == readLen {
-			err = nil
-		} else if err != nil {
-			return bytesRead, err
+		return buf, nil
+	}
+
+	block := offset - blockOff
+	blockReader, err := r.c.getBlockReader(context.Background(), r.b, block)
+	if err != nil {
+		return nil, err
+	}
+	defer blockReader.Close()
+	f, ok := blockFile(blockReader)
+	if !ok {
+		buf := make([]byte, length)
+		_, err := blockReader.ReadAt(buf, blockOff)
+		if err != nil && err != io.EOF {
+			return nil, err
 		}
+		return buf, nil
 	}
-	return bytesRead, nil
+	return r.mmaps.Map(f, blockOff, length)
 }
 
-func (c *BlockBlobCache) Get(key string) (cloud.GetReader, error) {
-	c.lock.Lock()
-	br := c.blobReaderCache[key]
-	c.lock.Unlock()
+func (r *cacheReader) ReadAt(p []byte, off int64) (int, error) {
+	return r.ReadAtContext(context.Background(), p, off)
+}
 
-	if br == nil {
-		var err error
-		br, err = c.backing.Get(key)
-		if err != nil {
-			return nil, err
+// Blocks spanned by the read are fetched in parallel.
+func (r *cacheReader) ReadAtContext(ctx context.Context, p []byte, off int64) (int, error) {
+	size := r.b.size
+	if off >= size {
+		return 0, io.EOF
+	}
+	var eof error
+	if int64(len(p)) > size-off {
+		p = p[:size-off]
+		eof = io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	r.readahead(off, len(p))
+
+	bs := r.c.blockSize
+	if off/bs == (off+int64(len(p))-1)/bs {
+		n, err := r.readBlock(ctx, p, off)
+		if err == nil {
+			err = eof
 		}
+		return n, err
+	}
 
-		c.lock.Lock()
-		c.blobReaderCache[key] = br
-		c.lock.Unlock()
+	// Split the read at block boundaries.
+	var chunks [][]byte
+	for rem, chunkOff := p, off; len(rem) > 0; {
+		chunkLen := int(min(int64(len(rem)), bs-chunkOff%bs))
+		chunks = append(chunks, rem[:chunkLen])
+		rem = rem[chunkLen:]
+		chunkOff += int64(chunkLen)
 	}
+	counts := make([]int, len(chunks))
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	chunkOff := off
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk []byte, chunkOff int64) {
+			defer wg.Done()
+			counts[i], errs[i] = r.readBlock(ctx, chunk, chunkOff)
+		}(i, chunk, chunkOff)
+		chunkOff += int64(len(chunk))
+	}
+	wg.Wait()
 
-	r := &cacheReader{
-		c:   c,
-		key: key,
-		br:  br,
+	bytesRead := 0
+	for i := range chunks {
+		bytesRead += counts[i]
+		if errs[i] != nil {
+			return bytesRead, errs[i]
+		}
 	}
-	return r, nil
+	return bytesRead, eof
 }
 
-func (c *BlockBlobCache) deleteCachedBlocks(key string) {
-	filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
-		if path == c.dir {
-			return nil
-		} else if d.IsDir() {
-			return fs.SkipDir
-		} else if err != nil {
-			log.Printf("Error walking path %s: %v", path, err)
-			return nil
-		}
+// Reads p from the single block containing off.
+func (r *cacheReader) readBlock(ctx context.Context, p []byte, off int64) (int, error) {
+	blockOff := off % r.c.blockSize
+	blockReader, err := r.c.getBlockReader(ctx, r.b, off-blockOff)
+	if err != nil {
+		return 0, err
+	}
+	defer blockReader.Close()
+	n, err := blockReader.ReadAt(p, blockOff)
+	if err == io.EOF && n == len(p) {
+		err = nil
+	}
+	return n, err
+}
 
-		if !strings.HasPrefix(d.Name(), key+"-") {
-			return nil
-		}
+// Starts fetching the blocks following a sequential read.
+func (r *cacheReader) readahead(off int64, length int) {
+	if r.c.readaheadBlocks <= 0 {
+		return
+	}
 
-		blockOffsetStr := strings.TrimPrefix(d.Name(), key+"-")
-		_, err = strconv.ParseUint(blockOffsetStr, 10, 64)
+	bs := r.c.blockSize
+	end := off + int64(length)
+	r.readaheadLock.Lock()
+	sequential := off == r.lastReadEnd
+	r.lastReadEnd = end
+	// Start after the block containing the end of the read.
+	start := max((end-1)/bs*bs+bs, r.readaheadEnd)
+	limit := min((end-1)/bs*bs+bs*int64(1+r.c.readaheadBlocks), r.b.size)
+	if sequential && start < limit {
+		r.readaheadEnd = limit
+	}
+	r.readaheadLock.Unlock()
+	if !sequential {
+		return
+	}
+
+	for block := start; block < limit; block += bs {
+		r.c.prefetch(r.b, block)
+	}
+}
+
+func (c *BlockBlobCache) Get(key string) (cloud.GetReader, error) {
+	return c.GetContext(context.Background(), key)
+}
+
+// Blobs are revalidated if they were last validated longer than the validation
+// TTL ago.
+func (c *BlockBlobCache) GetContext(ctx context.Context, key string) (cloud.GetReader, error) {
+	for attempt := 0; attempt < maxOpenAttempts; attempt++ {
+		entry, err := c.validate(ctx, key)
 		if err != nil {
-			log.Printf("Error parsing block offset '%s': %v", blockOffsetStr, err)
-			return nil
+			return nil, err
 		}
-
-		err = os.Remove(path)
+		b, err := c.openBlob(ctx, key, entry)
 		if err != nil {
-			log.Printf("Error removing block file %s: %v", path, err)
+			return nil, err
+		} else if b != nil {
+			return &cacheReader{c: c, b: b}, nil
 		}
-		openFileCache.Remove(path)
-		return nil
-	})
+	}
+	return nil, fmt.Errorf("cloud/cache: blob %s repeatedly modified while opening", key)
 }
 
-func (c *BlockBlobCache) Delete(key string) error {
+// Returns a backing store reader for the version of blob key in entry, or nil
+// if the blob has been modified since it was validated. The blob's version is
+// re-checked after opening, since an overwrite of the same size is otherwise
+// indistinguishable.
+func (c *BlockBlobCache) openBlob(ctx context.Context, key string, entry blobIndexEntry) (*cachedBlob, error) {
+	c.lock.Lock()
+	b := c.blobReaderCache[key]
+	c.lock.Unlock()
+	if b != nil && b.id == entry.ID {
+		return b, nil
+	}
+
+	br, err := cloud.AsContextBlobStore(c.backing).GetContext(ctx, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.invalidate(key)
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+	attrs, err := cloud.AttributesContext(ctx, c.backing, key)
+	if err != nil {
+		br.Close()
+		if errors.Is(err, fs.ErrNotExist) {
+			c.invalidate(key)
+		}
+		return nil, err
+	}
+	v := versionOf(attrs)
+	if !v.equal(entry.Version) || br.Size() != v.Size {
+		br.Close()
+		c.updateVersion(key, v)
+		return nil, nil
+	}
+
 	c.lock.Lock()
-	br := c.blobReaderCache[key]
-	if br != nil {
+	defer c.lock.Unlock()
+	if e := c.index.Blobs[key]; e == nil || e.ID != entry.ID {
+		// Invalidated while opening.
+		br.Close()
+		return nil, nil
+	}
+	if b := c.blobReaderCache[key]; b != nil && b.id == entry.ID {
+		// Opened concurrently.
 		br.Close()
-		delete(c.blobReaderCache, key)
+		return b, nil
+	}
+	b = &cachedBlob{
+		key:  key,
+		id:   entry.ID,
+		size: entry.Version.Size,
+		br:   br,
 	}
+	c.blobReaderCache[key] = b
+	return b, nil
+}
+
+func (c *BlockBlobCache) Delete(key string) error {
+	return c.DeleteContext(context.Background(), key)
+}
+
+// Drops cached state for key, which has been modified or deleted.
+func (c *BlockBlobCache) invalidate(key string) {
+	c.lock.Lock()
+	_, indexed := c.index.Blobs[key]
+	c.dropBlobLocked(key)
 	c.lock.Unlock()
 
-	c.deleteCachedBlocks(key)
-	return c.backing.Delete(key)
+	c.deleteCachedBlocks(key, 0)
+	if indexed {
+		c.saveIndex()
+	}
+}
+
+func (c *BlockBlobCache) DeleteContext(ctx context.Context, key string) error {
+	c.invalidate(key)
+	return cloud.AsContextBlobStore(c.backing).DeleteContext(ctx, key)
+}
+
+func (c *BlockBlobCache) Copy(src, dst string) error {
+	return c.CopyContext(context.Background(), src, dst)
+}
+
+func (c *BlockBlobCache) CopyContext(ctx context.Context, src, dst string) error {
+	err := cloud.CopyContext(ctx, c.backing, src, dst)
+	c.invalidate(dst)
+	return err
+}
+
+func (c *BlockBlobCache) Rename(src, dst string) error {
+	return c.RenameContext(context.Background(), src, dst)
+}
+
+func (c *BlockBlobCache) RenameContext(ctx context.Context, src, dst string) error {
+	c.invalidate(src)
+	err := cloud.RenameContext(ctx, c.backing, src, dst)
+	c.invalidate(dst)
+	return err
+}
+
+// Invalidates the cached blob once it has been overwritten.
+type invalidatingWriter struct {
+	cloud.PutWriter
+	c   *BlockBlobCache
+	key string
+}
+
+func (w *invalidatingWriter) Close() error {
+	err := w.PutWriter.Close()
+	if err == nil {
+		w.c.invalidate(w.key)
+	}
+	return err
 }
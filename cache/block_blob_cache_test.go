@@ -0,0 +1,454 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
+	"github.com/akmistry/cloud-util/test_util"
+)
+
+func TestBlockBlobCache_MemMap(t *testing.T) {
+	backing := local.NewMemBlobStore()
+	c, err := NewBlockBlobCache(backing, t.TempDir(), 16*DefaultBlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test_util.TestBlobMemMap(t, c)
+
+	// Mapped data remains valid after the block is evicted.
+	data := []byte("cached block")
+	w, err := c.Put("blob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := c.Get("blob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.(cloud.MemMapper); !ok {
+		t.Fatalf("BlockBlobCache reader doesn't implement cloud.MemMapper")
+	}
+	buf, err := cloud.MemMap(r, 7, 5)
+	if err != nil || string(buf) != "block" {
+		t.Fatalf("MemMap(7, 5) = %q, %v", buf, err)
+	}
+	c.blockCacheLru.Purge()
+	if string(buf) != "block" {
+		t.Errorf("MemMap(7, 5) after eviction = %q", buf)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+// Counts reads and size lookups from the backing store, and the maximum
+// number of reads in progress at once.
+type countingBlobStore struct {
+	cloud.BlobStore
+
+	lock      sync.Mutex
+	reads     int
+	sizes     int
+	active    int
+	maxActive int
+	readDelay time.Duration
+}
+
+type countingReader struct {
+	cloud.GetReader
+	s *countingBlobStore
+}
+
+func (r *countingReader) ReadAt(b []byte, off int64) (int, error) {
+	s := r.s
+	s.lock.Lock()
+	s.reads++
+	s.active++
+	s.maxActive = max(s.maxActive, s.active)
+	s.lock.Unlock()
+
+	time.Sleep(s.readDelay)
+	n, err := r.GetReader.ReadAt(b, off)
+
+	s.lock.Lock()
+	s.active--
+	s.lock.Unlock()
+	return n, err
+}
+
+func (s *countingBlobStore) Get(key string) (cloud.GetReader, error) {
+	r, err := s.BlobStore.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return &countingReader{GetReader: r, s: s}, nil
+}
+
+func (s *countingBlobStore) Size(key string) (int64, error) {
+	s.lock.Lock()
+	s.sizes++
+	s.lock.Unlock()
+	return s.BlobStore.Size(key)
+}
+
+func (s *countingBlobStore) stats() (int, int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.reads, s.maxActive
+}
+
+func newTestBlob(t *testing.T, bs cloud.BlobStore, key string, size int) []byte {
+	t.Helper()
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 13)
+	}
+	w, err := bs.Put(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestBlockBlobCache_ParallelRead(t *testing.T) {
+	const blockSize = 4096
+	backing := &countingBlobStore{BlobStore: local.NewMemBlobStore(), readDelay: 10 * time.Millisecond}
+	data := newTestBlob(t, backing, "blob", 40*blockSize+100)
+	c, err := NewBlockBlobCacheWithOptions(backing, t.TempDir(), 100*blockSize,
+		&BlockBlobCacheOptions{BlockSize: blockSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := c.Get("blob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	buf := make([]byte, len(data)+10)
+	n, err := r.ReadAt(buf[:len(data)-10], 10)
+	if err != nil || n != len(data)-10 {
+		t.Fatalf("ReadAt = %d, %v", n, err)
+	} else if !bytes.Equal(buf[:n], data[10:]) {
+		t.Errorf("ReadAt returned unexpected data")
+	}
+	reads, maxActive := backing.stats()
+	if reads != 41 {
+		t.Errorf("Backing reads %d != expected 41", reads)
+	}
+	if maxActive < 2 || maxActive > maxConcurrentBlockFetches {
+		t.Errorf("Concurrent backing reads %d not in [2, %d]", maxActive, maxConcurrentBlockFetches)
+	}
+
+	// Reads past the end are truncated.
+	n, err = r.ReadAt(buf, 0)
+	if err != io.EOF || n != len(data) || !bytes.Equal(buf[:n], data) {
+		t.Errorf("ReadAt past end = %d, %v", n, err)
+	}
+	n, err = r.ReadAt(buf, int64(len(data)))
+	if err != io.EOF || n != 0 {
+		t.Errorf("ReadAt at end = %d, %v", n, err)
+	}
+}
+
+func TestBlockBlobCache_Readahead(t *testing.T) {
+	const blockSize = 4096
+	backing := &countingBlobStore{BlobStore: local.NewMemBlobStore()}
+	data := newTestBlob(t, backing, "blob", 16*blockSize)
+	c, err := NewBlockBlobCacheWithOptions(backing, t.TempDir(), 100*blockSize,
+		&BlockBlobCacheOptions{BlockSize: blockSize, ReadaheadBlocks: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := c.Get("blob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	cached := func(block int64) bool {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		id := c.index.Blobs["blob"].ID
+		return c.blockCacheLru.Contains(blockCacheKey{blobKey: "blob", id: id, block: block * blockSize})
+	}
+
+	// A random read doesn't trigger readahead.
+	buf := make([]byte, blockSize)
+	if _, err := r.ReadAt(buf, 8*blockSize); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if cached(9) {
+		t.Errorf("Block 9 read ahead after random read")
+	}
+
+	// Sequential reads fetch the following blocks.
+	for off := int64(0); off < 2*blockSize; off += blockSize {
+		if _, err := r.ReadAt(buf, off); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(buf, data[off:off+blockSize]) {
+			t.Errorf("ReadAt(%d) returned unexpected data", off)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for block := int64(2); block <= 5; block++ {
+		for !cached(block) && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if !cached(block) {
+			t.Errorf("Block %d not read ahead", block)
+		}
+	}
+	if cached(6) {
+		t.Errorf("Block 6 read ahead beyond the readahead window")
+	}
+}
+
+func TestBlockBlobCache_BlockSizeChange(t *testing.T) {
+	backing := local.NewMemBlobStore()
+	data := newTestBlob(t, backing, "blob", 10000)
+	dir := t.TempDir()
+
+	for _, blockSize := range []int64{4096, 1024, 1024} {
+		c, err := NewBlockBlobCacheWithOptions(backing, dir, 1<<20, &BlockBlobCacheOptions{BlockSize: blockSize})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(readBlob(t, c, "blob"), data) {
+			t.Errorf("Block size %d: unexpected data", blockSize)
+		}
+		dirents, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Block files, the block size file, and the index.
+		expected := int((int64(len(data))+blockSize-1)/blockSize) + 2
+		if len(dirents) != expected {
+			t.Errorf("Block size %d: %d files in cache != expected %d", blockSize, len(dirents), expected)
+		}
+	}
+}
+
+// Returns the names of the cached block files of blob key.
+func blockFiles(t *testing.T, c *BlockBlobCache, key string) []string {
+	t.Helper()
+	dirents, err := os.ReadDir(c.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, d := range dirents {
+		if blobKey, _, _, ok := parseBlockFileName(d.Name()); ok && blobKey == key {
+			names = append(names, d.Name())
+		}
+	}
+	return names
+}
+
+func TestBlockBlobCache_Validation(t *testing.T) {
+	const blockSize = 4096
+	backing := local.NewMemBlobStore()
+	data := newTestBlob(t, backing, "blob", 3*blockSize)
+	c, err := NewBlockBlobCacheWithOptions(backing, t.TempDir(), 100*blockSize,
+		&BlockBlobCacheOptions{BlockSize: blockSize, ValidationTTL: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(readBlob(t, c, "blob"), data) {
+		t.Fatalf("Unexpected data")
+	}
+	oldBlocks := blockFiles(t, c, "blob")
+	if len(oldBlocks) != 3 {
+		t.Fatalf("Cached blocks %v, expected 3", oldBlocks)
+	}
+
+	// Overwrite the blob, with the same size, without going through the cache.
+	newData := bytes.Repeat([]byte{'x'}, len(data))
+	w, err := backing.Put("blob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(newData)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(readBlob(t, c, "blob"), newData) {
+		t.Errorf("Stale data returned after blob modified")
+	}
+	for _, name := range blockFiles(t, c, "blob") {
+		for _, old := range oldBlocks {
+			if name == old {
+				t.Errorf("Stale block %s not removed", name)
+			}
+		}
+	}
+
+	// Deleted blobs are dropped from the cache.
+	if err := backing.Delete("blob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("blob"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Get(blob) of deleted blob error = %v", err)
+	}
+	if blocks := blockFiles(t, c, "blob"); len(blocks) != 0 {
+		t.Errorf("Blocks of deleted blob %v not removed", blocks)
+	}
+	if _, ok := c.index.Blobs["blob"]; ok {
+		t.Errorf("Deleted blob still in index")
+	}
+}
+
+func TestBlockBlobCache_ValidationTTL(t *testing.T) {
+	const blockSize = 4096
+	backing := &countingBlobStore{BlobStore: local.NewMemBlobStore()}
+	data := newTestBlob(t, backing, "blob", 3*blockSize)
+	dir := t.TempDir()
+	options := &BlockBlobCacheOptions{BlockSize: blockSize, ValidationTTL: time.Hour}
+	c, err := NewBlockBlobCacheWithOptions(backing, dir, 100*blockSize, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(readBlob(t, c, "blob"), data) {
+		t.Fatalf("Unexpected data")
+	}
+
+	// Within the TTL, the size is served from the cache's metadata.
+	newData := newTestBlob(t, backing.BlobStore, "blob", 2*blockSize)
+	if size, err := c.Size("blob"); err != nil || size != int64(len(data)) {
+		t.Errorf("Size(blob) = %d, %v, expected cached size %d", size, err, len(data))
+	}
+
+	// Blobs are revalidated after a restart.
+	c, err = NewBlockBlobCacheWithOptions(backing, dir, 100*blockSize, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, err := c.Size("blob"); err != nil || size != int64(len(newData)) {
+		t.Errorf("Size(blob) after restart = %d, %v, expected %d", size, err, len(newData))
+	}
+	if !bytes.Equal(readBlob(t, c, "blob"), newData) {
+		t.Errorf("Stale data returned after restart")
+	}
+
+	// Blocks of unmodified blobs are reused after a restart.
+	reads, _ := backing.stats()
+	c, err = NewBlockBlobCacheWithOptions(backing, dir, 100*blockSize, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(readBlob(t, c, "blob"), newData) {
+		t.Errorf("Unexpected data after restart")
+	}
+	if newReads, _ := backing.stats(); newReads != reads {
+		t.Errorf("%d backing reads after restart, expected 0", newReads-reads)
+	}
+	if blocks := blockFiles(t, c, "blob"); len(blocks) != 2 {
+		t.Errorf("Cached blocks %v, expected 2", blocks)
+	}
+}
+
+func TestBlockBlobCache_DefaultValidationTTL(t *testing.T) {
+	const blockSize = 4096
+	backing := &countingBlobStore{BlobStore: local.NewMemBlobStore()}
+	data := newTestBlob(t, backing, "blob", 3*blockSize)
+	c, err := NewBlockBlobCacheWithOptions(backing, t.TempDir(), 100*blockSize,
+		&BlockBlobCacheOptions{BlockSize: blockSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(readBlob(t, c, "blob"), data) {
+		t.Fatalf("Unexpected data")
+	}
+
+	backing.lock.Lock()
+	sizes := backing.sizes
+	backing.lock.Unlock()
+	for i := 0; i < 3; i++ {
+		if size, err := c.Size("blob"); err != nil || size != int64(len(data)) {
+			t.Errorf("Size(blob) = %d, %v, expected %d", size, err, len(data))
+		}
+		if !bytes.Equal(readBlob(t, c, "blob"), data) {
+			t.Errorf("Unexpected data")
+		}
+	}
+	backing.lock.Lock()
+	defer backing.lock.Unlock()
+	if backing.sizes != sizes {
+		t.Errorf("%d backing size lookups within the TTL, expected 0", backing.sizes-sizes)
+	}
+}
+
+// Runs a hook before opening blobs.
+type hookBlobStore struct {
+	*local.MemBlobStore
+
+	beforeGet func()
+}
+
+func (s *hookBlobStore) Get(key string) (cloud.GetReader, error) {
+	return s.GetContext(context.Background(), key)
+}
+
+func (s *hookBlobStore) GetContext(ctx context.Context, key string) (cloud.GetReader, error) {
+	if s.beforeGet != nil {
+		s.beforeGet()
+		s.beforeGet = nil
+	}
+	return s.MemBlobStore.GetContext(ctx, key)
+}
+
+func TestBlockBlobCache_OverwriteWhileOpening(t *testing.T) {
+	const blockSize = 4096
+	backing := &hookBlobStore{MemBlobStore: local.NewMemBlobStore()}
+	data := newTestBlob(t, backing, "blob", 3*blockSize)
+	c, err := NewBlockBlobCacheWithOptions(backing, t.TempDir(), 100*blockSize,
+		&BlockBlobCacheOptions{BlockSize: blockSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, err := c.Size("blob"); err != nil || size != int64(len(data)) {
+		t.Fatalf("Size(blob) = %d, %v, expected %d", size, err, len(data))
+	}
+
+	// Overwrite the blob with the same size after it has been validated, but
+	// before it is opened.
+	newData := bytes.Repeat([]byte{'x'}, len(data))
+	backing.beforeGet = func() {
+		w, err := backing.MemBlobStore.Put("blob")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(newData)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(readBlob(t, c, "blob"), newData) {
+		t.Errorf("Stale data returned after blob modified")
+	}
+	attrs, err := backing.Attributes("blob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := c.index.Blobs["blob"]; e == nil || e.Version.ETag != attrs.ETag {
+		t.Errorf("Index entry %+v, expected ETag %s", e, attrs.ETag)
+	}
+}
@@ -0,0 +1,239 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/util"
+)
+
+const (
+	// Records the version of each cached blob. Cached blocks of blobs which
+	// aren't in the index are discarded.
+	blockIndexFileName = "index"
+)
+
+// Identifies a version of a blob. Stores which don't support attributes only
+// provide the size.
+type blobVersion struct {
+	Size    int64     `json:"size"`
+	ETag    string    `json:"etag,omitempty"`
+	ModTime time.Time `json:"mtime"`
+}
+
+func versionOf(attrs *cloud.BlobAttributes) blobVersion {
+	return blobVersion{
+		Size:    attrs.Size,
+		ETag:    attrs.ETag,
+		ModTime: attrs.ModTime,
+	}
+}
+
+func (v blobVersion) equal(o blobVersion) bool {
+	return v.Size == o.Size && v.ETag == o.ETag && v.ModTime.Equal(o.ModTime)
+}
+
+type blobIndexEntry struct {
+	// Unique within the cache, and part of the names of cached block files, so
+	// that blocks of different versions of a blob are never mixed.
+	ID      uint64      `json:"id"`
+	Version blobVersion `json:"version"`
+
+	// When the version was last checked against the backing store. Not
+	// persisted, so blobs are revalidated after a restart.
+	validated time.Time
+}
+
+type blockIndex struct {
+	NextID uint64                     `json:"next_id"`
+	Blobs  map[string]*blobIndexEntry `json:"blobs"`
+}
+
+// Block files are named <key>-<blob ID>-<block offset>.
+func (c *BlockBlobCache) makeBlockFilePath(key string, id uint64, block int64) string {
+	if block%c.blockSize != 0 {
+		log.Fatalf("block %d %% blockSize %d != 0", block, c.blockSize)
+	}
+	basename := key + "-" + strconv.FormatUint(id, 10) + "-" + strconv.FormatInt(block, 10)
+	return filepath.Join(c.dir, basename)
+}
+
+func parseBlockFileName(name string) (key string, id uint64, block int64, ok bool) {
+	rest, blockStr, ok := cutLast(name, "-")
+	if !ok {
+		return "", 0, 0, false
+	}
+	key, idStr, ok := cutLast(rest, "-")
+	if !ok {
+		return "", 0, 0, false
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	block, err = strconv.ParseInt(blockStr, 10, 64)
+	if err != nil || block < 0 {
+		return "", 0, 0, false
+	}
+	return key, id, block, true
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+// Loads the index. Cached blocks can't be validated without an index, so they
+// are discarded if it's missing or invalid.
+func (c *BlockBlobCache) loadIndex() error {
+	c.index = blockIndex{Blobs: make(map[string]*blobIndexEntry)}
+	buf, err := os.ReadFile(filepath.Join(c.dir, blockIndexFileName))
+	if err == nil {
+		err = json.Unmarshal(buf, &c.index)
+		if err == nil && c.index.Blobs != nil {
+			return nil
+		}
+		log.Printf("Invalid cache index: %v", err)
+		c.index = blockIndex{Blobs: make(map[string]*blobIndexEntry)}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return c.discardBlocks()
+}
+
+// Writes the index, logging any error. Blocks are never served from an
+// out-of-date index, since blocks whose blob ID doesn't match the index are
+// discarded on startup.
+func (c *BlockBlobCache) saveIndex() {
+	c.indexSaveLock.Lock()
+	defer c.indexSaveLock.Unlock()
+
+	c.lock.Lock()
+	buf, err := json.Marshal(&c.index)
+	c.lock.Unlock()
+	if err != nil {
+		log.Printf("Error encoding cache index: %v", err)
+		return
+	}
+
+	f, err := util.CreateTempFile(c.dir, blockTempPrefix+"*")
+	if err != nil {
+		log.Printf("Error saving cache index: %v", err)
+		return
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(buf)
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(c.dir, blockIndexFileName))
+	}
+	if err != nil {
+		log.Printf("Error saving cache index: %v", err)
+	}
+}
+
+// Returns the version of blob key, revalidating it against the backing store
+// if it was last validated longer than the validation TTL ago.
+func (c *BlockBlobCache) validate(ctx context.Context, key string) (blobIndexEntry, error) {
+	c.lock.Lock()
+	e := c.index.Blobs[key]
+	if e != nil && time.Since(e.validated) < c.validationTTL {
+		entry := *e
+		c.lock.Unlock()
+		return entry, nil
+	}
+	c.lock.Unlock()
+
+	attrs, err := cloud.AttributesContext(ctx, c.backing, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.invalidate(key)
+		return blobIndexEntry{}, err
+	} else if err != nil {
+		return blobIndexEntry{}, err
+	}
+	return c.updateVersion(key, versionOf(attrs)), nil
+}
+
+// Records the current version of blob key. If the version has changed, the
+// blob is assigned a new ID and cached blocks of the old version are
+// discarded.
+func (c *BlockBlobCache) updateVersion(key string, v blobVersion) blobIndexEntry {
+	c.lock.Lock()
+	e := c.index.Blobs[key]
+	if e != nil && e.Version.equal(v) {
+		e.validated = time.Now()
+		entry := *e
+		c.lock.Unlock()
+		return entry
+	}
+
+	modified := e != nil
+	c.dropBlobLocked(key)
+	c.index.NextID++
+	e = &blobIndexEntry{
+		ID:        c.index.NextID,
+		Version:   v,
+		validated: time.Now(),
+	}
+	c.index.Blobs[key] = e
+	entry := *e
+	c.lock.Unlock()
+
+	if modified {
+		c.deleteCachedBlocks(key, entry.ID)
+	}
+	c.saveIndex()
+	return entry
+}
+
+// Drops all in-memory state for blob key. Cached blocks in the LRU are
+// removed, and block files of the blob which aren't in the LRU must be
+// removed separately with deleteCachedBlocks.
+func (c *BlockBlobCache) dropBlobLocked(key string) {
+	if b := c.blobReaderCache[key]; b != nil {
+		b.br.Close()
+		delete(c.blobReaderCache, key)
+	}
+	for _, k := range c.blockCacheLru.Keys() {
+		if k.blobKey == key {
+			c.blockCacheLru.Remove(k)
+		}
+	}
+	delete(c.index.Blobs, key)
+}
+
+// Removes the block files of blob key, except those of blob ID keepID.
+func (c *BlockBlobCache) deleteCachedBlocks(key string, keepID uint64) {
+	dirents, err := os.ReadDir(c.dir)
+	if err != nil {
+		log.Printf("Error reading cache directory %s: %v", c.dir, err)
+		return
+	}
+	for _, d := range dirents {
+		blobKey, id, _, ok := parseBlockFileName(d.Name())
+		if d.IsDir() || !ok || blobKey != key || id == keepID {
+			continue
+		}
+		path := filepath.Join(c.dir, d.Name())
+		err = os.Remove(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Error removing block file %s: %v", path, err)
+		}
+		openFileCache.Remove(path)
+	}
+}
@@ -2,12 +2,16 @@ package cache
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -16,12 +20,15 @@ import (
 	"golang.org/x/sync/semaphore"
 
 	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/util"
 )
 
 const (
 	tempPrefix      = "temp-"
 	pendingPrefix   = "pending-"
 	completedPrefix = "completed-"
+	// PutOptions of pending blobs, applied when uploading.
+	optionsPrefix = "options-"
 
 	maxActiveUploads    = 2
 	maxCompletedUploads = 10
@@ -29,6 +36,14 @@ const (
 	maxOpenStagedFiles = maxCompletedUploads + maxActiveUploads
 )
 
+var _ = (cloud.BlobStore)((*StagedBlobUploader)(nil))
+var _ = (cloud.ContextBlobStore)((*StagedBlobUploader)(nil))
+var _ = (cloud.BlobCapabilitiesProvider)((*StagedBlobUploader)(nil))
+var _ = (cloud.AttributesBlobStore)((*StagedBlobUploader)(nil))
+var _ = (cloud.Lister)((*StagedBlobUploader)(nil))
+var _ = (cloud.PageLister)((*StagedBlobUploader)(nil))
+var _ = (cloud.Copier)((*StagedBlobUploader)(nil))
+
 type StagedBlobUploader struct {
 	dir          string
 	backing      cloud.BlobStore
@@ -47,6 +62,13 @@ func NewStagedBlobUploader(bs cloud.BlobStore, dir string) (*StagedBlobUploader,
 		return nil, err
 	}
 
+	stats, err := util.RemoveStaleTempFiles(dir, tempPrefix, util.DefaultTempFileMaxAge)
+	if err != nil {
+		return nil, err
+	} else if stats.Files > 0 {
+		log.Printf("Removed %d stale temp files (%d bytes)", stats.Files, stats.Bytes)
+	}
+
 	dirents, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, err
@@ -54,14 +76,7 @@ func NewStagedBlobUploader(bs cloud.BlobStore, dir string) (*StagedBlobUploader,
 	pendingBlobs := make(map[string]bool, len(dirents))
 	var completedBlobs []string
 	for _, e := range dirents {
-		if strings.HasPrefix(e.Name(), tempPrefix) {
-			log.Printf("Deleting temp file %s", e.Name())
-			err = os.Remove(filepath.Join(dir, e.Name()))
-			if err != nil {
-				log.Printf("Error removing temp file %s: %v", e.Name(), err)
-			}
-			continue
-		} else if strings.HasPrefix(e.Name(), completedPrefix) {
+		if strings.HasPrefix(e.Name(), completedPrefix) {
 			completedBlobs = append(completedBlobs, filepath.Join(dir, e.Name()))
 			continue
 		} else if !strings.HasPrefix(e.Name(), pendingPrefix) {
@@ -106,6 +121,54 @@ func (u *StagedBlobUploader) makeCompletedName(key string) string {
 	return filepath.Join(u.dir, completedPrefix+key)
 }
 
+func (u *StagedBlobUploader) makeOptionsName(key string) string {
+	return filepath.Join(u.dir, optionsPrefix+key)
+}
+
+// Returns nil options if key was put without options.
+func (u *StagedBlobUploader) readOptions(key string) (*cloud.PutOptions, error) {
+	buf, err := os.ReadFile(u.makeOptionsName(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	options := new(cloud.PutOptions)
+	err = json.Unmarshal(buf, options)
+	if err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+func (u *StagedBlobUploader) writeOptions(key string, options *cloud.PutOptions) error {
+	if options == nil {
+		err := os.Remove(u.makeOptionsName(key))
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return err
+	}
+
+	buf, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	f, err := util.CreateTempFile(u.dir, tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(buf)
+	closeErr := f.Close()
+	if err != nil {
+		return err
+	} else if closeErr != nil {
+		return closeErr
+	}
+	return os.Rename(f.Name(), u.makeOptionsName(key))
+}
+
 func (u *StagedBlobUploader) doBlobUpload(key string) {
 	u.activeUploads.Acquire(context.Background(), 1)
 	defer u.activeUploads.Release(1)
@@ -131,7 +194,11 @@ func (u *StagedBlobUploader) doBlobUpload(key string) {
 		log.Printf("Uploading %s", key)
 		pendingName := u.makePendingName(key)
 		f, err := os.Open(pendingName)
-		if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// Deleted or renamed before the upload started.
+			log.Printf("Pending blob %s no longer exists", key)
+			return
+		} else if err != nil {
 			panic(err)
 		}
 		defer f.Close()
@@ -145,9 +212,10 @@ func (u *StagedBlobUploader) doBlobUpload(key string) {
 		if err == nil {
 			if size == fi.Size() {
 				err = os.Rename(pendingName, u.makeCompletedName(key))
-				if err != nil {
+				if err != nil && !errors.Is(err, os.ErrNotExist) {
 					panic(err)
 				}
+				os.Remove(u.makeOptionsName(key))
 				return
 			}
 			log.Printf("Uploaded blob %s size %d != pending size %d", key, size, fi.Size())
@@ -157,12 +225,16 @@ func (u *StagedBlobUploader) doBlobUpload(key string) {
 				retry(err)
 				continue
 			}
-		} else if err != os.ErrNotExist {
+		} else if !errors.Is(err, os.ErrNotExist) {
 			retry(err)
 			continue
 		}
 
-		w, err := u.backing.Put(key)
+		options, err := u.readOptions(key)
+		if err != nil {
+			panic(err)
+		}
+		w, err := cloud.PutWithOptionsContext(context.Background(), u.backing, key, options)
 		if err != nil {
 			retry(err)
 			continue
@@ -180,9 +252,20 @@ func (u *StagedBlobUploader) doBlobUpload(key string) {
 		}
 		cName := u.makeCompletedName(key)
 		err = os.Rename(pendingName, cName)
-		if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// The blob was deleted or renamed during the upload, so the uploaded
+			// blob must not be left behind.
+			log.Printf("Pending blob %s removed during upload, deleting", key)
+			err = u.backing.Delete(key)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("Error deleting %s: %v", key, err)
+			}
+			u.fileCache.Remove(pendingName)
+			return
+		} else if err != nil {
 			panic(err)
 		}
+		os.Remove(u.makeOptionsName(key))
 		// Remove the pending name from the cache so that files don't stay open
 		// with the pending name.
 		u.fileCache.Remove(pendingName)
@@ -206,7 +289,22 @@ func (u *StagedBlobUploader) findStagedBlob(key string) string {
 	return ""
 }
 
+func (u *StagedBlobUploader) Capabilities() cloud.BlobCapabilities {
+	caps := cloud.GetBlobCapabilities(u.backing)
+	// Blobs are uploaded asynchronously, after Close.
+	caps.Preconditions = false
+	return caps
+}
+
 func (u *StagedBlobUploader) Size(key string) (int64, error) {
+	return u.SizeContext(context.Background(), key)
+}
+
+func (u *StagedBlobUploader) SizeContext(ctx context.Context, key string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	fname := u.findStagedBlob(key)
 	if fname != "" {
 		fi, err := os.Stat(fname)
@@ -217,7 +315,56 @@ func (u *StagedBlobUploader) Size(key string) (int64, error) {
 		// Fallback
 	}
 
-	return u.backing.Size(key)
+	return cloud.AsContextBlobStore(u.backing).SizeContext(ctx, key)
+}
+
+func (u *StagedBlobUploader) Attributes(key string) (*cloud.BlobAttributes, error) {
+	return u.AttributesContext(context.Background(), key)
+}
+
+// Blobs which have not yet been uploaded only have their size, modification
+// time, and PutOptions available.
+func (u *StagedBlobUploader) AttributesContext(ctx context.Context, key string) (*cloud.BlobAttributes, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(u.makePendingName(key))
+	if err == nil {
+		attrs := &cloud.BlobAttributes{
+			Size:    fi.Size(),
+			ModTime: fi.ModTime(),
+		}
+		options, err := u.readOptions(key)
+		if err != nil {
+			return nil, err
+		} else if options != nil {
+			attrs.ContentType = options.ContentType
+			attrs.Metadata = options.Metadata
+		}
+		return attrs, nil
+	}
+
+	return cloud.AttributesContext(ctx, u.backing, key)
+}
+
+// Returns the sorted keys of all pending and completed blobs.
+func (u *StagedBlobUploader) stagedKeys() ([]string, error) {
+	dirents, err := os.ReadDir(u.dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, e := range dirents {
+		if strings.HasPrefix(e.Name(), pendingPrefix) {
+			keys = append(keys, strings.TrimPrefix(e.Name(), pendingPrefix))
+		} else if strings.HasPrefix(e.Name(), completedPrefix) {
+			keys = append(keys, strings.TrimPrefix(e.Name(), completedPrefix))
+		}
+	}
+	// A blob may be briefly both pending and completed.
+	slices.Sort(keys)
+	return slices.Compact(keys), nil
 }
 
 func (u *StagedBlobUploader) List() ([]string, error) {
@@ -231,36 +378,134 @@ func (u *StagedBlobUploader) List() ([]string, error) {
 		return list, err
 	}
 
-	dirents, err := os.ReadDir(u.dir)
+	staged, err := u.stagedKeys()
 	if err != nil {
 		return nil, err
 	}
-	for _, e := range dirents {
-		var key string
-		if strings.HasPrefix(e.Name(), pendingPrefix) {
-			key = strings.TrimPrefix(e.Name(), pendingPrefix)
-		} else if strings.HasPrefix(e.Name(), completedPrefix) {
-			key = strings.TrimPrefix(e.Name(), completedPrefix)
-		} else {
-			continue
+	listed := make(map[string]bool, len(list))
+	for _, k := range list {
+		listed[k] = true
+	}
+	for _, k := range staged {
+		if !listed[k] {
+			list = append(list, k)
 		}
+	}
+	return list, nil
+}
 
-		hasKey := false
-		for _, k := range list {
-			if k == key {
-				hasKey = true
+type stagedPageToken struct {
+	Backing string `json:"b"`
+	// Key of the last returned entry.
+	After string `json:"a"`
+}
+
+// Each page of the backing store's listing is merged with the staged blobs
+// which fall within the same range of keys.
+func (u *StagedBlobUploader) ListPage(ctx context.Context, options *cloud.ListOptions, pageToken string) ([]cloud.BlobEntry, string, error) {
+	var token stagedPageToken
+	if pageToken != "" {
+		buf, err := base64.RawURLEncoding.DecodeString(pageToken)
+		if err == nil {
+			err = json.Unmarshal(buf, &token)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("cloud/cache: invalid page token: %w", err)
+		}
+	}
+
+	entries, next, err := cloud.ListPage(ctx, u.backing, options, token.Backing)
+	if err != nil {
+		return nil, "", err
+	}
+	// The previous page may have been truncated part way through this page.
+	for len(entries) > 0 && token.After != "" && entries[0].Key <= token.After {
+		entries = entries[1:]
+	}
+	staged, err := u.stagedKeys()
+	if err != nil {
+		return nil, "", err
+	}
+	var o cloud.ListOptions
+	if options != nil {
+		o = *options
+	}
+	o.PageSize = len(staged) + 1
+	stagedEntries, _ := cloud.PageKeys(staged, &o, token.After)
+	if next != "" {
+		// Staged blobs after this page will be merged into a later page.
+		upper := token.After
+		if len(entries) > 0 {
+			upper = entries[len(entries)-1].Key
+		}
+		for i, e := range stagedEntries {
+			if e.Key > upper {
+				stagedEntries = stagedEntries[:i]
 				break
 			}
 		}
-		if hasKey {
+	}
+	for i, e := range stagedEntries {
+		if e.IsDir {
+			continue
+		}
+		fname := u.findStagedBlob(e.Key)
+		if fname == "" {
 			continue
 		}
-		list = append(list, key)
+		fi, err := os.Stat(fname)
+		if err == nil {
+			stagedEntries[i].Size = fi.Size()
+			stagedEntries[i].ModTime = fi.ModTime()
+		}
 	}
-	return list, nil
+
+	merged := make([]cloud.BlobEntry, 0, len(entries)+len(stagedEntries))
+	for len(entries) > 0 || len(stagedEntries) > 0 {
+		if len(stagedEntries) == 0 || (len(entries) > 0 && entries[0].Key < stagedEntries[0].Key) {
+			merged = append(merged, entries[0])
+			entries = entries[1:]
+			continue
+		}
+		if len(entries) > 0 && entries[0].Key == stagedEntries[0].Key {
+			// Staged blobs may be newer than the backing blob.
+			entries = entries[1:]
+		}
+		merged = append(merged, stagedEntries[0])
+		stagedEntries = stagedEntries[1:]
+	}
+
+	pageSize := cloud.DefaultListPageSize
+	if options != nil && options.PageSize > 0 {
+		pageSize = options.PageSize
+	}
+	if len(merged) > pageSize {
+		// Resume from the same page of the backing store.
+		merged = merged[:pageSize]
+	} else if next == "" {
+		return merged, "", nil
+	} else {
+		token.Backing = next
+	}
+	if len(merged) > 0 {
+		token.After = merged[len(merged)-1].Key
+	}
+	buf, err := json.Marshal(&token)
+	if err != nil {
+		return nil, "", err
+	}
+	return merged, base64.RawURLEncoding.EncodeToString(buf), nil
 }
 
 func (u *StagedBlobUploader) Delete(key string) error {
+	return u.DeleteContext(context.Background(), key)
+}
+
+func (u *StagedBlobUploader) DeleteContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	fname := u.findStagedBlob(key)
 	if fname != "" {
 		err := os.Remove(fname)
@@ -269,8 +514,122 @@ func (u *StagedBlobUploader) Delete(key string) error {
 		}
 		u.completedLru.Remove(fname)
 	}
+	os.Remove(u.makeOptionsName(key))
+
+	return cloud.AsContextBlobStore(u.backing).DeleteContext(ctx, key)
+}
+
+// Atomically replaces newname with a hard link to oldname.
+func (u *StagedBlobUploader) linkReplace(oldname, newname string) error {
+	f, err := util.CreateTempFile(u.dir, tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	tempName := f.Name()
+	f.Close()
+	os.Remove(tempName)
+	err = os.Link(oldname, tempName)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tempName, newname)
+	if err != nil {
+		os.Remove(tempName)
+	}
+	return err
+}
+
+func (u *StagedBlobUploader) Copy(src, dst string) error {
+	return u.CopyContext(context.Background(), src, dst)
+}
+
+func (u *StagedBlobUploader) CopyContext(ctx context.Context, src, dst string) error {
+	return u.copy(ctx, src, dst, false)
+}
+
+func (u *StagedBlobUploader) Rename(src, dst string) error {
+	return u.RenameContext(context.Background(), src, dst)
+}
+
+func (u *StagedBlobUploader) RenameContext(ctx context.Context, src, dst string) error {
+	return u.copy(ctx, src, dst, true)
+}
+
+// Blobs which haven't been uploaded yet are copied or moved locally, and
+// uploaded as dst. Otherwise, the backing store performs the copy, and the
+// completed file is copied or moved to keep it cached.
+func (u *StagedBlobUploader) copy(ctx context.Context, src, dst string, rename bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Remove any stale cached copy of dst, which would otherwise shadow the
+	// copy.
+	dstCompleted := u.makeCompletedName(dst)
+	u.completedLru.Remove(dstCompleted)
+	os.Remove(dstCompleted)
+	u.fileCache.Remove(dstCompleted)
 
-	return u.backing.Delete(key)
+	srcPending := u.makePendingName(src)
+	if _, err := os.Stat(srcPending); err == nil {
+		options, err := u.readOptions(src)
+		if err != nil {
+			return err
+		}
+		err = u.writeOptions(dst, options)
+		if err != nil {
+			return err
+		}
+		if rename {
+			err = os.Rename(srcPending, u.makePendingName(dst))
+			u.fileCache.Remove(srcPending)
+		} else {
+			err = u.linkReplace(srcPending, u.makePendingName(dst))
+		}
+		if err != nil {
+			return err
+		}
+		go u.doBlobUpload(dst)
+
+		if rename {
+			os.Remove(u.makeOptionsName(src))
+			// An earlier version of src may have been uploaded. An in-progress
+			// upload of src is deleted when it completes.
+			err = cloud.AsContextBlobStore(u.backing).DeleteContext(ctx, src)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if rename {
+		err = cloud.RenameContext(ctx, u.backing, src, dst)
+	} else {
+		err = cloud.CopyContext(ctx, u.backing, src, dst)
+	}
+	if err != nil {
+		return err
+	}
+
+	srcCompleted := u.makeCompletedName(src)
+	if rename {
+		err = os.Rename(srcCompleted, dstCompleted)
+	} else {
+		err = u.linkReplace(srcCompleted, dstCompleted)
+	}
+	if err == nil {
+		u.completedLru.Add(dstCompleted, true)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Unable to copy staged blob %s to %s: %v", src, dst, err)
+	}
+	if rename {
+		u.completedLru.Remove(srcCompleted)
+		os.Remove(srcCompleted)
+		u.fileCache.Remove(srcCompleted)
+	}
+	return nil
 }
 
 type fileReader struct {
@@ -286,6 +645,10 @@ type fileReader struct {
 }
 
 func (r *fileReader) ReadAt(b []byte, off int64) (int, error) {
+	return r.ReadAtContext(context.Background(), b, off)
+}
+
+func (r *fileReader) ReadAtContext(ctx context.Context, b []byte, off int64) (int, error) {
 	const maxTries = 2
 
 	r.lock.Lock()
@@ -308,7 +671,7 @@ func (r *fileReader) ReadAt(b []byte, off int64) (int, error) {
 
 	if r.backingReader == nil {
 		var err error
-		r.backingReader, err = r.u.backing.Get(r.key)
+		r.backingReader, err = cloud.AsContextBlobStore(r.u.backing).GetContext(ctx, r.key)
 		if err != nil {
 			r.lock.Unlock()
 			return 0, err
@@ -317,7 +680,7 @@ func (r *fileReader) ReadAt(b []byte, off int64) (int, error) {
 	br := r.backingReader
 	r.lock.Unlock()
 
-	return br.ReadAt(b, off)
+	return cloud.ReadAtContext(ctx, br, b, off)
 }
 
 func (r *fileReader) Size() int64 {
@@ -334,6 +697,14 @@ func (r *fileReader) Close() error {
 }
 
 func (u *StagedBlobUploader) Get(key string) (cloud.GetReader, error) {
+	return u.GetContext(context.Background(), key)
+}
+
+func (u *StagedBlobUploader) GetContext(ctx context.Context, key string) (cloud.GetReader, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	fname := u.findStagedBlob(key)
 	if fname != "" {
 		f, err := os.Open(fname)
@@ -348,13 +719,14 @@ func (u *StagedBlobUploader) Get(key string) (cloud.GetReader, error) {
 			log.Printf("Unable to open for reading %s: %v", fname, err)
 		}
 	}
-	return u.backing.Get(key)
+	return cloud.AsContextBlobStore(u.backing).GetContext(ctx, key)
 }
 
 type pendingWriter struct {
-	u   *StagedBlobUploader
-	f   *os.File
-	key string
+	u       *StagedBlobUploader
+	f       *os.File
+	key     string
+	options *cloud.PutOptions
 }
 
 func (w *pendingWriter) Write(b []byte) (int, error) {
@@ -377,6 +749,10 @@ func (w *pendingWriter) Close() (err error) {
 		return
 	}
 
+	err = w.u.writeOptions(w.key, w.options)
+	if err != nil {
+		return
+	}
 	err = os.Rename(w.f.Name(), w.u.makePendingName(w.key))
 	if err != nil {
 		return
@@ -398,15 +774,39 @@ func (w *pendingWriter) Cancel() error {
 }
 
 func (u *StagedBlobUploader) Put(key string) (cloud.PutWriter, error) {
+	return u.PutContext(context.Background(), key)
+}
+
+// The context only bounds staging the blob locally. The upload to the backing
+// store happens asynchronously and is not bound by the context.
+func (u *StagedBlobUploader) PutContext(ctx context.Context, key string) (cloud.PutWriter, error) {
+	return u.PutWithOptionsContext(ctx, key, nil)
+}
+
+func (u *StagedBlobUploader) PutWithOptions(key string, options *cloud.PutOptions) (cloud.PutWriter, error) {
+	return u.PutWithOptionsContext(context.Background(), key, options)
+}
+
+func (u *StagedBlobUploader) PutWithOptionsContext(ctx context.Context, key string, options *cloud.PutOptions) (cloud.PutWriter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if options.HasPreconditions() {
+		return nil, cloud.ErrCallNotSupported
+	} else if options != nil && !cloud.GetBlobCapabilities(u.backing).Attributes {
+		// Fail now, rather than retrying the upload forever.
+		return nil, cloud.ErrCallNotSupported
+	}
+
 	// TODO: Check blob does not already exist
-	f, err := os.CreateTemp(u.dir, tempPrefix+"*")
+	f, err := util.CreateTempFile(u.dir, tempPrefix+"*")
 	if err != nil {
 		return nil, err
 	}
 	w := &pendingWriter{
-		u:   u,
-		f:   f,
-		key: key,
+		u:       u,
+		f:       f,
+		key:     key,
+		options: options,
 	}
 	return w, nil
 }
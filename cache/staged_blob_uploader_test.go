@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
+)
+
+func readBlob(t *testing.T, bs cloud.BlobStore, key string) []byte {
+	t.Helper()
+
+	r, err := bs.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%s) error = %v", key, err)
+	}
+	defer r.Close()
+	buf, err := io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+	if err != nil {
+		t.Fatalf("Read(%s) error = %v", key, err)
+	}
+	return buf
+}
+
+// Waits until there are no pending uploads, and done returns true.
+func waitUploads(t *testing.T, dir string, done func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		dirents, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pending := false
+		for _, e := range dirents {
+			if strings.HasPrefix(e.Name(), pendingPrefix) {
+				pending = true
+			}
+		}
+		if !pending && done() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for uploads")
+}
+
+func TestStagedBlobUploader_Copy(t *testing.T) {
+	backing, err := local.NewDirBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	u, err := NewStagedBlobUploader(backing, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("some blob data")
+	w, err := u.Put("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "a" may or may not have been uploaded yet.
+	err = u.Copy("a", "b")
+	if err != nil {
+		t.Fatalf("Copy(a, b) error = %v", err)
+	}
+	err = u.Rename("a", "c")
+	if err != nil {
+		t.Fatalf("Rename(a, c) error = %v", err)
+	}
+	for _, key := range []string{"b", "c"} {
+		if buf := readBlob(t, u, key); !bytes.Equal(buf, data) {
+			t.Errorf("Read(%s) %q != expected %q", key, buf, data)
+		}
+	}
+
+	waitUploads(t, dir, func() bool {
+		_, err := backing.Size("a")
+		return errors.Is(err, os.ErrNotExist)
+	})
+	for _, key := range []string{"b", "c"} {
+		if buf := readBlob(t, backing, key); !bytes.Equal(buf, data) {
+			t.Errorf("Backing read(%s) %q != expected %q", key, buf, data)
+		}
+	}
+	_, err = u.Size("a")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Size(a) error %v != expected os.ErrNotExist", err)
+	}
+
+	// Copy of uploaded blobs.
+	err = u.Rename("c", "d")
+	if err != nil {
+		t.Fatalf("Rename(c, d) error = %v", err)
+	}
+	if buf := readBlob(t, u, "d"); !bytes.Equal(buf, data) {
+		t.Errorf("Read(d) %q != expected %q", buf, data)
+	}
+	if buf := readBlob(t, backing, "d"); !bytes.Equal(buf, data) {
+		t.Errorf("Backing read(d) %q != expected %q", buf, data)
+	}
+	_, err = u.Size("c")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Size(c) error %v != expected os.ErrNotExist", err)
+	}
+}
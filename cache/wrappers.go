@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/akmistry/cloud-util"
+)
+
+func init() {
+	cloud.RegisterBlobStoreWrapper("block-cache", wrapBlockBlobCache)
+	cloud.RegisterBlobStoreWrapper("staged-upload", wrapStagedBlobUploader)
+}
+
+// Path format:
+//
+//	block-cache(<dir>,<cache size in bytes>[,<block size in bytes>[,<readahead blocks>[,<validation TTL>]]])|<inner blob store>
+//
+// The validation TTL is a duration, i.e. "30s". A negative TTL revalidates
+// blobs on every call.
+func wrapBlockBlobCache(args []string, bs cloud.BlobStore) (cloud.BlobStore, error) {
+	if len(args) < 2 || len(args) > 5 || args[0] == "" {
+		return nil, fmt.Errorf("cloud/cache: block-cache wrapper expects a directory and size")
+	}
+	size, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || size <= 0 {
+		return nil, fmt.Errorf("cloud/cache: invalid block-cache size: %s", args[1])
+	}
+	var options BlockBlobCacheOptions
+	if len(args) > 2 {
+		options.BlockSize, err = strconv.ParseInt(args[2], 10, 64)
+		if err != nil || options.BlockSize <= 0 {
+			return nil, fmt.Errorf("cloud/cache: invalid block-cache block size: %s", args[2])
+		}
+	}
+	if len(args) > 3 {
+		options.ReadaheadBlocks, err = strconv.Atoi(args[3])
+		if err != nil || options.ReadaheadBlocks < 0 {
+			return nil, fmt.Errorf("cloud/cache: invalid block-cache readahead: %s", args[3])
+		}
+	}
+	if len(args) > 4 {
+		options.ValidationTTL, err = time.ParseDuration(args[4])
+		if err != nil {
+			return nil, fmt.Errorf("cloud/cache: invalid block-cache validation TTL: %s", args[4])
+		}
+	}
+	return NewBlockBlobCacheWithOptions(bs, args[0], size, &options)
+}
+
+// Path format: staged-upload(<dir>)|<inner blob store>
+func wrapStagedBlobUploader(args []string, bs cloud.BlobStore) (cloud.BlobStore, error) {
+	if len(args) != 1 || args[0] == "" {
+		return nil, fmt.Errorf("cloud/cache: staged-upload wrapper expects a directory")
+	}
+	return NewStagedBlobUploader(bs, args[0])
+}
@@ -0,0 +1,104 @@
+package cloud
+
+type Consistency int
+
+const (
+	ConsistencyUnknown Consistency = iota
+	// Reads may not reflect recent writes.
+	ConsistencyEventual
+	// Reads always reflect all completed writes.
+	ConsistencyStrong
+)
+
+func (c Consistency) String() string {
+	switch c {
+	case ConsistencyEventual:
+		return "eventual"
+	case ConsistencyStrong:
+		return "strong"
+	default:
+		return "unknown"
+	}
+}
+
+// Describes the operations supported by a store. Wrappers may implement
+// optional interfaces (i.e. AtomicUnorderedStore) and still return
+// ErrCallNotSupported, so callers should check capabilities rather than
+// relying on type assertions.
+type Capabilities struct {
+	// AtomicPut and AtomicDelete are supported.
+	Atomic bool
+	// ListKeys is supported, and returns keys in order.
+	Ordered bool
+	// WriteOptions.TTL is respected.
+	TTL bool
+	// BatchStore is implemented natively, rather than emulated.
+	Batch bool
+	// Watcher is implemented natively, rather than by polling.
+	Watch bool
+	// TxnStore is supported.
+	Txn bool
+
+	// Maximum length of values and keys, in bytes. 0 if unlimited or unknown.
+	MaxValueSize int64
+	MaxKeySize   int
+
+	Consistency Consistency
+}
+
+type CapabilitiesProvider interface {
+	Capabilities() Capabilities
+}
+
+// GetCapabilities returns s.Capabilities() if s implements
+// CapabilitiesProvider. Otherwise, capabilities are inferred from the
+// interfaces s implements.
+func GetCapabilities(s UnorderedStore) Capabilities {
+	if p, ok := s.(CapabilitiesProvider); ok {
+		return p.Capabilities()
+	}
+
+	var caps Capabilities
+	_, caps.Atomic = s.(AtomicUnorderedStore)
+	_, caps.Ordered = s.(KeysLister)
+	_, caps.Batch = s.(BatchStore)
+	_, caps.Watch = s.(Watcher)
+	_, caps.Txn = s.(TxnStore)
+	return caps
+}
+
+// Describes the operations supported by a blob store.
+type BlobCapabilities struct {
+	// Lister and PageLister are supported.
+	List bool
+	// PutOptions are stored, and returned by Attributes.
+	Attributes bool
+	// PutOptions preconditions are supported.
+	Preconditions bool
+
+	// Maximum size of a blob, in bytes. 0 if unlimited or unknown.
+	MaxBlobSize int64
+
+	Consistency Consistency
+}
+
+type BlobCapabilitiesProvider interface {
+	Capabilities() BlobCapabilities
+}
+
+// GetBlobCapabilities returns bs.Capabilities() if bs implements
+// BlobCapabilitiesProvider. Otherwise, capabilities are inferred from the
+// interfaces bs implements.
+func GetBlobCapabilities(bs BlobStore) BlobCapabilities {
+	if p, ok := bs.(BlobCapabilitiesProvider); ok {
+		return p.Capabilities()
+	}
+
+	var caps BlobCapabilities
+	_, caps.List = bs.(Lister)
+	if _, ok := bs.(PageLister); ok {
+		caps.List = true
+	}
+	_, caps.Attributes = bs.(AttributesBlobStore)
+	return caps
+}
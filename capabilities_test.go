@@ -0,0 +1,31 @@
+package cloud_test
+
+import (
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
+)
+
+func TestGetCapabilities(t *testing.T) {
+	caps := cloud.GetCapabilities(local.NewInMemoryStore())
+	expected := cloud.Capabilities{
+		Atomic:      true,
+		Ordered:     true,
+		TTL:         true,
+		Batch:       true,
+		Watch:       true,
+		Txn:         true,
+		Consistency: cloud.ConsistencyStrong,
+	}
+	if caps != expected {
+		t.Errorf("InMemoryStore capabilities %+v != expected %+v", caps, expected)
+	}
+
+	// Inferred from the implemented interfaces.
+	caps = cloud.GetCapabilities(plainStore{local.NewInMemoryStore()})
+	expected = cloud.Capabilities{Ordered: true}
+	if caps != expected {
+		t.Errorf("Inferred capabilities %+v != expected %+v", caps, expected)
+	}
+}
@@ -0,0 +1,204 @@
+package cloud
+
+import (
+	"context"
+	"io"
+)
+
+// Context-aware variant of UnorderedStore. Implementations should abandon the
+// operation and return ctx.Err() (possibly wrapped) when the context is done.
+type ContextStore interface {
+	GetContext(ctx context.Context, key string) (*KVPair, error)
+	ExistsContext(ctx context.Context, key string) (bool, error)
+	PutContext(ctx context.Context, key string, value []byte, options *WriteOptions) error
+	DeleteContext(ctx context.Context, key string) error
+}
+
+// Context-aware variant of AtomicUnorderedStore.
+type AtomicContextStore interface {
+	ContextStore
+
+	AtomicPutContext(ctx context.Context, key string, value []byte, previous *KVPair, options *WriteOptions) (bool, *KVPair, error)
+	AtomicDeleteContext(ctx context.Context, key string, previous *KVPair) (bool, error)
+}
+
+// Context-aware variant of KeysLister.
+type ContextKeysLister interface {
+	ListKeysContext(ctx context.Context, start string) ([]string, error)
+}
+
+// Context-aware variant of BlobStore. The context passed to GetContext only
+// bounds opening the blob. Use ReadAtContext to bound individual reads. The
+// context passed to PutContext bounds the entire write, up to and including
+// Close.
+type ContextBlobStore interface {
+	SizeContext(ctx context.Context, key string) (int64, error)
+	GetContext(ctx context.Context, key string) (GetReader, error)
+	PutContext(ctx context.Context, key string) (PutWriter, error)
+	DeleteContext(ctx context.Context, key string) error
+}
+
+// Optionally implemented by a GetReader to support cancelable reads.
+type ContextReaderAt interface {
+	ReadAtContext(ctx context.Context, b []byte, off int64) (int, error)
+}
+
+// AsContextStore returns s as a ContextStore. If s does not natively
+// implement ContextStore, the returned adapter only checks the context before
+// starting each operation, and operations can not be interrupted once started.
+func AsContextStore(s UnorderedStore) ContextStore {
+	if cs, ok := s.(ContextStore); ok {
+		return cs
+	}
+	return &contextStoreAdapter{s: s}
+}
+
+// AsAtomicContextStore is the AtomicUnorderedStore equivalent of
+// AsContextStore.
+func AsAtomicContextStore(s AtomicUnorderedStore) AtomicContextStore {
+	if cs, ok := s.(AtomicContextStore); ok {
+		return cs
+	}
+	return &atomicContextStoreAdapter{contextStoreAdapter{s: s}, s}
+}
+
+// AsContextBlobStore is the BlobStore equivalent of AsContextStore.
+func AsContextBlobStore(bs BlobStore) ContextBlobStore {
+	if cbs, ok := bs.(ContextBlobStore); ok {
+		return cbs
+	}
+	return &contextBlobStoreAdapter{bs: bs}
+}
+
+// ListKeysContext calls l.ListKeysContext if implemented. Otherwise, the
+// context is checked before calling l.ListKeys.
+func ListKeysContext(ctx context.Context, l KeysLister, start string) ([]string, error) {
+	if cl, ok := l.(ContextKeysLister); ok {
+		return cl.ListKeysContext(ctx, start)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return l.ListKeys(start)
+}
+
+// ReadAtContext calls r.ReadAtContext if implemented. Otherwise, the context
+// is checked before calling r.ReadAt.
+func ReadAtContext(ctx context.Context, r io.ReaderAt, b []byte, off int64) (int, error) {
+	if cr, ok := r.(ContextReaderAt); ok {
+		return cr.ReadAtContext(ctx, b, off)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.ReadAt(b, off)
+}
+
+type contextStoreAdapter struct {
+	s UnorderedStore
+}
+
+func (a *contextStoreAdapter) GetContext(ctx context.Context, key string) (*KVPair, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.s.Get(key)
+}
+
+func (a *contextStoreAdapter) ExistsContext(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return a.s.Exists(key)
+}
+
+func (a *contextStoreAdapter) PutContext(ctx context.Context, key string, value []byte, options *WriteOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.s.Put(key, value, options)
+}
+
+func (a *contextStoreAdapter) DeleteContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.s.Delete(key)
+}
+
+type atomicContextStoreAdapter struct {
+	contextStoreAdapter
+	as AtomicUnorderedStore
+}
+
+func (a *atomicContextStoreAdapter) AtomicPutContext(ctx context.Context, key string, value []byte, previous *KVPair, options *WriteOptions) (bool, *KVPair, error) {
+	if err := ctx.Err(); err != nil {
+		return false, nil, err
+	}
+	return a.as.AtomicPut(key, value, previous, options)
+}
+
+func (a *atomicContextStoreAdapter) AtomicDeleteContext(ctx context.Context, key string, previous *KVPair) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return a.as.AtomicDelete(key, previous)
+}
+
+type contextBlobStoreAdapter struct {
+	bs BlobStore
+}
+
+func (a *contextBlobStoreAdapter) SizeContext(ctx context.Context, key string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return a.bs.Size(key)
+}
+
+func (a *contextBlobStoreAdapter) GetContext(ctx context.Context, key string) (GetReader, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.bs.Get(key)
+}
+
+func (a *contextBlobStoreAdapter) PutContext(ctx context.Context, key string) (PutWriter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	w, err := a.bs.Put(key)
+	if err != nil {
+		return nil, err
+	}
+	return &contextPutWriter{PutWriter: w, ctx: ctx}, nil
+}
+
+func (a *contextBlobStoreAdapter) DeleteContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.bs.Delete(key)
+}
+
+// Cancels the underlying writer if the context is done before Close.
+type contextPutWriter struct {
+	PutWriter
+	ctx context.Context
+}
+
+func (w *contextPutWriter) Write(b []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		w.PutWriter.Cancel()
+		return 0, err
+	}
+	return w.PutWriter.Write(b)
+}
+
+func (w *contextPutWriter) Close() error {
+	if err := w.ctx.Err(); err != nil {
+		w.PutWriter.Cancel()
+		return err
+	}
+	return w.PutWriter.Close()
+}
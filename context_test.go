@@ -0,0 +1,191 @@
+package cloud_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
+)
+
+// Hides the context interfaces of the wrapped store.
+type plainAtomicStore struct {
+	cloud.AtomicUnorderedStore
+}
+
+type plainLister struct {
+	cloud.KeysLister
+}
+
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func TestAsContextStore(t *testing.T) {
+	ms := local.NewInMemoryStore()
+	if cs := cloud.AsContextStore(ms); cs != cloud.ContextStore(ms) {
+		t.Errorf("AsContextStore didn't return the native implementation")
+	}
+
+	cs := cloud.AsContextStore(&plainAtomicStore{ms})
+	ctx := context.Background()
+	if err := cs.PutContext(ctx, "a", []byte("1"), nil); err != nil {
+		t.Fatalf("PutContext(a) error = %v", err)
+	}
+	if p, err := cs.GetContext(ctx, "a"); err != nil || string(p.Value) != "1" {
+		t.Errorf("GetContext(a) = %v, %v", p, err)
+	}
+	if ok, err := cs.ExistsContext(ctx, "a"); err != nil || !ok {
+		t.Errorf("ExistsContext(a) = %v, %v", ok, err)
+	}
+
+	// Operations aren't started once the context is done.
+	ctx = canceledContext()
+	if err := cs.PutContext(ctx, "b", []byte("2"), nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("PutContext(b) error = %v", err)
+	}
+	if ok, _ := ms.Exists("b"); ok {
+		t.Errorf("PutContext(b) with canceled context wrote the key")
+	}
+	if _, err := cs.GetContext(ctx, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetContext(a) error = %v", err)
+	}
+	if _, err := cs.ExistsContext(ctx, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExistsContext(a) error = %v", err)
+	}
+	if err := cs.DeleteContext(ctx, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("DeleteContext(a) error = %v", err)
+	}
+	if ok, _ := ms.Exists("a"); !ok {
+		t.Errorf("DeleteContext(a) with canceled context deleted the key")
+	}
+
+	if err := cs.DeleteContext(context.Background(), "a"); err != nil {
+		t.Errorf("DeleteContext(a) error = %v", err)
+	}
+}
+
+func TestAsAtomicContextStore(t *testing.T) {
+	ms := local.NewInMemoryStore()
+	if cs := cloud.AsAtomicContextStore(ms); cs != cloud.AtomicContextStore(ms) {
+		t.Errorf("AsAtomicContextStore didn't return the native implementation")
+	}
+
+	cs := cloud.AsAtomicContextStore(&plainAtomicStore{ms})
+	ok, p, err := cs.AtomicPutContext(context.Background(), "a", []byte("1"), nil, nil)
+	if err != nil || !ok {
+		t.Fatalf("AtomicPutContext(a) = %v, %v", ok, err)
+	}
+
+	ctx := canceledContext()
+	if _, _, err := cs.AtomicPutContext(ctx, "a", []byte("2"), p, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("AtomicPutContext(a) error = %v", err)
+	}
+	if _, err := cs.AtomicDeleteContext(ctx, "a", p); !errors.Is(err, context.Canceled) {
+		t.Errorf("AtomicDeleteContext(a) error = %v", err)
+	}
+	if _, err := cs.GetContext(ctx, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetContext(a) error = %v", err)
+	}
+
+	if ok, err := cs.AtomicDeleteContext(context.Background(), "a", p); err != nil || !ok {
+		t.Errorf("AtomicDeleteContext(a) = %v, %v", ok, err)
+	}
+}
+
+func TestListKeysContext(t *testing.T) {
+	ms := local.NewInMemoryStore()
+	ms.Put("a", []byte("1"), nil)
+
+	for _, l := range []cloud.KeysLister{ms, &plainLister{ms}} {
+		keys, err := cloud.ListKeysContext(context.Background(), l, "")
+		if err != nil || len(keys) != 1 || keys[0] != "a" {
+			t.Errorf("ListKeysContext(%T) = %v, %v", l, keys, err)
+		}
+		_, err = cloud.ListKeysContext(canceledContext(), l, "")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("ListKeysContext(%T) error = %v", l, err)
+		}
+	}
+}
+
+func TestReadAtContext(t *testing.T) {
+	r := strings.NewReader("hello")
+	buf := make([]byte, 3)
+	n, err := cloud.ReadAtContext(context.Background(), r, buf, 1)
+	if err != nil || string(buf[:n]) != "ell" {
+		t.Errorf("ReadAtContext = %q, %v", buf[:n], err)
+	}
+	n, err = cloud.ReadAtContext(canceledContext(), r, buf, 1)
+	if n != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadAtContext = %d, %v", n, err)
+	}
+}
+
+func TestAsContextBlobStore(t *testing.T) {
+	mbs := local.NewMemBlobStore()
+	if cbs := cloud.AsContextBlobStore(mbs); cbs != cloud.ContextBlobStore(mbs) {
+		t.Errorf("AsContextBlobStore didn't return the native implementation")
+	}
+
+	cbs := cloud.AsContextBlobStore(&plainBlobStore{mbs})
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := cbs.PutContext(ctx, "a")
+	if err != nil {
+		t.Fatalf("PutContext(a) error = %v", err)
+	}
+	w.Write([]byte("hello"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	r, err := cbs.GetContext(ctx, "a")
+	if err != nil {
+		t.Fatalf("GetContext(a) error = %v", err)
+	}
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+	r.Close()
+	if err != nil || string(data) != "hello" {
+		t.Errorf("GetContext(a) data = %q, %v", data, err)
+	}
+	if size, err := cbs.SizeContext(ctx, "a"); err != nil || size != 5 {
+		t.Errorf("SizeContext(a) = %d, %v", size, err)
+	}
+
+	// Writes are canceled if the context is done before Close.
+	w, err = cbs.PutContext(ctx, "b")
+	if err != nil {
+		t.Fatalf("PutContext(b) error = %v", err)
+	}
+	w.Write([]byte("partial"))
+	cancel()
+	if _, err := w.Write([]byte("more")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Write() after cancel error = %v", err)
+	}
+	if err := w.Close(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Close() after cancel error = %v", err)
+	}
+	if _, err := mbs.Size("b"); err == nil {
+		t.Errorf("Canceled write to b was committed")
+	}
+
+	if _, err := cbs.PutContext(ctx, "c"); !errors.Is(err, context.Canceled) {
+		t.Errorf("PutContext(c) error = %v", err)
+	}
+	if _, err := cbs.GetContext(ctx, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetContext(a) error = %v", err)
+	}
+	if _, err := cbs.SizeContext(ctx, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("SizeContext(a) error = %v", err)
+	}
+	if err := cbs.DeleteContext(ctx, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("DeleteContext(a) error = %v", err)
+	}
+	if err := cbs.DeleteContext(context.Background(), "a"); err != nil {
+		t.Errorf("DeleteContext(a) error = %v", err)
+	}
+}
@@ -1,11 +1,13 @@
 package crypto
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
 
 	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/store_util"
 )
 
 type ScrambleFunc func(string) string
@@ -16,6 +18,11 @@ type ScrambledKeyStore struct {
 }
 
 var _ = (cloud.UnorderedStore)((*ScrambledKeyStore)(nil))
+var _ = (cloud.AtomicContextStore)((*ScrambledKeyStore)(nil))
+var _ = (cloud.BatchStore)((*ScrambledKeyStore)(nil))
+var _ = (cloud.ContextBatchStore)((*ScrambledKeyStore)(nil))
+var _ = (cloud.TxnStore)((*ScrambledKeyStore)(nil))
+var _ = (cloud.CapabilitiesProvider)((*ScrambledKeyStore)(nil))
 
 func NewScrambledKeyStore(s cloud.UnorderedStore, keyFunc ScrambleFunc) *ScrambledKeyStore {
 	return &ScrambledKeyStore{
@@ -39,6 +46,16 @@ func NewHMacSha256KeyStore(s cloud.UnorderedStore, salt []byte) *ScrambledKeySto
 	return NewScrambledKeyStore(s, keyFunc)
 }
 
+func (s *ScrambledKeyStore) Capabilities() cloud.Capabilities {
+	caps := cloud.GetCapabilities(s.s)
+	// Scrambling doesn't preserve key order.
+	caps.Ordered = false
+	caps.Watch = false
+	// Keys of any length are scrambled to a fixed length.
+	caps.MaxKeySize = 0
+	return caps
+}
+
 func (s *ScrambledKeyStore) makeKey(key string) string {
 	return s.keyFunc(key)
 }
@@ -72,3 +89,89 @@ func (s *ScrambledKeyStore) AtomicDelete(key string, previous *cloud.KVPair) (bo
 	}
 	return false, cloud.ErrCallNotSupported
 }
+
+func (s *ScrambledKeyStore) GetContext(ctx context.Context, key string) (*cloud.KVPair, error) {
+	return cloud.AsContextStore(s.s).GetContext(ctx, s.makeKey(key))
+}
+
+func (s *ScrambledKeyStore) ExistsContext(ctx context.Context, key string) (bool, error) {
+	return cloud.AsContextStore(s.s).ExistsContext(ctx, s.makeKey(key))
+}
+
+func (s *ScrambledKeyStore) PutContext(ctx context.Context, key string, value []byte, options *cloud.WriteOptions) error {
+	return cloud.AsContextStore(s.s).PutContext(ctx, s.makeKey(key), value, options)
+}
+
+func (s *ScrambledKeyStore) DeleteContext(ctx context.Context, key string) error {
+	return cloud.AsContextStore(s.s).DeleteContext(ctx, s.makeKey(key))
+}
+
+func (s *ScrambledKeyStore) AtomicPutContext(ctx context.Context, key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+	if as, ok := s.s.(cloud.AtomicUnorderedStore); ok {
+		return cloud.AsAtomicContextStore(as).AtomicPutContext(ctx, s.makeKey(key), value, previous, options)
+	}
+	return false, nil, cloud.ErrCallNotSupported
+}
+
+func (s *ScrambledKeyStore) AtomicDeleteContext(ctx context.Context, key string, previous *cloud.KVPair) (bool, error) {
+	if as, ok := s.s.(cloud.AtomicUnorderedStore); ok {
+		return cloud.AsAtomicContextStore(as).AtomicDeleteContext(ctx, s.makeKey(key), previous)
+	}
+	return false, cloud.ErrCallNotSupported
+}
+
+func (s *ScrambledKeyStore) Txn(txn *cloud.Txn) error {
+	return s.TxnContext(context.Background(), txn)
+}
+
+func (s *ScrambledKeyStore) TxnContext(ctx context.Context, txn *cloud.Txn) error {
+	innerTxn := &cloud.Txn{
+		Compares: make([]cloud.TxnCompare, len(txn.Compares)),
+		Ops:      make([]cloud.TxnOp, len(txn.Ops)),
+	}
+	for i, c := range txn.Compares {
+		innerTxn.Compares[i] = c
+		innerTxn.Compares[i].Key = s.makeKey(c.Key)
+	}
+	for i, op := range txn.Ops {
+		innerTxn.Ops[i] = op
+		innerTxn.Ops[i].Key = s.makeKey(op.Key)
+	}
+	return cloud.TxnContext(ctx, s.s, innerTxn)
+}
+
+func (s *ScrambledKeyStore) makeKeys(keys []string) []string {
+	innerKeys := make([]string, len(keys))
+	for i, k := range keys {
+		innerKeys[i] = s.makeKey(k)
+	}
+	return innerKeys
+}
+
+func (s *ScrambledKeyStore) GetMulti(keys []string) ([]*cloud.KVPair, error) {
+	return s.GetMultiContext(context.Background(), keys)
+}
+
+func (s *ScrambledKeyStore) GetMultiContext(ctx context.Context, keys []string) ([]*cloud.KVPair, error) {
+	return store_util.GetMultiContext(ctx, s.s, s.makeKeys(keys))
+}
+
+func (s *ScrambledKeyStore) PutMulti(pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	return s.PutMultiContext(context.Background(), pairs, options)
+}
+
+func (s *ScrambledKeyStore) PutMultiContext(ctx context.Context, pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	innerPairs := make([]*cloud.KVPair, len(pairs))
+	for i, p := range pairs {
+		innerPairs[i] = &cloud.KVPair{Key: s.makeKey(p.Key), Value: p.Value}
+	}
+	return store_util.PutMultiContext(ctx, s.s, innerPairs, options)
+}
+
+func (s *ScrambledKeyStore) DeleteMulti(keys []string) error {
+	return s.DeleteMultiContext(context.Background(), keys)
+}
+
+func (s *ScrambledKeyStore) DeleteMultiContext(ctx context.Context, keys []string) error {
+	return store_util.DeleteMultiContext(ctx, s.s, s.makeKeys(keys))
+}
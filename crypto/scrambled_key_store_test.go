@@ -0,0 +1,44 @@
+package crypto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
+	"github.com/akmistry/cloud-util/store_util"
+)
+
+// Hides the TxnStore implementation of the wrapped store.
+type atomicOnlyStore struct {
+	cloud.AtomicUnorderedStore
+}
+
+func TestScrambledKeyStore_ReadModifyWrite(t *testing.T) {
+	stores := []cloud.UnorderedStore{
+		NewHMacSha256KeyStore(local.NewInMemoryStore(), []byte("salt")),
+		// Falls back to AtomicPut if the wrapped store has no Txn.
+		NewHMacSha256KeyStore(atomicOnlyStore{local.NewInMemoryStore()}, []byte("salt")),
+	}
+	for _, s := range stores {
+		for i := 0; i < 3; i++ {
+			err := store_util.ReadModifyWrite(context.Background(), s, []string{"key"},
+				func(pairs []*cloud.KVPair) ([]cloud.TxnOp, error) {
+					value := []byte("x")
+					if pairs[0] != nil {
+						value = append(pairs[0].Value, 'x')
+					}
+					return []cloud.TxnOp{{Key: "key", Value: value}}, nil
+				})
+			if err != nil {
+				t.Fatalf("ReadModifyWrite error = %v", err)
+			}
+		}
+		p, err := s.Get("key")
+		if err != nil {
+			t.Fatalf("Get(key) error = %v", err)
+		} else if string(p.Value) != "xxx" {
+			t.Errorf("Value %s != expected xxx", p.Value)
+		}
+	}
+}
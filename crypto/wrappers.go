@@ -0,0 +1,19 @@
+package crypto
+
+import (
+	"fmt"
+
+	"github.com/akmistry/cloud-util"
+)
+
+func init() {
+	cloud.RegisterStoreWrapper("hmac", wrapHMacSha256KeyStore)
+}
+
+// Path format: hmac(<salt>)|<inner store>
+func wrapHMacSha256KeyStore(args []string, s cloud.UnorderedStore) (cloud.UnorderedStore, error) {
+	if len(args) != 1 || args[0] == "" {
+		return nil, fmt.Errorf("cloud/crypto: hmac wrapper expects a salt")
+	}
+	return NewHMacSha256KeyStore(s, []byte(args[0])), nil
+}
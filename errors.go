@@ -0,0 +1,165 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// Classification of errors returned by stores, independent of the backend.
+type ErrorKind int
+
+const (
+	KindUnknown ErrorKind = iota
+	KindNotFound
+	KindAlreadyExists
+	KindPreconditionFailed
+	// The request was rate limited, and may be retried after backing off.
+	KindThrottled
+	// The backend is temporarily unavailable, and the request may be retried.
+	KindUnavailable
+	KindPermissionDenied
+	// The context was canceled, or its deadline exceeded.
+	KindCanceled
+	// Stored data failed an integrity check.
+	KindCorrupt
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindAlreadyExists:
+		return "already exists"
+	case KindPreconditionFailed:
+		return "precondition failed"
+	case KindThrottled:
+		return "throttled"
+	case KindUnavailable:
+		return "unavailable"
+	case KindPermissionDenied:
+		return "permission denied"
+	case KindCanceled:
+		return "canceled"
+	case KindCorrupt:
+		return "corrupt"
+	default:
+		return "unknown"
+	}
+}
+
+// Sentinels for each kind, for use with errors.Is. ErrPreconditionFailed is
+// the sentinel for KindPreconditionFailed.
+var (
+	ErrNotFound         = errors.New("cloud: not found")
+	ErrAlreadyExists    = errors.New("cloud: already exists")
+	ErrThrottled        = errors.New("cloud: throttled")
+	ErrUnavailable      = errors.New("cloud: unavailable")
+	ErrPermissionDenied = errors.New("cloud: permission denied")
+	ErrCanceled         = errors.New("cloud: canceled")
+	ErrCorrupt          = errors.New("cloud: corrupt")
+)
+
+// Errors which are classified as each kind, in addition to *Error.
+var kindErrors = map[ErrorKind][]error{
+	KindNotFound:           {ErrNotFound, ErrKeyNotFound, os.ErrNotExist},
+	KindAlreadyExists:      {ErrAlreadyExists, ErrKeyExists, os.ErrExist},
+	KindPreconditionFailed: {ErrPreconditionFailed, ErrKeyModified},
+	KindThrottled:          {ErrThrottled},
+	KindUnavailable:        {ErrUnavailable},
+	KindPermissionDenied:   {ErrPermissionDenied, os.ErrPermission},
+	KindCanceled:           {ErrCanceled, context.Canceled, context.DeadlineExceeded},
+	KindCorrupt:            {ErrCorrupt},
+}
+
+// Error is returned by stores to classify backend errors. It matches the
+// sentinel for its kind with errors.Is. KindNotFound errors also match
+// os.ErrNotExist, which blob stores have historically returned.
+//
+// KV stores return libkv's sentinel errors (i.e. ErrKeyNotFound) unwrapped,
+// for compatibility with libkv. Use KindOf to classify any error.
+type Error struct {
+	// Operation, i.e. "get".
+	Op   string
+	Key  string
+	Kind ErrorKind
+	// Underlying error, which may be nil.
+	Err error
+}
+
+func NewError(op, key string, kind ErrorKind, err error) *Error {
+	return &Error{Op: op, Key: key, Kind: kind, Err: err}
+}
+
+func (e *Error) Error() string {
+	msg := "cloud:"
+	if e.Op != "" {
+		msg += " " + e.Op
+	}
+	if e.Key != "" {
+		msg += " " + e.Key
+	}
+	if e.Op != "" || e.Key != "" {
+		msg += ":"
+	}
+	msg += " " + e.Kind.String()
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	switch e.Kind {
+	case KindNotFound:
+		return target == ErrNotFound || target == os.ErrNotExist
+	case KindAlreadyExists:
+		return target == ErrAlreadyExists || target == os.ErrExist
+	case KindPreconditionFailed:
+		return target == ErrPreconditionFailed
+	case KindThrottled:
+		return target == ErrThrottled
+	case KindUnavailable:
+		return target == ErrUnavailable
+	case KindPermissionDenied:
+		return target == ErrPermissionDenied || target == os.ErrPermission
+	case KindCanceled:
+		return target == ErrCanceled
+	case KindCorrupt:
+		return target == ErrCorrupt
+	}
+	return false
+}
+
+// KindOf classifies err. Returns KindUnknown if err is nil or unrecognised.
+func KindOf(err error) ErrorKind {
+	if err == nil {
+		return KindUnknown
+	}
+	var e *Error
+	if errors.As(err, &e) && e.Kind != KindUnknown {
+		return e.Kind
+	}
+	for kind, errs := range kindErrors {
+		for _, target := range errs {
+			if errors.Is(err, target) {
+				return kind
+			}
+		}
+	}
+	return KindUnknown
+}
+
+// IsRetryable returns true if err is transient, and the operation may succeed
+// if retried.
+func IsRetryable(err error) bool {
+	switch KindOf(err) {
+	case KindThrottled, KindUnavailable:
+		return true
+	}
+	return false
+}
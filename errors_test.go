@@ -0,0 +1,62 @@
+package cloud_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+)
+
+func TestKindOf(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind cloud.ErrorKind
+	}{
+		{nil, cloud.KindUnknown},
+		{errors.New("foo"), cloud.KindUnknown},
+		{cloud.ErrKeyNotFound, cloud.KindNotFound},
+		{os.ErrNotExist, cloud.KindNotFound},
+		{cloud.ErrKeyExists, cloud.KindAlreadyExists},
+		{cloud.ErrKeyModified, cloud.KindPreconditionFailed},
+		{cloud.ErrPreconditionFailed, cloud.KindPreconditionFailed},
+		{context.Canceled, cloud.KindCanceled},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), cloud.KindCanceled},
+		{cloud.NewError("get", "a", cloud.KindThrottled, nil), cloud.KindThrottled},
+		{fmt.Errorf("wrapped: %w", cloud.NewError("get", "a", cloud.KindUnavailable, nil)), cloud.KindUnavailable},
+		{cloud.NewError("get", "a", cloud.KindUnknown, os.ErrPermission), cloud.KindPermissionDenied},
+		{fmt.Errorf("wrapped: %w", cloud.ErrCorrupt), cloud.KindCorrupt},
+	}
+	for _, c := range cases {
+		if kind := cloud.KindOf(c.err); kind != c.kind {
+			t.Errorf("KindOf(%v) %v != expected %v", c.err, kind, c.kind)
+		}
+	}
+}
+
+func TestError_Is(t *testing.T) {
+	err := cloud.NewError("get", "a", cloud.KindNotFound, errors.New("missing"))
+	if !errors.Is(err, cloud.ErrNotFound) {
+		t.Errorf("%v is not ErrNotFound", err)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%v is not os.ErrNotExist", err)
+	}
+	if errors.Is(err, cloud.ErrAlreadyExists) {
+		t.Errorf("%v is ErrAlreadyExists", err)
+	}
+
+	err = cloud.NewError("get", "a", cloud.KindCanceled, context.DeadlineExceeded)
+	if !errors.Is(err, cloud.ErrCanceled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("%v is not ErrCanceled and context.DeadlineExceeded", err)
+	}
+
+	if !cloud.IsRetryable(cloud.NewError("put", "a", cloud.KindThrottled, nil)) {
+		t.Errorf("Throttled error not retryable")
+	}
+	if cloud.IsRetryable(err) {
+		t.Errorf("%v retryable", err)
+	}
+}
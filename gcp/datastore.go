@@ -1,7 +1,6 @@
 package gcp
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"net/url"
@@ -19,6 +18,15 @@ const (
 
 	datastoreTimeout = time.Second
 	maxTries         = 5
+
+	// Datastore limits on the number of keys in a single request.
+	maxLookupKeys   = 1000
+	maxMutationKeys = 500
+
+	// Datastore limits on entity and key name sizes. The value must fit in
+	// the entity alongside the other properties.
+	maxEntitySize  = 1048572
+	maxKeyNameSize = 1500
 )
 
 type Datastore struct {
@@ -31,6 +39,14 @@ type Datastore struct {
 
 var _ = (cloud.OrderedStore)((*Datastore)(nil))
 var _ = (cloud.AtomicUnorderedStore)((*Datastore)(nil))
+var _ = (cloud.AtomicContextStore)((*Datastore)(nil))
+var _ = (cloud.ContextKeysLister)((*Datastore)(nil))
+var _ = (cloud.BatchStore)((*Datastore)(nil))
+var _ = (cloud.ContextBatchStore)((*Datastore)(nil))
+var _ = (cloud.Scanner)((*Datastore)(nil))
+var _ = (cloud.ContextScanner)((*Datastore)(nil))
+var _ = (cloud.TxnStore)((*Datastore)(nil))
+var _ = (cloud.CapabilitiesProvider)((*Datastore)(nil))
 
 func init() {
 	cloud.RegisterStoreScheme(scheme, openStore)
@@ -65,6 +81,27 @@ func openStore(path string) (cloud.UnorderedStore, error) {
 
 type Entity struct {
 	Value []byte `datastore:",noindex"`
+	// Entities written before versioning was introduced have a version of 0.
+	Version int64 `datastore:",noindex"`
+	// Expired entities are hidden from reads, except keys-only queries (i.e.
+	// ListKeys and keys-only scans). A TTL policy on this property can be used
+	// to have Datastore delete them.
+	Expiry time.Time `datastore:",noindex,omitempty"`
+}
+
+func (e *Entity) expired(now time.Time) bool {
+	return !e.Expiry.IsZero() && !now.Before(e.Expiry)
+}
+
+func (e *Entity) pair(key string) *cloud.KVPair {
+	return &cloud.KVPair{Key: key, Value: e.Value, LastIndex: uint64(e.Version)}
+}
+
+// Returns a version greater than prev. Versions are time-based so that writes
+// which don't read the previous version (i.e. Put), and keys which are deleted
+// and re-created, are still very likely to get a new version.
+func nextVersion(prev int64) int64 {
+	return max(prev+1, time.Now().UnixNano())
 }
 
 func NewDatastore(name string) (*Datastore, error) {
@@ -89,63 +126,98 @@ func NewDatastore(name string) (*Datastore, error) {
 	}, nil
 }
 
+func (s *Datastore) Capabilities() cloud.Capabilities {
+	return cloud.Capabilities{
+		Atomic:  true,
+		Ordered: true,
+		TTL:     true,
+		Batch:   true,
+		Txn:     true,
+		// Leave room for the version, expiry and property names.
+		MaxValueSize: maxEntitySize - 256,
+		MaxKeySize:   maxKeyNameSize,
+		Consistency:  cloud.ConsistencyStrong,
+	}
+}
+
 func (s *Datastore) createKey(k string) *datastore.Key {
 	return datastore.NameKey(s.entityKind, k, nil)
 }
 
-func (s *Datastore) Get(key string) (*cloud.KVPair, error) {
+func (s *Datastore) GetContext(ctx context.Context, key string) (*cloud.KVPair, error) {
 	s.requestsCounter.WithLabelValues("get").Inc()
 	var entity Entity
 	var lastErr error
 	for i := 0; i < maxTries; i++ {
-		ctx, cf := context.WithTimeout(context.Background(), datastoreTimeout)
-		err := s.client.Get(ctx, s.createKey(key), &entity)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		tctx, cf := context.WithTimeout(ctx, datastoreTimeout)
+		err := s.client.Get(tctx, s.createKey(key), &entity)
 		cf()
-		if err == datastore.ErrNoSuchEntity {
+		if err == datastore.ErrNoSuchEntity || (err == nil && entity.expired(time.Now())) {
 			return nil, cloud.ErrKeyNotFound
 		} else if err == nil {
-			return &cloud.KVPair{Key: key, Value: entity.Value}, nil
+			return entity.pair(key), nil
 		}
 		lastErr = err
 	}
-	return nil, fmt.Errorf("Datastore.Get exceeded max tries, lastErr: %v", lastErr)
+	return nil, wrapError("get", key, lastErr)
 }
 
-func (s *Datastore) Put(key string, value []byte, options *cloud.WriteOptions) error {
+func (s *Datastore) Get(key string) (*cloud.KVPair, error) {
+	return s.GetContext(context.Background(), key)
+}
+
+func (s *Datastore) PutContext(ctx context.Context, key string, value []byte, options *cloud.WriteOptions) error {
 	s.requestsCounter.WithLabelValues("put").Inc()
-	entity := Entity{Value: value}
+	entity := Entity{Value: value, Version: nextVersion(0), Expiry: cloud.Expiry(options)}
 	dsKey := s.createKey(key)
 	var lastErr error
 	for i := 0; i < maxTries; i++ {
-		ctx, cf := context.WithTimeout(context.Background(), datastoreTimeout)
-		_, err := s.client.Put(ctx, dsKey, &entity)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tctx, cf := context.WithTimeout(ctx, datastoreTimeout)
+		_, err := s.client.Put(tctx, dsKey, &entity)
 		cf()
 		if err == nil {
 			return nil
 		}
 		lastErr = err
 	}
-	return fmt.Errorf("Datastore.Put exceeded max tries, lastErr: %v", lastErr)
+	return wrapError("put", key, lastErr)
 }
 
-func (s *Datastore) Delete(key string) error {
+func (s *Datastore) Put(key string, value []byte, options *cloud.WriteOptions) error {
+	return s.PutContext(context.Background(), key, value, options)
+}
+
+func (s *Datastore) DeleteContext(ctx context.Context, key string) error {
 	s.requestsCounter.WithLabelValues("delete").Inc()
 	var lastErr error
 	dsKey := s.createKey(key)
 	for i := 0; i < maxTries; i++ {
-		ctx, cf := context.WithTimeout(context.Background(), datastoreTimeout)
-		err := s.client.Delete(ctx, dsKey)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tctx, cf := context.WithTimeout(ctx, datastoreTimeout)
+		err := s.client.Delete(tctx, dsKey)
 		cf()
 		if err == nil || err == datastore.ErrNoSuchEntity {
 			return nil
 		}
 		lastErr = err
 	}
-	return fmt.Errorf("Datastore.Delete exceeded max tries, lastErr: %v", lastErr)
+	return wrapError("delete", key, lastErr)
 }
 
-func (s *Datastore) Exists(key string) (bool, error) {
-	_, err := s.Get(key)
+func (s *Datastore) Delete(key string) error {
+	return s.DeleteContext(context.Background(), key)
+}
+
+func (s *Datastore) ExistsContext(ctx context.Context, key string) (bool, error) {
+	_, err := s.GetContext(ctx, key)
 	if err == cloud.ErrKeyNotFound {
 		return false, nil
 	} else if err != nil {
@@ -154,16 +226,166 @@ func (s *Datastore) Exists(key string) (bool, error) {
 	return true, nil
 }
 
-func (s *Datastore) AtomicPut(key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+func (s *Datastore) Exists(key string) (bool, error) {
+	return s.ExistsContext(context.Background(), key)
+}
+
+func (s *Datastore) createKeys(keys []string) []*datastore.Key {
+	dsKeys := make([]*datastore.Key, len(keys))
+	for i, k := range keys {
+		dsKeys[i] = s.createKey(k)
+	}
+	return dsKeys
+}
+
+func (s *Datastore) getMultiChunk(ctx context.Context, keys []string, pairs []*cloud.KVPair) error {
+	dsKeys := s.createKeys(keys)
+	var lastErr error
+	for i := 0; i < maxTries; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entities := make([]Entity, len(keys))
+		tctx, cf := context.WithTimeout(ctx, datastoreTimeout)
+		err := s.client.GetMulti(tctx, dsKeys, entities)
+		cf()
+		now := time.Now()
+		if err == nil {
+			for j, k := range keys {
+				if !entities[j].expired(now) {
+					pairs[j] = entities[j].pair(k)
+				}
+			}
+			return nil
+		}
+
+		multiErr, ok := err.(datastore.MultiError)
+		if !ok {
+			lastErr = err
+			continue
+		}
+		lastErr = nil
+		for j, k := range keys {
+			if multiErr[j] == nil {
+				if !entities[j].expired(now) {
+					pairs[j] = entities[j].pair(k)
+				}
+			} else if multiErr[j] != datastore.ErrNoSuchEntity {
+				lastErr = multiErr[j]
+			}
+		}
+		if lastErr == nil {
+			return nil
+		}
+	}
+	return wrapError("get", "", lastErr)
+}
+
+func (s *Datastore) GetMulti(keys []string) ([]*cloud.KVPair, error) {
+	return s.GetMultiContext(context.Background(), keys)
+}
+
+func (s *Datastore) GetMultiContext(ctx context.Context, keys []string) ([]*cloud.KVPair, error) {
+	s.requestsCounter.WithLabelValues("get_multi").Inc()
+	pairs := make([]*cloud.KVPair, len(keys))
+	for start := 0; start < len(keys); start += maxLookupKeys {
+		end := min(start+maxLookupKeys, len(keys))
+		err := s.getMultiChunk(ctx, keys[start:end], pairs[start:end])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pairs, nil
+}
+
+func (s *Datastore) PutMulti(pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	return s.PutMultiContext(context.Background(), pairs, options)
+}
+
+func (s *Datastore) PutMultiContext(ctx context.Context, pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	s.requestsCounter.WithLabelValues("put_multi").Inc()
+	expiry := cloud.Expiry(options)
+	for start := 0; start < len(pairs); start += maxMutationKeys {
+		chunk := pairs[start:min(start+maxMutationKeys, len(pairs))]
+		dsKeys := make([]*datastore.Key, len(chunk))
+		entities := make([]Entity, len(chunk))
+		for i, p := range chunk {
+			dsKeys[i] = s.createKey(p.Key)
+			entities[i].Value = p.Value
+			entities[i].Version = nextVersion(0)
+			entities[i].Expiry = expiry
+		}
+
+		var err error
+		for i := 0; i < maxTries; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			tctx, cf := context.WithTimeout(ctx, datastoreTimeout)
+			_, err = s.client.PutMulti(tctx, dsKeys, entities)
+			cf()
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return wrapError("put", "", err)
+		}
+	}
+	return nil
+}
+
+func (s *Datastore) DeleteMulti(keys []string) error {
+	return s.DeleteMultiContext(context.Background(), keys)
+}
+
+func (s *Datastore) DeleteMultiContext(ctx context.Context, keys []string) error {
+	s.requestsCounter.WithLabelValues("delete_multi").Inc()
+	for start := 0; start < len(keys); start += maxMutationKeys {
+		dsKeys := s.createKeys(keys[start:min(start+maxMutationKeys, len(keys))])
+
+		var err error
+		for i := 0; i < maxTries; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			tctx, cf := context.WithTimeout(ctx, datastoreTimeout)
+			err = s.client.DeleteMulti(tctx, dsKeys)
+			cf()
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return wrapError("delete", "", err)
+		}
+	}
+	return nil
+}
+
+// Returns the KV sentinel errors from a transaction as-is, and wraps
+// everything else.
+func txnError(op, key string, err error) error {
+	switch err {
+	case nil, cloud.ErrKeyNotFound, cloud.ErrKeyExists, cloud.ErrKeyModified:
+		return err
+	}
+	return wrapError(op, key, err)
+}
+
+func (s *Datastore) AtomicPutContext(ctx context.Context, key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
 	s.requestsCounter.WithLabelValues("put_txn").Inc()
 
-	entity := Entity{Value: value}
+	entity := Entity{Value: value, Expiry: cloud.Expiry(options)}
 	dsKey := s.createKey(key)
-	ctx, cf := context.WithTimeout(context.Background(), datastoreTimeout)
+	ctx, cf := context.WithTimeout(ctx, datastoreTimeout)
 	defer cf()
 	_, err := s.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
 		var oldEntity Entity
 		err := tx.Get(dsKey, &oldEntity)
+		if err == nil && oldEntity.expired(time.Now()) {
+			err = datastore.ErrNoSuchEntity
+		}
 		if err == datastore.ErrNoSuchEntity {
 			if previous != nil {
 				return cloud.ErrKeyNotFound
@@ -174,26 +396,27 @@ func (s *Datastore) AtomicPut(key string, value []byte, previous *cloud.KVPair,
 			return cloud.ErrKeyExists
 		}
 
-		if previous != nil && !bytes.Equal(previous.Value, oldEntity.Value) {
+		if previous != nil && !cloud.MatchesPrevious(previous, oldEntity.Value, uint64(oldEntity.Version)) {
 			return cloud.ErrKeyModified
 		}
 
+		entity.Version = nextVersion(oldEntity.Version)
 		_, err = tx.Put(dsKey, &entity)
 		return err
 	})
 
 	if err != nil {
-		return false, nil, err
+		return false, nil, txnError("put", key, err)
 	}
 
-	updated := &cloud.KVPair{
-		Key:   key,
-		Value: value,
-	}
-	return true, updated, nil
+	return true, entity.pair(key), nil
 }
 
-func (s *Datastore) AtomicDelete(key string, previous *cloud.KVPair) (bool, error) {
+func (s *Datastore) AtomicPut(key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+	return s.AtomicPutContext(context.Background(), key, value, previous, options)
+}
+
+func (s *Datastore) AtomicDeleteContext(ctx context.Context, key string, previous *cloud.KVPair) (bool, error) {
 	s.requestsCounter.WithLabelValues("delete_txn").Inc()
 
 	if previous == nil {
@@ -201,18 +424,18 @@ func (s *Datastore) AtomicDelete(key string, previous *cloud.KVPair) (bool, erro
 	}
 
 	dsKey := s.createKey(key)
-	ctx, cf := context.WithTimeout(context.Background(), datastoreTimeout)
+	ctx, cf := context.WithTimeout(ctx, datastoreTimeout)
 	defer cf()
 	_, err := s.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
 		var oldEntity Entity
 		err := tx.Get(dsKey, &oldEntity)
-		if err == datastore.ErrNoSuchEntity {
+		if err == datastore.ErrNoSuchEntity || (err == nil && oldEntity.expired(time.Now())) {
 			return cloud.ErrKeyNotFound
 		} else if err != nil {
 			return err
 		}
 
-		if !bytes.Equal(previous.Value, oldEntity.Value) {
+		if !cloud.MatchesPrevious(previous, oldEntity.Value, uint64(oldEntity.Version)) {
 			return cloud.ErrKeyModified
 		}
 
@@ -220,12 +443,72 @@ func (s *Datastore) AtomicDelete(key string, previous *cloud.KVPair) (bool, erro
 	})
 
 	if err != nil {
-		return false, err
+		return false, txnError("delete", key, err)
 	}
 	return true, nil
 }
 
-func (s *Datastore) ListKeys(start string) ([]string, error) {
+func (s *Datastore) AtomicDelete(key string, previous *cloud.KVPair) (bool, error) {
+	return s.AtomicDeleteContext(context.Background(), key, previous)
+}
+
+func (s *Datastore) TxnContext(ctx context.Context, txn *cloud.Txn) error {
+	s.requestsCounter.WithLabelValues("txn").Inc()
+	if err := txn.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cf := context.WithTimeout(ctx, datastoreTimeout)
+	defer cf()
+	_, err := s.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		now := time.Now()
+		for _, c := range txn.Compares {
+			var entity Entity
+			err := tx.Get(s.createKey(c.Key), &entity)
+			if err != nil && err != datastore.ErrNoSuchEntity {
+				return err
+			}
+			exists := err == nil && !entity.expired(now)
+			if exists != (c.Previous != nil) {
+				return cloud.ErrKeyModified
+			} else if exists && !cloud.MatchesPrevious(c.Previous, entity.Value, uint64(entity.Version)) {
+				return cloud.ErrKeyModified
+			}
+		}
+
+		for _, op := range txn.Ops {
+			dsKey := s.createKey(op.Key)
+			if op.Delete {
+				if err := tx.Delete(dsKey); err != nil {
+					return err
+				}
+				continue
+			}
+
+			var oldEntity Entity
+			err := tx.Get(dsKey, &oldEntity)
+			if err != nil && err != datastore.ErrNoSuchEntity {
+				return err
+			}
+			entity := Entity{
+				Value:   op.Value,
+				Version: nextVersion(oldEntity.Version),
+				Expiry:  cloud.Expiry(op.Options),
+			}
+			if _, err := tx.Put(dsKey, &entity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return txnError("txn", "", err)
+}
+
+func (s *Datastore) Txn(txn *cloud.Txn) error {
+	return s.TxnContext(context.Background(), txn)
+}
+
+func (s *Datastore) ListKeysContext(ctx context.Context, start string) ([]string, error) {
 	s.requestsCounter.WithLabelValues("list").Inc()
 	q := datastore.NewQuery(s.entityKind).KeysOnly().Limit(1024)
 	if len(start) > 0 {
@@ -233,7 +516,7 @@ func (s *Datastore) ListKeys(start string) ([]string, error) {
 	}
 
 	var keys []string
-	ctx, cf := context.WithTimeout(context.Background(), datastoreTimeout)
+	ctx, cf := context.WithTimeout(ctx, datastoreTimeout)
 	defer cf()
 	iter := s.client.Run(ctx, q)
 	for {
@@ -241,9 +524,89 @@ func (s *Datastore) ListKeys(start string) ([]string, error) {
 		if err == iterator.Done {
 			break
 		} else if err != nil {
-			return nil, fmt.Errorf("Datastore.List error getting next key: %v", err)
+			return nil, wrapError("list", "", err)
 		}
 		keys = append(keys, k.Name)
 	}
 	return keys, nil
 }
+
+func (s *Datastore) ListKeys(start string) ([]string, error) {
+	return s.ListKeysContext(context.Background(), start)
+}
+
+func (s *Datastore) Scan(start, end string, limit int, options *cloud.ScanOptions) ([]*cloud.KVPair, error) {
+	return s.ScanContext(context.Background(), start, end, limit, options)
+}
+
+// Expired entities are filtered out after being fetched, so the query is
+// repeated until limit live entities are found or the range is exhausted.
+func (s *Datastore) ScanContext(ctx context.Context, start, end string, limit int, options *cloud.ScanOptions) ([]*cloud.KVPair, error) {
+	s.requestsCounter.WithLabelValues("scan").Inc()
+
+	var opts cloud.ScanOptions
+	if options != nil {
+		opts = *options
+	}
+
+	q := datastore.NewQuery(s.entityKind)
+	if len(start) > 0 {
+		q = q.FilterField("__key__", ">=", s.createKey(start))
+	}
+	if len(end) > 0 {
+		q = q.FilterField("__key__", "<", s.createKey(end))
+	}
+	if opts.Reverse {
+		q = q.Order("-__key__")
+	} else {
+		q = q.Order("__key__")
+	}
+	if opts.KeysOnly {
+		q = q.KeysOnly()
+	}
+
+	now := time.Now()
+	var pairs []*cloud.KVPair
+	for {
+		pageQuery := q
+		remaining := limit - len(pairs)
+		if limit > 0 {
+			pageQuery = q.Limit(remaining)
+		}
+		cursor, fetched, err := s.scanPage(ctx, pageQuery, opts.KeysOnly, now, &pairs)
+		if err != nil {
+			return nil, wrapError("scan", "", err)
+		} else if limit <= 0 || len(pairs) >= limit || fetched < remaining {
+			return pairs, nil
+		}
+		q = q.Start(cursor)
+	}
+}
+
+// Runs q, appending live entities to pairs. Returns the cursor after the last
+// entity, and the number of entities fetched, including expired entities.
+func (s *Datastore) scanPage(ctx context.Context, q *datastore.Query, keysOnly bool, now time.Time, pairs *[]*cloud.KVPair) (datastore.Cursor, int, error) {
+	ctx, cf := context.WithTimeout(ctx, datastoreTimeout)
+	defer cf()
+	iter := s.client.Run(ctx, q)
+	fetched := 0
+	for {
+		var entity Entity
+		var dst interface{}
+		if !keysOnly {
+			dst = &entity
+		}
+		k, err := iter.Next(dst)
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return datastore.Cursor{}, 0, err
+		}
+		fetched++
+		if !entity.expired(now) {
+			*pairs = append(*pairs, entity.pair(k.Name))
+		}
+	}
+	cursor, err := iter.Cursor()
+	return cursor, fetched, err
+}
@@ -0,0 +1,73 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"cloud.google.com/go/datastore"
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akmistry/cloud-util"
+)
+
+// Classifies errors from the GCP client libraries, which use both HTTP and
+// gRPC transports.
+func classifyError(err error) cloud.ErrorKind {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return cloud.KindCanceled
+	} else if errors.Is(err, storage.ErrObjectNotExist) || errors.Is(err, storage.ErrBucketNotExist) ||
+		errors.Is(err, datastore.ErrNoSuchEntity) {
+		return cloud.KindNotFound
+	}
+
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		switch gerr.Code {
+		case http.StatusNotFound:
+			return cloud.KindNotFound
+		case http.StatusConflict:
+			return cloud.KindAlreadyExists
+		case http.StatusPreconditionFailed:
+			return cloud.KindPreconditionFailed
+		case http.StatusTooManyRequests:
+			return cloud.KindThrottled
+		case http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return cloud.KindUnavailable
+		case http.StatusForbidden, http.StatusUnauthorized:
+			return cloud.KindPermissionDenied
+		}
+		return cloud.KindUnknown
+	}
+
+	if st, ok := status.FromError(err); ok {
+		switch st.Code() {
+		case codes.NotFound:
+			return cloud.KindNotFound
+		case codes.AlreadyExists:
+			return cloud.KindAlreadyExists
+		case codes.FailedPrecondition, codes.Aborted:
+			return cloud.KindPreconditionFailed
+		case codes.ResourceExhausted:
+			return cloud.KindThrottled
+		case codes.Unavailable, codes.Internal:
+			return cloud.KindUnavailable
+		case codes.PermissionDenied, codes.Unauthenticated:
+			return cloud.KindPermissionDenied
+		case codes.Canceled, codes.DeadlineExceeded:
+			return cloud.KindCanceled
+		}
+	}
+	return cloud.KindUnknown
+}
+
+func wrapError(op, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return cloud.NewError(op, key, classifyError(err), err)
+}
@@ -4,20 +4,23 @@ import (
 	"bytes"
 	"context"
 	"crypto/md5"
+	"encoding/binary"
 	"fmt"
 	"hash"
 	"io"
-	"os"
+	"strconv"
 
 	"cloud.google.com/go/storage"
 	prom "github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sync/semaphore"
+	"google.golang.org/api/iterator"
 
 	"github.com/akmistry/cloud-util"
 )
 
 const (
 	maxPendingFetches = 512
+	maxObjectSize     = 5 << 40
 	contentSizeKey    = "cloudutil-content-size"
 )
 
@@ -52,23 +55,76 @@ func NewGcsStore(bucket string) *GcsStore {
 	}
 }
 
-func (s *GcsStore) Size(key string) (int64, error) {
+var _ = (cloud.BlobStore)((*GcsStore)(nil))
+var _ = (cloud.ContextBlobStore)((*GcsStore)(nil))
+var _ = (cloud.BlobCapabilitiesProvider)((*GcsStore)(nil))
+var _ = (cloud.AttributesBlobStore)((*GcsStore)(nil))
+var _ = (cloud.Lister)((*GcsStore)(nil))
+var _ = (cloud.PageLister)((*GcsStore)(nil))
+var _ = (cloud.Copier)((*GcsStore)(nil))
+
+func (s *GcsStore) Capabilities() cloud.BlobCapabilities {
+	return cloud.BlobCapabilities{
+		List:          true,
+		Attributes:    true,
+		Preconditions: true,
+		MaxBlobSize:   maxObjectSize,
+		Consistency:   cloud.ConsistencyStrong,
+	}
+}
+
+func (s *GcsStore) SizeContext(ctx context.Context, key string) (int64, error) {
 	s.requestsCounter.WithLabelValues("size").Add(1)
 
-	s.pendingSema.Acquire(context.Background(), 1)
+	err := s.pendingSema.Acquire(ctx, 1)
+	if err != nil {
+		return 0, err
+	}
 	defer s.pendingSema.Release(1)
 
 	obj := s.bucketHandle.Object(key)
-	attrs, err := obj.Attrs(context.TODO())
-	if err == storage.ErrObjectNotExist {
-		return 0, os.ErrNotExist
-	} else if err != nil {
-		return 0, err
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return 0, wrapError("size", key, err)
 	}
 
 	return attrs.Size, nil
 }
 
+func (s *GcsStore) Size(key string) (int64, error) {
+	return s.SizeContext(context.Background(), key)
+}
+
+func (s *GcsStore) AttributesContext(ctx context.Context, key string) (*cloud.BlobAttributes, error) {
+	s.requestsCounter.WithLabelValues("attrs").Add(1)
+
+	err := s.pendingSema.Acquire(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer s.pendingSema.Release(1)
+
+	obj := s.bucketHandle.Object(key)
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return nil, wrapError("attributes", key, err)
+	}
+
+	return &cloud.BlobAttributes{
+		Size:        attrs.Size,
+		ContentType: attrs.ContentType,
+		ETag:        strconv.FormatInt(attrs.Generation, 10),
+		ModTime:     attrs.Updated,
+		MD5:         attrs.MD5,
+		CRC32C:      binary.BigEndian.AppendUint32(nil, attrs.CRC32C),
+		Metadata:    attrs.Metadata,
+	}, nil
+}
+
+func (s *GcsStore) Attributes(key string) (*cloud.BlobAttributes, error) {
+	return s.AttributesContext(context.Background(), key)
+}
+
 type getReader struct {
 	s    *GcsStore
 	key  string
@@ -83,22 +139,23 @@ func (r *getReader) Size() int64 {
 	return r.size
 }
 
-func (r *getReader) ReadAt(b []byte, off int64) (int, error) {
+func (r *getReader) ReadAtContext(ctx context.Context, b []byte, off int64) (int, error) {
 	if off >= r.size {
 		return 0, io.EOF
 	}
 
 	r.s.requestsCounter.WithLabelValues("get").Add(1)
 
-	r.s.pendingSema.Acquire(context.Background(), 1)
+	err := r.s.pendingSema.Acquire(ctx, 1)
+	if err != nil {
+		return 0, err
+	}
 	defer r.s.pendingSema.Release(1)
 
 	obj := r.s.bucketHandle.Object(r.key)
-	reader, err := obj.NewRangeReader(context.TODO(), off, int64(len(b)))
-	if err == storage.ErrObjectNotExist {
-		return 0, os.ErrNotExist
-	} else if err != nil {
-		return 0, err
+	reader, err := obj.NewRangeReader(ctx, off, int64(len(b)))
+	if err != nil {
+		return 0, wrapError("read", r.key, err)
 	}
 
 	defer reader.Close()
@@ -113,12 +170,16 @@ func (r *getReader) ReadAt(b []byte, off int64) (int, error) {
 	return n, nil
 }
 
+func (r *getReader) ReadAt(b []byte, off int64) (int, error) {
+	return r.ReadAtContext(context.Background(), b, off)
+}
+
 func (r *getReader) Close() error {
 	return nil
 }
 
-func (s *GcsStore) Get(key string) (cloud.GetReader, error) {
-	size, err := s.Size(key)
+func (s *GcsStore) GetContext(ctx context.Context, key string) (cloud.GetReader, error) {
+	size, err := s.SizeContext(ctx, key)
 	if err != nil {
 		return nil, err
 	}
@@ -126,9 +187,14 @@ func (s *GcsStore) Get(key string) (cloud.GetReader, error) {
 	return r, nil
 }
 
+func (s *GcsStore) Get(key string) (cloud.GetReader, error) {
+	return s.GetContext(context.Background(), key)
+}
+
 type putWriter struct {
 	name   string
 	s      *GcsStore
+	ctx    context.Context
 	w      *storage.Writer
 	closed bool
 	size   int64
@@ -167,13 +233,16 @@ func (w *putWriter) Close() error {
 
 	w.s.pendingSema.Release(1)
 	err := w.w.Close()
-	if err != nil {
-		w.err = fmt.Errorf("GCS close error: %v", err)
+	if classifyError(err) == cloud.KindPreconditionFailed {
+		w.err = cloud.ErrPreconditionFailed
+		return w.err
+	} else if err != nil {
+		w.err = wrapError("put", w.name, err)
 		return w.err
 	}
 
 	// Verify size and md5 hash to make sure blob was written correctly.
-	attrs, err := w.s.bucketHandle.Object(w.name).Attrs(context.TODO())
+	attrs, err := w.s.bucketHandle.Object(w.name).Attrs(w.ctx)
 	if err != nil {
 		w.s.Delete(w.name)
 		w.err = fmt.Errorf("GCS put validation error: %v", err)
@@ -208,31 +277,155 @@ func (w *putWriter) Cancel() error {
 	return nil
 }
 
-func (s *GcsStore) Put(key string) (cloud.PutWriter, error) {
+func (s *GcsStore) PutContext(ctx context.Context, key string) (cloud.PutWriter, error) {
+	return s.PutWithOptionsContext(ctx, key, nil)
+}
+
+func (s *GcsStore) PutWithOptionsContext(ctx context.Context, key string, options *cloud.PutOptions) (cloud.PutWriter, error) {
 	s.requestsCounter.WithLabelValues("put").Add(1)
 
-	s.pendingSema.Acquire(context.Background(), 1)
+	err := s.pendingSema.Acquire(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
 
-	obj := s.bucketHandle.Object(key).If(storage.Conditions{DoesNotExist: true})
-	writer := obj.NewWriter(context.TODO())
+	obj := s.bucketHandle.Object(key)
+	if options.HasPreconditions() {
+		var conds storage.Conditions
+		if options.IfNotExist {
+			conds.DoesNotExist = true
+		} else {
+			conds.GenerationMatch, err = strconv.ParseInt(options.IfMatch, 10, 64)
+			if err != nil {
+				s.pendingSema.Release(1)
+				return nil, fmt.Errorf("cloud/gcp: invalid generation %q: %w", options.IfMatch, err)
+			}
+		}
+		obj = obj.If(conds)
+	}
+	writer := obj.NewWriter(ctx)
 	writer.ChunkSize = 0
 	writer.ContentType = "application/octet-stream"
 	writer.CacheControl = "no-transform"
+	if options != nil {
+		if options.ContentType != "" {
+			writer.ContentType = options.ContentType
+		}
+		writer.Metadata = options.Metadata
+	}
 
-	return &putWriter{name: key, s: s, w: writer, hasher: md5.New()}, nil
+	return &putWriter{name: key, s: s, ctx: ctx, w: writer, hasher: md5.New()}, nil
 }
 
-func (s *GcsStore) Delete(key string) error {
+func (s *GcsStore) Put(key string) (cloud.PutWriter, error) {
+	return s.PutContext(context.Background(), key)
+}
+
+func (s *GcsStore) PutWithOptions(key string, options *cloud.PutOptions) (cloud.PutWriter, error) {
+	return s.PutWithOptionsContext(context.Background(), key, options)
+}
+
+func (s *GcsStore) DeleteContext(ctx context.Context, key string) error {
 	s.requestsCounter.WithLabelValues("delete").Add(1)
 
-	s.pendingSema.Acquire(context.Background(), 1)
+	err := s.pendingSema.Acquire(ctx, 1)
+	if err != nil {
+		return err
+	}
 	defer s.pendingSema.Release(1)
 
 	obj := s.bucketHandle.Object(key)
-	err := obj.Delete(context.TODO())
-	if err == storage.ErrObjectNotExist {
-		return nil
+	err = obj.Delete(ctx)
+	return wrapError("delete", key, err)
+}
+
+func (s *GcsStore) Delete(key string) error {
+	return s.DeleteContext(context.Background(), key)
+}
+
+func (s *GcsStore) CopyContext(ctx context.Context, src, dst string) error {
+	s.requestsCounter.WithLabelValues("copy").Add(1)
+
+	err := s.pendingSema.Acquire(ctx, 1)
+	if err != nil {
+		return err
+	}
+	defer s.pendingSema.Release(1)
+
+	srcObj := s.bucketHandle.Object(src)
+	_, err = s.bucketHandle.Object(dst).CopierFrom(srcObj).Run(ctx)
+	return wrapError("copy", src, err)
+}
+
+func (s *GcsStore) Copy(src, dst string) error {
+	return s.CopyContext(context.Background(), src, dst)
+}
+
+// GCS has no rename operation, so this is a copy followed by a delete.
+func (s *GcsStore) RenameContext(ctx context.Context, src, dst string) error {
+	err := s.CopyContext(ctx, src, dst)
+	if err != nil {
+		return err
+	}
+	return s.DeleteContext(ctx, src)
+}
+
+func (s *GcsStore) Rename(src, dst string) error {
+	return s.RenameContext(context.Background(), src, dst)
+}
+
+func (s *GcsStore) ListPage(ctx context.Context, options *cloud.ListOptions, pageToken string) ([]cloud.BlobEntry, string, error) {
+	s.requestsCounter.WithLabelValues("list").Add(1)
+
+	var o cloud.ListOptions
+	if options != nil {
+		o = *options
+	}
+	pageSize := o.PageSize
+	if pageSize <= 0 {
+		pageSize = cloud.DefaultListPageSize
+	}
+
+	err := s.pendingSema.Acquire(ctx, 1)
+	if err != nil {
+		return nil, "", err
 	}
+	defer s.pendingSema.Release(1)
+
+	q := &storage.Query{
+		Prefix:    o.Prefix,
+		Delimiter: o.Delimiter,
+	}
+	if o.StartAfter != "" {
+		// StartOffset is inclusive.
+		q.StartOffset = o.StartAfter + "\x00"
+	}
+	err = q.SetAttrSelection([]string{"Name", "Size", "Updated"})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var objs []*storage.ObjectAttrs
+	it := s.bucketHandle.Objects(ctx, q)
+	next, err := iterator.NewPager(it, pageSize, pageToken).NextPage(&objs)
+	if err != nil {
+		return nil, "", wrapError("list", o.Prefix, err)
+	}
+	entries := make([]cloud.BlobEntry, 0, len(objs))
+	for _, obj := range objs {
+		if obj.Prefix != "" {
+			entries = append(entries, cloud.BlobEntry{Key: obj.Prefix, IsDir: true})
+			continue
+		}
+		entries = append(entries, cloud.BlobEntry{
+			Key:     obj.Name,
+			Size:    obj.Size,
+			ModTime: obj.Updated,
+		})
+	}
+	return entries, next, nil
+}
 
-	return err
+func (s *GcsStore) List() ([]string, error) {
+	return cloud.ListAll(context.Background(), s, "")
 }
@@ -32,22 +32,42 @@ func (a *libkvStoreAdapter) AtomicDelete(key string, previous *KVPair) (bool, er
 	return false, ErrCallNotSupported
 }
 
-func (*libkvStoreAdapter) Watch(key string, stopCh <-chan struct{}) (<-chan *store.KVPair, error) {
-	return nil, store.ErrCallNotSupported
+func (a *libkvStoreAdapter) Watch(key string, stopCh <-chan struct{}) (<-chan *store.KVPair, error) {
+	return Watch(a.UnorderedStore, key, stopCh)
 }
 
-func (*libkvStoreAdapter) WatchTree(directory string, stopCh <-chan struct{}) (<-chan []*store.KVPair, error) {
-	return nil, store.ErrCallNotSupported
+func (a *libkvStoreAdapter) WatchTree(directory string, stopCh <-chan struct{}) (<-chan []*store.KVPair, error) {
+	return WatchTree(a.UnorderedStore, directory, stopCh)
 }
 
-func (*libkvStoreAdapter) NewLock(key string, options *store.LockOptions) (store.Locker, error) {
-	return nil, store.ErrCallNotSupported
+func (a *libkvStoreAdapter) NewLock(key string, options *store.LockOptions) (store.Locker, error) {
+	if as, ok := a.UnorderedStore.(AtomicUnorderedStore); ok {
+		return NewLock(as, key, options), nil
+	}
+	return nil, ErrCallNotSupported
 }
 
-func (*libkvStoreAdapter) List(directory string) ([]*store.KVPair, error) {
-	return nil, store.ErrCallNotSupported
+// List returns all pairs with keys beginning with directory, or
+// ErrKeyNotFound if there are none, as other libkv stores do.
+func (a *libkvStoreAdapter) List(directory string) ([]*store.KVPair, error) {
+	os, ok := a.UnorderedStore.(OrderedStore)
+	if !ok {
+		return nil, ErrCallNotSupported
+	}
+	pairs, err := listPrefix(os, directory)
+	if err != nil {
+		return nil, err
+	} else if len(pairs) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return pairs, nil
 }
 
-func (*libkvStoreAdapter) DeleteTree(directory string) error {
-	return store.ErrCallNotSupported
+// DeleteTree deletes all keys beginning with directory.
+func (a *libkvStoreAdapter) DeleteTree(directory string) error {
+	os, ok := a.UnorderedStore.(OrderedStore)
+	if !ok {
+		return ErrCallNotSupported
+	}
+	return deletePrefix(os, directory)
 }
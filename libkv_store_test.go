@@ -0,0 +1,73 @@
+package cloud_test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
+)
+
+func testListTree(t *testing.T, s cloud.OrderedStore) {
+	kv := cloud.GetLibkvStore(s)
+
+	_, err := kv.List("dir/")
+	if err != cloud.ErrKeyNotFound {
+		t.Errorf("List(empty) error %v != expected cloud.ErrKeyNotFound", err)
+	}
+
+	keys := []string{"dir", "dir/a", "dir/b", "dir/sub/c", "dis", "dir0"}
+	// Enough keys to require listing multiple pages.
+	var expected []string
+	for i := 0; i < 40; i++ {
+		keys = append(keys, fmt.Sprintf("dir/n%02d", i))
+	}
+	for _, k := range keys {
+		err := s.Put(k, []byte(k), nil)
+		if err != nil {
+			t.Fatalf("Put(%s) error = %v", k, err)
+		}
+	}
+
+	pairs, err := kv.List("dir/")
+	if err != nil {
+		t.Fatalf("List error = %v", err)
+	}
+	for _, k := range keys {
+		if strings.HasPrefix(k, "dir/") {
+			expected = append(expected, k)
+		}
+	}
+	sort.Strings(expected)
+	if len(pairs) != len(expected) {
+		t.Fatalf("List returned %d pairs, expected %d", len(pairs), len(expected))
+	}
+	for i, p := range pairs {
+		if p.Key != expected[i] || string(p.Value) != expected[i] {
+			t.Errorf("List pair %s = %s, expected key and value %s", p.Key, p.Value, expected[i])
+		}
+	}
+
+	err = kv.DeleteTree("dir/")
+	if err != nil {
+		t.Fatalf("DeleteTree error = %v", err)
+	}
+	for _, k := range keys {
+		exists, err := s.Exists(k)
+		if err != nil {
+			t.Errorf("Exists(%s) error = %v", k, err)
+		} else if exists != (k == "dir" || k == "dis" || k == "dir0") {
+			t.Errorf("Exists(%s) = %v after DeleteTree", k, exists)
+		}
+	}
+}
+
+func TestLibkvStore_ListTree(t *testing.T) {
+	testListTree(t, local.NewInMemoryStore())
+}
+
+func TestLibkvStore_ListTreeFallback(t *testing.T) {
+	testListTree(t, plainStore{local.NewInMemoryStore()})
+}
@@ -0,0 +1,389 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"github.com/dgraph-io/badger"
+
+	"github.com/akmistry/cloud-util"
+)
+
+const (
+	storeScheme = "local"
+
+	badgerValueLogFileSize = 256 << 20
+	// Badger limits on key and value sizes.
+	badgerMaxKeySize   = 65000
+	badgerMaxValueSize = badgerValueLogFileSize / 2
+
+	badgerMaxListedKeys = 16
+
+	// Maximum number of attempts to commit a read-write transaction which
+	// conflicts with other transactions.
+	badgerMaxUpdateTries = 16
+)
+
+// Persistent, ordered store backed by Badger. Writes are synced to disk
+// before returning. Versions are Badger commit timestamps, which increase
+// on every write and are never reused.
+type BadgerStore struct {
+	db *badger.DB
+}
+
+var _ = (cloud.AtomicOrderedStore)((*BadgerStore)(nil))
+var _ = (cloud.AtomicContextStore)((*BadgerStore)(nil))
+var _ = (cloud.ContextKeysLister)((*BadgerStore)(nil))
+var _ = (cloud.TxnStore)((*BadgerStore)(nil))
+var _ = (cloud.CapabilitiesProvider)((*BadgerStore)(nil))
+
+func init() {
+	cloud.RegisterStoreScheme(storeScheme, openBadgerStore)
+}
+
+// URL format: local://<dir>, where dir is either an absolute or relative path.
+// Example:
+//
+//	local:///home/myhome/db -> /home/myhome/db (note the 3 /'s)
+//	local://db -> ./db (relative to current directory)
+func openBadgerStore(path string) (cloud.UnorderedStore, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	p := u.EscapedPath()
+	if u.Scheme != storeScheme {
+		return nil, fmt.Errorf("cloud/local: unexpected scheme: %s", u.Scheme)
+	} else if u.Host == "" && p == "" {
+		return nil, fmt.Errorf("cloud/local: empty path")
+	}
+
+	name := p
+	if u.Host != "" {
+		name = filepath.Join(u.Host, name)
+	}
+	return NewBadgerStore(name)
+}
+
+// NewBadgerStore opens the store in dir, creating it if it doesn't exist. The
+// store can only be opened by one process at a time.
+func NewBadgerStore(dir string) (*BadgerStore, error) {
+	opts := badger.DefaultOptions(dir)
+	opts.SyncWrites = true
+	opts.ValueLogFileSize = badgerValueLogFileSize
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &BadgerStore{db: db}, nil
+}
+
+func (s *BadgerStore) Capabilities() cloud.Capabilities {
+	return cloud.Capabilities{
+		Atomic:       true,
+		Ordered:      true,
+		TTL:          true,
+		Txn:          true,
+		MaxValueSize: badgerMaxValueSize,
+		MaxKeySize:   badgerMaxKeySize,
+		Consistency:  cloud.ConsistencyStrong,
+	}
+}
+
+func (s *BadgerStore) Close() error {
+	return s.db.Close()
+}
+
+// Runs fn in a read-write transaction, retrying if it conflicts with another
+// transaction. Returns badger.ErrConflict if every attempt conflicts.
+func (s *BadgerStore) update(fn func(txn *badger.Txn) error) error {
+	var err error
+	for i := 0; i < badgerMaxUpdateTries; i++ {
+		err = s.db.Update(fn)
+		if err != badger.ErrConflict {
+			return err
+		}
+	}
+	return err
+}
+
+// Returns the item for key, or nil if it doesn't exist.
+func getItem(txn *badger.Txn, key string) (*badger.Item, error) {
+	item, err := txn.Get([]byte(key))
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Returns true if item matches previous, as for AtomicPut.
+func matchesItem(item *badger.Item, previous *cloud.KVPair) (bool, error) {
+	if previous.LastIndex != 0 {
+		return previous.LastIndex == item.Version(), nil
+	}
+	value, err := item.ValueCopy(nil)
+	if err != nil {
+		return false, err
+	}
+	return cloud.MatchesPrevious(previous, value, item.Version()), nil
+}
+
+// Badger expiry times are in seconds, so they are rounded up to the next
+// second so that keys never expire early.
+func setEntry(txn *badger.Txn, key string, value []byte, options *cloud.WriteOptions) error {
+	e := badger.NewEntry([]byte(key), value)
+	if expiry := cloud.Expiry(options); !expiry.IsZero() {
+		e.ExpiresAt = uint64(expiry.Add(time.Second - 1).Unix())
+	}
+	return txn.SetEntry(e)
+}
+
+func (s *BadgerStore) Get(key string) (*cloud.KVPair, error) {
+	var pair *cloud.KVPair
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		value, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		pair = &cloud.KVPair{Key: key, Value: value, LastIndex: item.Version()}
+		return nil
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil, cloud.ErrKeyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+func (s *BadgerStore) Exists(key string) (bool, error) {
+	var exists bool
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := getItem(txn, key)
+		exists = item != nil
+		return err
+	})
+	return exists, err
+}
+
+func (s *BadgerStore) Put(key string, value []byte, options *cloud.WriteOptions) error {
+	return s.update(func(txn *badger.Txn) error {
+		return setEntry(txn, key, value, options)
+	})
+}
+
+func (s *BadgerStore) Delete(key string) error {
+	return s.update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+}
+
+func (s *BadgerStore) AtomicPut(key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+	// Prevents compaction from discarding the version written below, even if
+	// it is overwritten before it can be found.
+	guard := s.db.NewTransaction(false)
+	defer guard.Discard()
+
+	var readTs uint64
+	err := s.update(func(txn *badger.Txn) error {
+		readTs = txn.ReadTs()
+		item, err := getItem(txn, key)
+		if err != nil {
+			return err
+		} else if item == nil {
+			if previous != nil {
+				return cloud.ErrKeyNotFound
+			}
+		} else if previous == nil {
+			return cloud.ErrKeyExists
+		} else if ok, err := matchesItem(item, previous); err != nil {
+			return err
+		} else if !ok {
+			return cloud.ErrKeyModified
+		}
+		return setEntry(txn, key, value, options)
+	})
+	if err != nil {
+		return false, nil, err
+	}
+
+	version, err := s.committedVersion(key, readTs)
+	if err != nil {
+		return false, nil, err
+	}
+	updated := &cloud.KVPair{
+		Key:       key,
+		Value:     value,
+		LastIndex: version,
+	}
+	return true, updated, nil
+}
+
+// Returns the version of key written by a committed transaction which read
+// key at readTs. Badger doesn't expose commit timestamps, but any other write
+// to key between the read and the commit would have conflicted, so the
+// transaction's write is the oldest version of key newer than readTs.
+func (s *BadgerStore) committedVersion(key string, readTs uint64) (uint64, error) {
+	var version uint64
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.AllVersions = true
+		opts.PrefetchValues = false
+		opts.Prefix = []byte(key)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		// Versions are iterated newest first.
+		for it.Seek([]byte(key)); it.Valid(); it.Next() {
+			item := it.Item()
+			if !bytes.Equal(item.Key(), []byte(key)) || item.Version() <= readTs {
+				break
+			}
+			version = item.Version()
+		}
+		return nil
+	})
+	if err == nil && version == 0 {
+		err = fmt.Errorf("cloud/local: committed version of %s not found", key)
+	}
+	return version, err
+}
+
+func (s *BadgerStore) AtomicDelete(key string, previous *cloud.KVPair) (bool, error) {
+	if previous == nil {
+		return false, cloud.ErrPreviousNotSpecified
+	}
+
+	err := s.update(func(txn *badger.Txn) error {
+		item, err := getItem(txn, key)
+		if err != nil {
+			return err
+		} else if item == nil {
+			return cloud.ErrKeyNotFound
+		} else if ok, err := matchesItem(item, previous); err != nil {
+			return err
+		} else if !ok {
+			return cloud.ErrKeyModified
+		}
+		return txn.Delete([]byte(key))
+	})
+	return err == nil, err
+}
+
+func (s *BadgerStore) Txn(txn *cloud.Txn) error {
+	if err := txn.Validate(); err != nil {
+		return err
+	}
+
+	return s.update(func(btxn *badger.Txn) error {
+		for _, c := range txn.Compares {
+			item, err := getItem(btxn, c.Key)
+			if err != nil {
+				return err
+			} else if (item != nil) != (c.Previous != nil) {
+				return cloud.ErrKeyModified
+			} else if item == nil {
+				continue
+			}
+			if ok, err := matchesItem(item, c.Previous); err != nil {
+				return err
+			} else if !ok {
+				return cloud.ErrKeyModified
+			}
+		}
+
+		for _, op := range txn.Ops {
+			var err error
+			if op.Delete {
+				err = btxn.Delete([]byte(op.Key))
+			} else {
+				err = setEntry(btxn, op.Key, op.Value, op.Options)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BadgerStore) ListKeys(start string) ([]string, error) {
+	var keys []string
+	err := s.db.View(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.IteratorOptions{})
+		defer iter.Close()
+		for iter.Seek([]byte(start)); iter.Valid() && len(keys) < badgerMaxListedKeys; iter.Next() {
+			keys = append(keys, string(iter.Item().Key()))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *BadgerStore) GetContext(ctx context.Context, key string) (*cloud.KVPair, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.Get(key)
+}
+
+func (s *BadgerStore) ExistsContext(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.Exists(key)
+}
+
+func (s *BadgerStore) PutContext(ctx context.Context, key string, value []byte, options *cloud.WriteOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Put(key, value, options)
+}
+
+func (s *BadgerStore) DeleteContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(key)
+}
+
+func (s *BadgerStore) AtomicPutContext(ctx context.Context, key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+	if err := ctx.Err(); err != nil {
+		return false, nil, err
+	}
+	return s.AtomicPut(key, value, previous, options)
+}
+
+func (s *BadgerStore) AtomicDeleteContext(ctx context.Context, key string, previous *cloud.KVPair) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.AtomicDelete(key, previous)
+}
+
+func (s *BadgerStore) TxnContext(ctx context.Context, txn *cloud.Txn) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Txn(txn)
+}
+
+func (s *BadgerStore) ListKeysContext(ctx context.Context, start string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.ListKeys(start)
+}
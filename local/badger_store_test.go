@@ -0,0 +1,128 @@
+package local
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/test_util"
+)
+
+func newTestBadgerStore(t *testing.T) *BadgerStore {
+	s, err := NewBadgerStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewBadgerStore error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestBadgerStore(t *testing.T) {
+	test_util.TestUnorderedStore(t, newTestBadgerStore(t))
+	test_util.TestListKeys(t, newTestBadgerStore(t))
+	test_util.TestAtomicStore(t, newTestBadgerStore(t))
+	test_util.TestTxn(t, newTestBadgerStore(t))
+}
+
+// Badger expiry times have a granularity of 1 second, which is too coarse for
+// test_util.TestTTL.
+func TestBadgerStore_TTL(t *testing.T) {
+	s := newTestBadgerStore(t)
+
+	err := s.Put("expiring", []byte("value"), &cloud.WriteOptions{TTL: 500 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Put(expiring) error = %v", err)
+	}
+	if ok, err := s.Exists("expiring"); err != nil || !ok {
+		t.Errorf("Exists(expiring) = %v, %v", ok, err)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	if ok, err := s.Exists("expiring"); err != nil || ok {
+		t.Errorf("Exists(expiring) after expiry = %v, %v", ok, err)
+	}
+	keys, err := s.ListKeys("")
+	if err != nil || len(keys) != 0 {
+		t.Errorf("ListKeys() = %v, %v", keys, err)
+	}
+}
+
+func TestBadgerStore_Reopen(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "db")
+	s, err := cloud.OpenAtomicOrderedStore("local://" + dir)
+	if err != nil {
+		t.Fatalf("OpenAtomicOrderedStore error = %v", err)
+	}
+	_, created, err := s.AtomicPut("key", []byte("value"), nil, nil)
+	if err != nil {
+		t.Fatalf("AtomicPut(key) error = %v", err)
+	}
+	cloud.DoStoreClose(s)
+
+	s, err = cloud.OpenAtomicOrderedStore("local://" + dir)
+	if err != nil {
+		t.Fatalf("OpenAtomicOrderedStore error = %v", err)
+	}
+	defer cloud.DoStoreClose(s)
+	p, err := s.Get("key")
+	if err != nil {
+		t.Fatalf("Get(key) error = %v", err)
+	} else if string(p.Value) != "value" || p.LastIndex != created.LastIndex {
+		t.Errorf("Get(key) = %v, expected value with version %d", p, created.LastIndex)
+	}
+
+	// Versions continue to increase after reopening.
+	_, updated, err := s.AtomicPut("key", []byte("value2"), p, nil)
+	if err != nil {
+		t.Fatalf("AtomicPut(key) error = %v", err)
+	} else if updated.LastIndex <= created.LastIndex {
+		t.Errorf("AtomicPut(key) LastIndex %d <= previous %d", updated.LastIndex, created.LastIndex)
+	}
+}
+
+// Concurrent AtomicPuts of the same value must each return the version they
+// wrote.
+func TestBadgerStore_AtomicPutVersion(t *testing.T) {
+	s := newTestBadgerStore(t)
+	const (
+		workers = 4
+		puts    = 50
+	)
+
+	var lock sync.Mutex
+	versions := make(map[uint64]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < puts; {
+				previous, err := s.Get("key")
+				if err == cloud.ErrKeyNotFound {
+					previous = nil
+				} else if err != nil {
+					t.Errorf("Get(key) error = %v", err)
+					return
+				}
+				ok, pair, err := s.AtomicPut("key", []byte("value"), previous, nil)
+				if err == cloud.ErrKeyModified || err == cloud.ErrKeyExists {
+					continue
+				} else if err != nil || !ok {
+					t.Errorf("AtomicPut(key) = %v, %v", ok, err)
+					return
+				}
+				j++
+
+				lock.Lock()
+				if pair.LastIndex == 0 || versions[pair.LastIndex] {
+					t.Errorf("AtomicPut(key) returned version %d more than once", pair.LastIndex)
+				}
+				versions[pair.LastIndex] = true
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
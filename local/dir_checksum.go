@@ -0,0 +1,303 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/akmistry/cloud-util"
+)
+
+const (
+	// Granularity of block checksums, which is the minimum amount of data
+	// read to verify a ReadAt.
+	checksumBlockSize = 64 * 1024
+
+	// Corrupt blobs are moved here by Scrub.
+	quarantineDirName = "quarantine"
+)
+
+// Computes the CRC32C of each block written.
+type blockSummer struct {
+	sums []uint32
+	crc  uint32
+	// Bytes written to the current block.
+	n int
+}
+
+func (s *blockSummer) Write(b []byte) (int, error) {
+	written := len(b)
+	for len(b) > 0 {
+		chunk := min(len(b), checksumBlockSize-s.n)
+		s.crc = crc32.Update(s.crc, crc32cTable, b[:chunk])
+		s.n += chunk
+		b = b[chunk:]
+		if s.n == checksumBlockSize {
+			s.sums = append(s.sums, s.crc)
+			s.crc = 0
+			s.n = 0
+		}
+	}
+	return written, nil
+}
+
+// Sums returns the checksums of all blocks, including the final partial block.
+func (s *blockSummer) Sums() []uint32 {
+	sums := s.sums
+	if s.n > 0 {
+		sums = append(sums, s.crc)
+	}
+	return sums
+}
+
+// Verifies the blocks of a blob file against their checksums. Blob files are
+// never modified in place, so each block is only verified once per reader.
+type blockVerifier struct {
+	blockSize int64
+	sums      []uint32
+	size      int64
+
+	lock     sync.Mutex
+	verified []bool
+}
+
+// Returns nil if the checksums don't match the blob's size.
+func newBlockVerifier(blockSize int64, sums []uint32, size int64) *blockVerifier {
+	if blockSize <= 0 || int64(len(sums)) != (size+blockSize-1)/blockSize {
+		return nil
+	}
+	return &blockVerifier{
+		blockSize: blockSize,
+		sums:      sums,
+		size:      size,
+		verified:  make([]bool, len(sums)),
+	}
+}
+
+// Returns the range of whole blocks covering length bytes at off.
+func (v *blockVerifier) blockRange(off int64, length int) (int64, int64) {
+	start := off / v.blockSize * v.blockSize
+	end := min((off+int64(length)+v.blockSize-1)/v.blockSize*v.blockSize, v.size)
+	return start, end
+}
+
+// Verifies buf, which holds the whole blocks of the blob starting at off.
+func (v *blockVerifier) check(key string, buf []byte, off int64) error {
+	for i := int64(0); i < int64(len(buf)); i += v.blockSize {
+		block := (off + i) / v.blockSize
+		v.lock.Lock()
+		verified := v.verified[block]
+		v.lock.Unlock()
+		if verified {
+			continue
+		}
+
+		n := min(v.blockSize, int64(len(buf))-i)
+		if crc32.Checksum(buf[i:i+n], crc32cTable) != v.sums[block] {
+			return cloud.NewError("get", key, cloud.KindCorrupt,
+				fmt.Errorf("checksum mismatch in block at offset %d", off+i))
+		}
+
+		v.lock.Lock()
+		v.verified[block] = true
+		v.lock.Unlock()
+	}
+	return nil
+}
+
+// Returns true if the blocks in [start, end) have all been verified.
+func (v *blockVerifier) isVerified(start, end int64) bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	for block := start / v.blockSize; block*v.blockSize < end; block++ {
+		if !v.verified[block] {
+			return false
+		}
+	}
+	return true
+}
+
+// Reads b at off from f, verifying the blocks read. Unverified blocks are
+// read whole, and verified from the same buffer b is filled from, so data is
+// only read once. Block-aligned reads are read directly into b.
+func (v *blockVerifier) readAt(f io.ReaderAt, key string, b []byte, off int64) (int, error) {
+	if off < 0 || off >= v.size || len(b) == 0 {
+		return f.ReadAt(b, off)
+	}
+	start, end := v.blockRange(off, len(b))
+	if v.isVerified(start, end) {
+		return f.ReadAt(b, off)
+	}
+
+	direct := start == off && end-start <= int64(len(b))
+	var buf []byte
+	if direct {
+		buf = b[:end-start]
+	} else {
+		buf = make([]byte, end-start)
+	}
+	n, err := f.ReadAt(buf, start)
+	if int64(n) < end-start {
+		if err == nil || err == io.EOF {
+			// The blob file is never truncated in place.
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	err = v.check(key, buf, start)
+	if err != nil {
+		return 0, err
+	}
+
+	if direct {
+		n = len(buf)
+	} else {
+		n = copy(b, buf[off-start:])
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+type ScrubOptions struct {
+	// Move corrupt blobs, and their attributes, out of the store into the
+	// quarantine directory. Otherwise, corrupt blobs are only reported.
+	Quarantine bool
+}
+
+// Result of DirBlobStore.Scrub.
+type ScrubResult struct {
+	// Number of blobs checked.
+	Scanned int
+	// Blobs without stored checksums, which can't be checked.
+	Unverified []string
+	// Blobs whose contents don't match their checksums.
+	Corrupt []string
+}
+
+// Scrub reads every blob and checks its contents against the checksums stored
+// when it was written. Blobs which are overwritten or deleted while being
+// checked are skipped.
+func (s *DirBlobStore) Scrub(ctx context.Context, options *ScrubOptions) (*ScrubResult, error) {
+	var opts ScrubOptions
+	if options != nil {
+		opts = *options
+	}
+
+	keys, err := s.listKeys()
+	if err != nil {
+		return nil, err
+	}
+	result := &ScrubResult{}
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		paths, fi, ok, err := s.checkBlob(key)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return result, fmt.Errorf("cloud/local: error checking %s: %w", key, err)
+		}
+		result.Scanned++
+		if fi == nil {
+			result.Unverified = append(result.Unverified, key)
+			continue
+		} else if ok {
+			continue
+		}
+
+		result.Corrupt = append(result.Corrupt, key)
+		if opts.Quarantine {
+			err = s.quarantine(paths, fi)
+			if err != nil {
+				return result, fmt.Errorf("cloud/local: error quarantining %s: %w", key, err)
+			}
+		}
+	}
+	return result, nil
+}
+
+// Checks the blob's contents against its stored checksums. Returns a nil
+// file info if the blob has no checksums.
+func (s *DirBlobStore) checkBlob(key string) (blobPaths, fs.FileInfo, bool, error) {
+	paths, _, err := s.findBlob(key)
+	if err != nil {
+		return paths, nil, false, err
+	}
+	f, err := os.Open(paths.blob)
+	if err != nil {
+		return paths, nil, false, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return paths, nil, false, err
+	}
+	stored, err := readAttrs(paths.attrs, key, fi)
+	if err != nil {
+		return paths, nil, false, err
+	} else if stored == nil || len(stored.CRC32C) == 0 {
+		return paths, nil, false, nil
+	}
+
+	crc := crc32.New(crc32cTable)
+	var blockSums blockSummer
+	_, err = io.Copy(io.MultiWriter(crc, &blockSums), f)
+	if err != nil {
+		return paths, nil, false, err
+	}
+	ok := bytes.Equal(crc.Sum(nil), stored.CRC32C)
+	if ok && len(stored.BlockCRC32C) > 0 {
+		sums := blockSums.Sums()
+		ok = stored.BlockSize == checksumBlockSize && len(sums) == len(stored.BlockCRC32C)
+		for i := 0; ok && i < len(sums); i++ {
+			ok = sums[i] == stored.BlockCRC32C[i]
+		}
+	}
+	return paths, fi, ok, nil
+}
+
+// Moves a corrupt blob and its attributes to the quarantine directory, unless
+// the blob has been replaced since it was checked.
+func (s *DirBlobStore) quarantine(paths blobPaths, checked fs.FileInfo) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	fi, err := os.Stat(paths.blob)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if !os.SameFile(fi, checked) {
+		return nil
+	}
+
+	dir := filepath.Join(s.dir, quarantineDirName)
+	err = os.MkdirAll(dir, 0750)
+	if err != nil {
+		return err
+	}
+	// Blobs may be quarantined more than once.
+	prefix := strconv.FormatInt(time.Now().UnixNano(), 16) + "-"
+	base := filepath.Base(paths.blob)
+	err = os.Rename(paths.attrs, filepath.Join(dir, prefix+filepath.Base(paths.attrs)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.Rename(paths.blob, filepath.Join(dir, prefix+base))
+}
@@ -0,0 +1,172 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+)
+
+// Flips a byte of key's blob file, without changing its modification time, as
+// bit rot would.
+func corruptBlob(t *testing.T, s *DirBlobStore, key string, off int64) {
+	t.Helper()
+	path := s.makePaths(key).blob
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1)
+	f.ReadAt(b, off)
+	b[0] ^= 0xff
+	_, err = f.WriteAt(b, off)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chtimes(path, fi.ModTime(), fi.ModTime())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirBlobStore_Checksums(t *testing.T) {
+	s, err := NewDirBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 3*checksumBlockSize+100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	putBlob(t, s, "blob", string(data))
+	putBlob(t, s, "good", "good data")
+	corruptBlob(t, s, "blob", 2*checksumBlockSize+10)
+
+	r, err := s.Get("blob")
+	if err != nil {
+		t.Fatalf("Get(blob) error = %v", err)
+	}
+	defer r.Close()
+	buf := make([]byte, 100)
+	if _, err := r.ReadAt(buf, checksumBlockSize); err != nil {
+		t.Errorf("ReadAt uncorrupted block error = %v", err)
+	}
+	_, err = r.ReadAt(buf, 2*checksumBlockSize)
+	if cloud.KindOf(err) != cloud.KindCorrupt || !errors.Is(err, cloud.ErrCorrupt) {
+		t.Errorf("ReadAt corrupted block error %v != expected KindCorrupt", err)
+	}
+	_, err = cloud.MemMap(r, 2*checksumBlockSize-10, 20)
+	if cloud.KindOf(err) != cloud.KindCorrupt {
+		t.Errorf("MemMap corrupted block error %v != expected KindCorrupt", err)
+	}
+
+	// Blobs without attributes can't be verified.
+	writeLegacyBlob(t, s.dir, "legacy", "data")
+
+	ctx := context.Background()
+	result, err := s.Scrub(ctx, nil)
+	if err != nil {
+		t.Fatalf("Scrub error = %v", err)
+	} else if result.Scanned != 3 || !slices.Equal(result.Corrupt, []string{"blob"}) ||
+		!slices.Equal(result.Unverified, []string{"legacy"}) {
+		t.Errorf("Scrub result = %+v", result)
+	}
+	if _, err := s.Size("blob"); err != nil {
+		t.Errorf("Size(blob) error = %v", err)
+	}
+
+	result, err = s.Scrub(ctx, &ScrubOptions{Quarantine: true})
+	if err != nil {
+		t.Fatalf("Scrub error = %v", err)
+	} else if !slices.Equal(result.Corrupt, []string{"blob"}) {
+		t.Errorf("Scrub result = %+v", result)
+	}
+	if _, err := s.Size("blob"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Size(blob) after quarantine error = %v", err)
+	}
+	quarantined, err := os.ReadDir(filepath.Join(s.dir, quarantineDirName))
+	if err != nil || len(quarantined) != 2 {
+		t.Errorf("Quarantine directory = %v, %v", quarantined, err)
+	}
+	keys, err := s.List()
+	if err != nil || !slices.Equal(keys, []string{"good", "legacy"}) {
+		t.Errorf("List() = %q, %v", keys, err)
+	}
+}
+
+// Counts bytes read.
+type countingReaderAt struct {
+	r     io.ReaderAt
+	bytes int64
+}
+
+func (c *countingReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(b, off)
+	c.bytes += int64(n)
+	return n, err
+}
+
+func TestBlockVerifier_ReadAt(t *testing.T) {
+	const blockSize = 16
+	data := make([]byte, 5*blockSize+7)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	var sums []uint32
+	for off := 0; off < len(data); off += blockSize {
+		sums = append(sums, crc32.Checksum(data[off:min(off+blockSize, len(data))], crc32cTable))
+	}
+
+	reads := []struct {
+		off    int64
+		length int
+	}{
+		{0, blockSize},
+		{0, 2 * blockSize},
+		{3, 5},
+		{blockSize - 2, 4},
+		{blockSize, 3*blockSize + 3},
+		{4 * blockSize, 2 * blockSize},
+		{int64(len(data)) - 1, 10},
+		{int64(len(data)), 1},
+	}
+	for _, rd := range reads {
+		v := newBlockVerifier(blockSize, sums, int64(len(data)))
+		r := &countingReaderAt{r: bytes.NewReader(data)}
+		buf := make([]byte, rd.length)
+		n, err := v.readAt(r, "blob", buf, rd.off)
+		expected := data[min(rd.off, int64(len(data))):min(rd.off+int64(rd.length), int64(len(data)))]
+		if n != len(expected) || !bytes.Equal(buf[:n], expected) {
+			t.Errorf("readAt(%d, %d) = %d bytes, expected %d", rd.off, rd.length, n, len(expected))
+		}
+		if n < rd.length && err != io.EOF {
+			t.Errorf("readAt(%d, %d) error %v != expected io.EOF", rd.off, rd.length, err)
+		} else if n == rd.length && err != nil {
+			t.Errorf("readAt(%d, %d) error = %v", rd.off, rd.length, err)
+		}
+
+		// Each block is read once.
+		start, end := v.blockRange(rd.off, rd.length)
+		if rd.off < int64(len(data)) && r.bytes != end-start {
+			t.Errorf("readAt(%d, %d) read %d bytes, expected %d", rd.off, rd.length, r.bytes, end-start)
+		}
+		r.bytes = 0
+		if _, err := v.readAt(r, "blob", buf, rd.off); err != nil && err != io.EOF {
+			t.Errorf("readAt(%d, %d) error = %v", rd.off, rd.length, err)
+		} else if rd.off < int64(len(data)) && r.bytes != int64(n) {
+			t.Errorf("readAt(%d, %d) of verified blocks read %d bytes, expected %d", rd.off, rd.length, r.bytes, n)
+		}
+	}
+}
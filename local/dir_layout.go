@@ -0,0 +1,320 @@
+package local
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// On-disk layout of a DirBlobStore. Blob files are named by encoding the key,
+// so that keys containing path elements (i.e. "/" and "..") can't escape the
+// store directory. Keys are encoded using lowercase base32hex, which is safe
+// on case-insensitive file systems.
+//
+// Optionally, blobs are spread across levels of subdirectories, named by a
+// hash of the key, to keep directories small. Encoded keys longer than
+// maxNameLen are split into nested long-key directories.
+//
+// Blobs written before key encoding was introduced are stored directly in the
+// store directory, under their legacy prefixes. They remain readable, and are
+// moved to the current layout when overwritten, or by Migrate.
+
+const (
+	// Prefixes of blob files, attribute sidecar files, and long-key
+	// directories.
+	keyBlobPrefix  = "k-"
+	keyAttrsPrefix = "a-"
+	longDirPrefix  = "d-"
+
+	legacyBlobPrefix  = "b-"
+	legacyAttrsPrefix = "m-"
+
+	// Maximum length of an encoded key in a single file name. Leaves room for
+	// the prefix within the 255 byte name limit of most file systems.
+	maxNameLen = 200
+
+	// Each shard level has a fan-out of 256.
+	MaxShardLevels = 4
+
+	layoutFileName = "layout"
+)
+
+var keyEncoding = base32.NewEncoding("0123456789abcdefghijklmnopqrstuv").WithPadding(base32.NoPadding)
+
+// Stored in the layout file, which is written when the store is created.
+type dirLayout struct {
+	ShardLevels int `json:"shard_levels"`
+}
+
+// Reads the store's layout, creating the layout file if it doesn't exist.
+// If shardLevels is negative, the existing layout is used, or no sharding if
+// the store is new. Otherwise, it must match the existing layout.
+func (s *DirBlobStore) initLayout(shardLevels int) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := filepath.Join(s.dir, layoutFileName)
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		layout := dirLayout{ShardLevels: max(shardLevels, 0)}
+		buf, err = json.Marshal(&layout)
+		if err != nil {
+			return err
+		}
+		err = s.writeFile(path, buf)
+		if err != nil {
+			return err
+		}
+		s.shardLevels = layout.ShardLevels
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var layout dirLayout
+	err = json.Unmarshal(buf, &layout)
+	if err != nil {
+		return fmt.Errorf("cloud/local: invalid layout in %s: %w", s.dir, err)
+	} else if layout.ShardLevels < 0 || layout.ShardLevels > MaxShardLevels {
+		return fmt.Errorf("cloud/local: invalid shard levels in %s: %d", s.dir, layout.ShardLevels)
+	} else if shardLevels >= 0 && shardLevels != layout.ShardLevels {
+		return fmt.Errorf("cloud/local: %s has %d shard levels, not %d", s.dir, layout.ShardLevels, shardLevels)
+	}
+	s.shardLevels = layout.ShardLevels
+	return nil
+}
+
+// Paths of a blob file and its attributes sidecar.
+type blobPaths struct {
+	blob  string
+	attrs string
+}
+
+func (s *DirBlobStore) makePaths(key string) blobPaths {
+	dir := s.dir
+	if s.shardLevels > 0 {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		sum := h.Sum(nil)
+		for i := 0; i < s.shardLevels; i++ {
+			dir = filepath.Join(dir, hex.EncodeToString(sum[i:i+1]))
+		}
+	}
+
+	name := keyEncoding.EncodeToString([]byte(key))
+	for len(name) > maxNameLen {
+		dir = filepath.Join(dir, longDirPrefix+name[:maxNameLen])
+		name = name[maxNameLen:]
+	}
+	return blobPaths{
+		blob:  filepath.Join(dir, keyBlobPrefix+name),
+		attrs: filepath.Join(dir, keyAttrsPrefix+name),
+	}
+}
+
+// Returns the legacy paths of key. ok is false if key can't have been stored
+// in the legacy layout.
+func (s *DirBlobStore) legacyPaths(key string) (paths blobPaths, ok bool) {
+	if key == "" || strings.ContainsRune(key, '/') || strings.ContainsRune(key, filepath.Separator) ||
+		len(legacyBlobPrefix+key) > 255 {
+		return blobPaths{}, false
+	}
+	return blobPaths{
+		blob:  filepath.Join(s.dir, legacyBlobPrefix+key),
+		attrs: filepath.Join(s.dir, legacyAttrsPrefix+key),
+	}, true
+}
+
+// Returns the paths and file info of the existing blob for key, which may be
+// in the legacy layout. Returns fs.ErrNotExist if the blob doesn't exist.
+func (s *DirBlobStore) findBlob(key string) (blobPaths, fs.FileInfo, error) {
+	paths := s.makePaths(key)
+	fi, err := os.Stat(paths.blob)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return paths, fi, err
+	}
+
+	legacy, ok := s.legacyPaths(key)
+	if !ok {
+		return paths, nil, err
+	}
+	fi, err = os.Stat(legacy.blob)
+	if err == nil {
+		return legacy, fi, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return legacy, nil, err
+	}
+
+	// A concurrent write may have moved the blob from the legacy layout.
+	fi, err = os.Stat(paths.blob)
+	return paths, fi, err
+}
+
+// Removes key's legacy files, if they exist. Must be called with the lock
+// held, after the blob has been written in the current layout.
+func (s *DirBlobStore) removeLegacy(key string) error {
+	legacy, ok := s.legacyPaths(key)
+	if !ok {
+		return nil
+	}
+	err := os.Remove(legacy.attrs)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	err = os.Remove(legacy.blob)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Returns true if name is a shard directory name.
+func isShardName(name string) bool {
+	if len(name) != 2 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil && strings.ToLower(name) == name
+}
+
+// Appends the keys of blobs in dir, and its long-key subdirectories, to keys.
+// prefix is the encoded key prefix from enclosing long-key directories.
+func appendDirKeys(dir, prefix string, keys []string) ([]string, error) {
+	dirents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dirents {
+		name := d.Name()
+		if chunk, ok := strings.CutPrefix(name, longDirPrefix); ok && d.IsDir() {
+			keys, err = appendDirKeys(filepath.Join(dir, name), prefix+chunk, keys)
+			if err != nil {
+				return nil, err
+			}
+		} else if enc, ok := strings.CutPrefix(name, keyBlobPrefix); ok {
+			key, err := keyEncoding.DecodeString(prefix + enc)
+			if err != nil {
+				log.Printf("cloud/local: invalid blob file name %s: %v", filepath.Join(dir, name), err)
+				continue
+			}
+			keys = append(keys, string(key))
+		}
+	}
+	return keys, nil
+}
+
+// Appends the keys of blobs in shard directory dir, at the given level, to
+// keys.
+func (s *DirBlobStore) appendShardKeys(dir string, level int, keys []string) ([]string, error) {
+	if level == s.shardLevels {
+		return appendDirKeys(dir, "", keys)
+	}
+
+	dirents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dirents {
+		if !d.IsDir() || !isShardName(d.Name()) {
+			continue
+		}
+		keys, err = s.appendShardKeys(filepath.Join(dir, d.Name()), level+1, keys)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// Returns the keys of blobs stored in the legacy layout.
+func (s *DirBlobStore) legacyKeys() ([]string, error) {
+	dirents, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, d := range dirents {
+		if key, ok := strings.CutPrefix(d.Name(), legacyBlobPrefix); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Returns the sorted keys of all blobs, in both the current and legacy
+// layouts.
+func (s *DirBlobStore) listKeys() ([]string, error) {
+	keys, err := s.legacyKeys()
+	if err != nil {
+		return nil, err
+	}
+	keys, err = s.appendShardKeys(s.dir, 0, keys)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(keys)
+	// A blob may briefly exist in both layouts while being moved.
+	return slices.Compact(keys), nil
+}
+
+// Migrate moves all blobs stored in the legacy layout to the current layout.
+// The store remains usable while blobs are being moved.
+func (s *DirBlobStore) Migrate() error {
+	keys, err := s.legacyKeys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = s.migrateBlob(key)
+		if err != nil {
+			return fmt.Errorf("cloud/local: error migrating %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (s *DirBlobStore) migrateBlob(key string) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	legacy, _ := s.legacyPaths(key)
+	paths := s.makePaths(key)
+	_, err = os.Stat(paths.blob)
+	if err == nil {
+		// Already overwritten in the current layout.
+		return s.removeLegacy(key)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(paths.blob), 0750)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(legacy.attrs, paths.attrs)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	err = os.Rename(legacy.blob, paths.blob)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Deleted since listing.
+		os.Remove(paths.attrs)
+		return nil
+	}
+	return err
+}
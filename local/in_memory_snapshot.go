@@ -0,0 +1,376 @@
+package local
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"log"
+	"time"
+
+	"github.com/google/btree"
+
+	"github.com/akmistry/cloud-util"
+)
+
+// Snapshot format, with integers in big-endian order:
+//
+//	magic [4]byte
+//	format version uint32
+//	last version uint64
+//	item count uint64
+//	items
+//	CRC32C of all preceding bytes uint32
+//
+// Each item is:
+//
+//	key length uvarint, key
+//	value length uvarint, value
+//	version uvarint
+//	expiry in Unix nanoseconds varint, or 0 if the item never expires
+
+const (
+	snapshotMagic         = "CUMS"
+	snapshotFormatVersion = 1
+
+	// Guards against huge allocations when reading a corrupt snapshot, since
+	// the checksum is only verified at the end.
+	maxSnapshotFieldSize = 1 << 30
+)
+
+var (
+	ErrInvalidSnapshot = errors.New("cloud/local: invalid snapshot")
+)
+
+// Point-in-time, read-only copy of an InMemoryStore.
+type MemSnapshot struct {
+	t           *btree.BTreeG[*memItem]
+	lastVersion uint64
+	// Store's modification count when the snapshot was taken. Not serialised.
+	modCount uint64
+}
+
+// Snapshot returns a copy of the store's current contents. Items are shared
+// with the store, so taking a snapshot is cheap.
+func (s *InMemoryStore) Snapshot() *MemSnapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return &MemSnapshot{
+		t:           s.t.Clone(),
+		lastVersion: s.lastVersion,
+		modCount:    s.modCount,
+	}
+}
+
+// Restore replaces the store's contents with sn. Versions continue from
+// whichever of the store and snapshot is newer, so they are never reused.
+// Watchers are notified of every key in the store or snapshot.
+func (s *InMemoryStore) Restore(sn *MemSnapshot) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	old := s.t
+	s.t = sn.t.Clone()
+	s.lastVersion = max(s.lastVersion, sn.lastVersion)
+	s.modCount++
+
+	old.Ascend(func(item *memItem) bool {
+		s.notifyWatchers(item.key)
+		return true
+	})
+	hasExpiry := false
+	s.t.Ascend(func(item *memItem) bool {
+		s.notifyWatchers(item.key)
+		hasExpiry = hasExpiry || !item.expiry.IsZero()
+		return true
+	})
+	if hasExpiry {
+		s.startSweeper()
+	}
+}
+
+// Len returns the number of items in the snapshot, including expired items.
+func (sn *MemSnapshot) Len() int {
+	return sn.t.Len()
+}
+
+// Get returns the value of key at the time of the snapshot.
+func (sn *MemSnapshot) Get(key string) (*cloud.KVPair, error) {
+	item, ok := sn.t.Get(&memItem{key: key})
+	if !ok || item.expired(time.Now()) {
+		return nil, cloud.ErrKeyNotFound
+	}
+	return item.pair(false), nil
+}
+
+// WriteTo serialises the snapshot to w. Items which have expired are omitted.
+func (sn *MemSnapshot) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	crc := crc32.New(crc32cTable)
+	out := io.MultiWriter(bw, crc)
+
+	now := time.Now()
+	var items []*memItem
+	sn.t.Ascend(func(item *memItem) bool {
+		if !item.expired(now) {
+			items = append(items, item)
+		}
+		return true
+	})
+
+	buf := []byte(snapshotMagic)
+	buf = binary.BigEndian.AppendUint32(buf, snapshotFormatVersion)
+	buf = binary.BigEndian.AppendUint64(buf, sn.lastVersion)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(len(items)))
+	_, err := out.Write(buf)
+	if err != nil {
+		return cw.n, err
+	}
+
+	for _, item := range items {
+		buf = buf[:0]
+		buf = binary.AppendUvarint(buf, uint64(len(item.key)))
+		buf = append(buf, item.key...)
+		buf = binary.AppendUvarint(buf, uint64(len(item.value)))
+		buf = append(buf, item.value...)
+		buf = binary.AppendUvarint(buf, item.version)
+		var expiry int64
+		if !item.expiry.IsZero() {
+			expiry = item.expiry.UnixNano()
+		}
+		buf = binary.AppendVarint(buf, expiry)
+		_, err = out.Write(buf)
+		if err != nil {
+			return cw.n, err
+		}
+	}
+
+	_, err = bw.Write(binary.BigEndian.AppendUint32(nil, crc.Sum32()))
+	if err == nil {
+		err = bw.Flush()
+	}
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.n += int64(n)
+	return n, err
+}
+
+// ReadSnapshot reads a snapshot written by MemSnapshot.WriteTo. Returns
+// ErrInvalidSnapshot if the snapshot is corrupt or has an unsupported format.
+func ReadSnapshot(r io.Reader) (*MemSnapshot, error) {
+	br := &checksumReader{r: bufio.NewReader(r), crc: crc32.New(crc32cTable)}
+
+	header := make([]byte, len(snapshotMagic)+4+8+8)
+	_, err := io.ReadFull(br, header)
+	if err != nil {
+		return nil, snapshotReadError(err)
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrInvalidSnapshot)
+	}
+	header = header[len(snapshotMagic):]
+	if v := binary.BigEndian.Uint32(header); v != snapshotFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrInvalidSnapshot, v)
+	}
+	sn := &MemSnapshot{
+		t:           btree.NewG(4, memItemLessFunc),
+		lastVersion: binary.BigEndian.Uint64(header[4:]),
+	}
+	count := binary.BigEndian.Uint64(header[12:])
+
+	readBytes := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		} else if n > maxSnapshotFieldSize {
+			return nil, fmt.Errorf("%w: field length %d too large", ErrInvalidSnapshot, n)
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(br, b)
+		return b, err
+	}
+	for i := uint64(0); i < count; i++ {
+		key, err := readBytes()
+		if err != nil {
+			return nil, snapshotReadError(err)
+		}
+		value, err := readBytes()
+		if err != nil {
+			return nil, snapshotReadError(err)
+		}
+		version, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, snapshotReadError(err)
+		}
+		expiry, err := binary.ReadVarint(br)
+		if err != nil {
+			return nil, snapshotReadError(err)
+		}
+		item := &memItem{
+			key:     string(key),
+			value:   value,
+			version: version,
+		}
+		if expiry != 0 {
+			item.expiry = time.Unix(0, expiry)
+		}
+		sn.t.ReplaceOrInsert(item)
+	}
+
+	// The checksum itself is read without being checksummed.
+	sum := br.crc.Sum32()
+	trailer := make([]byte, 4)
+	_, err = io.ReadFull(br.r, trailer)
+	if err != nil {
+		return nil, snapshotReadError(err)
+	} else if binary.BigEndian.Uint32(trailer) != sum {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidSnapshot)
+	}
+	return sn, nil
+}
+
+// Truncated snapshots are invalid.
+func snapshotReadError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: truncated", ErrInvalidSnapshot)
+	}
+	return err
+}
+
+// Checksums bytes as they are consumed.
+type checksumReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+}
+
+func (r *checksumReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.crc.Write(b[:n])
+	return n, err
+}
+
+func (r *checksumReader) ReadByte() (byte, error) {
+	c, err := r.r.ReadByte()
+	if err == nil {
+		r.crc.Write([]byte{c})
+	}
+	return c, err
+}
+
+// SnapshotToBlob writes a snapshot of the store to blob key in bs.
+func (s *InMemoryStore) SnapshotToBlob(ctx context.Context, bs cloud.BlobStore, key string) error {
+	return writeSnapshotBlob(ctx, bs, key, s.Snapshot())
+}
+
+func writeSnapshotBlob(ctx context.Context, bs cloud.BlobStore, key string, sn *MemSnapshot) error {
+	w, err := cloud.AsContextBlobStore(bs).PutContext(ctx, key)
+	if err != nil {
+		return err
+	}
+	_, err = sn.WriteTo(w)
+	if err != nil {
+		w.Cancel()
+		return err
+	}
+	return w.Close()
+}
+
+// RestoreFromBlob replaces the store's contents with the snapshot in blob key
+// of bs, as for Restore. Returns an error matching os.ErrNotExist if the blob
+// doesn't exist.
+func (s *InMemoryStore) RestoreFromBlob(ctx context.Context, bs cloud.BlobStore, key string) error {
+	r, err := cloud.AsContextBlobStore(bs).GetContext(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	sn, err := ReadSnapshot(io.NewSectionReader(r, 0, r.Size()))
+	if err != nil {
+		return err
+	}
+	s.Restore(sn)
+	return nil
+}
+
+// Periodically writes snapshots of an InMemoryStore to a blob store.
+type PeriodicSnapshotter struct {
+	s   *InMemoryStore
+	bs  cloud.BlobStore
+	key string
+
+	// Modification count of the last snapshot written, to skip unchanged
+	// snapshots.
+	lastModCount uint64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// StartPeriodicSnapshots writes a snapshot of s to blob key in bs every
+// interval, if s has been modified. Errors are logged, and the snapshot is
+// retried at the next interval.
+func StartPeriodicSnapshots(s *InMemoryStore, bs cloud.BlobStore, key string, interval time.Duration) *PeriodicSnapshotter {
+	p := &PeriodicSnapshotter{
+		s:    s,
+		bs:   bs,
+		key:  key,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go p.run(interval)
+	return p
+}
+
+func (p *PeriodicSnapshotter) run(interval time.Duration) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			err := p.snapshot()
+			if err != nil {
+				log.Printf("cloud/local: error writing snapshot %s: %v", p.key, err)
+			}
+		}
+	}
+}
+
+func (p *PeriodicSnapshotter) snapshot() error {
+	sn := p.s.Snapshot()
+	if sn.modCount == p.lastModCount {
+		return nil
+	}
+
+	err := writeSnapshotBlob(context.Background(), p.bs, p.key, sn)
+	if err != nil {
+		return err
+	}
+	p.lastModCount = sn.modCount
+	return nil
+}
+
+// Stop stops periodic snapshots, and writes a final snapshot if the store has
+// been modified since the last one.
+func (p *PeriodicSnapshotter) Stop() error {
+	close(p.stop)
+	<-p.done
+	return p.snapshot()
+}
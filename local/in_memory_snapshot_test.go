@@ -0,0 +1,152 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/akmistry/cloud-util"
+)
+
+func TestInMemoryStore_Snapshot(t *testing.T) {
+	s := NewInMemoryStore()
+	defer s.Close()
+	s.Put("a", []byte("1"), nil)
+	s.Put("b", []byte("2"), &cloud.WriteOptions{TTL: time.Hour})
+	s.Put("expired", []byte("3"), &cloud.WriteOptions{TTL: time.Millisecond})
+	pa, _ := s.Get("a")
+	time.Sleep(5 * time.Millisecond)
+
+	sn := s.Snapshot()
+	// Snapshots aren't affected by later writes.
+	s.Put("a", []byte("updated"), nil)
+	s.Delete("b")
+	if p, err := sn.Get("a"); err != nil || string(p.Value) != "1" {
+		t.Errorf("Snapshot Get(a) = %v, %v", p, err)
+	}
+	if _, err := sn.Get("expired"); err != cloud.ErrKeyNotFound {
+		t.Errorf("Snapshot Get(expired) error %v != expected ErrKeyNotFound", err)
+	}
+
+	var buf bytes.Buffer
+	n, err := sn.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo error = %v", err)
+	} else if n != int64(buf.Len()) {
+		t.Errorf("WriteTo n %d != written %d", n, buf.Len())
+	}
+
+	loaded, err := ReadSnapshot(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadSnapshot error = %v", err)
+	} else if loaded.Len() != 2 {
+		t.Errorf("Loaded snapshot Len() %d != expected 2", loaded.Len())
+	}
+
+	restored := NewInMemoryStore()
+	defer restored.Close()
+	restored.Restore(loaded)
+	p, err := restored.Get("a")
+	if err != nil || string(p.Value) != "1" || p.LastIndex != pa.LastIndex {
+		t.Errorf("Restored Get(a) = %v, %v, expected version %d", p, err, pa.LastIndex)
+	}
+	if p, err := restored.Get("b"); err != nil || string(p.Value) != "2" {
+		t.Errorf("Restored Get(b) = %v, %v", p, err)
+	}
+
+	// Versions aren't reused after restoring.
+	_, updated, err := restored.AtomicPut("a", []byte("4"), p, nil)
+	if err != nil {
+		t.Fatalf("AtomicPut(a) error = %v", err)
+	} else if updated.LastIndex <= loaded.lastVersion {
+		t.Errorf("AtomicPut(a) version %d <= snapshot version %d", updated.LastIndex, loaded.lastVersion)
+	}
+}
+
+func TestReadSnapshot_Invalid(t *testing.T) {
+	s := NewInMemoryStore()
+	s.Put("key", []byte("value"), nil)
+	var buf bytes.Buffer
+	_, err := s.Snapshot().WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	corrupt := bytes.Clone(data)
+	corrupt[len(corrupt)-6] ^= 1
+	cases := map[string][]byte{
+		"empty":     nil,
+		"truncated": data[:len(data)-1],
+		"corrupt":   corrupt,
+		"magic":     append([]byte("XXXX"), data[4:]...),
+	}
+	for name, b := range cases {
+		_, err := ReadSnapshot(bytes.NewReader(b))
+		if !errors.Is(err, ErrInvalidSnapshot) {
+			t.Errorf("ReadSnapshot(%s) error %v != expected ErrInvalidSnapshot", name, err)
+		}
+	}
+}
+
+func TestInMemoryStore_SnapshotBlob(t *testing.T) {
+	ctx := context.Background()
+	bs := NewMemBlobStore()
+	s := NewInMemoryStore()
+	s.Put("key", []byte("value"), nil)
+
+	restored := NewInMemoryStore()
+	err := restored.RestoreFromBlob(ctx, bs, "snapshot")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("RestoreFromBlob error %v != expected os.ErrNotExist", err)
+	}
+
+	err = s.SnapshotToBlob(ctx, bs, "snapshot")
+	if err != nil {
+		t.Fatalf("SnapshotToBlob error = %v", err)
+	}
+	err = restored.RestoreFromBlob(ctx, bs, "snapshot")
+	if err != nil {
+		t.Fatalf("RestoreFromBlob error = %v", err)
+	}
+	if p, err := restored.Get("key"); err != nil || string(p.Value) != "value" {
+		t.Errorf("Restored Get(key) = %v, %v", p, err)
+	}
+
+	// Stop writes a final snapshot.
+	p := StartPeriodicSnapshots(s, bs, "periodic", time.Hour)
+	s.Put("key2", []byte("value2"), nil)
+	err = p.Stop()
+	if err != nil {
+		t.Fatalf("Stop error = %v", err)
+	}
+	err = restored.RestoreFromBlob(ctx, bs, "periodic")
+	if err != nil {
+		t.Fatalf("RestoreFromBlob error = %v", err)
+	}
+	if p, err := restored.Get("key2"); err != nil || string(p.Value) != "value2" {
+		t.Errorf("Restored Get(key2) = %v, %v", p, err)
+	}
+
+	// Deletes are snapshotted, even though they don't create a new version.
+	p = StartPeriodicSnapshots(s, bs, "periodic", time.Hour)
+	err = p.snapshot()
+	if err != nil {
+		t.Fatalf("snapshot error = %v", err)
+	}
+	s.Delete("key2")
+	err = p.Stop()
+	if err != nil {
+		t.Fatalf("Stop error = %v", err)
+	}
+	err = restored.RestoreFromBlob(ctx, bs, "periodic")
+	if err != nil {
+		t.Fatalf("RestoreFromBlob error = %v", err)
+	}
+	if p, err := restored.Get("key2"); err != cloud.ErrKeyNotFound {
+		t.Errorf("Restored Get(key2) = %v, %v after delete", p, err)
+	}
+}
@@ -2,17 +2,42 @@ package local
 
 import (
 	"bytes"
+	"context"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/btree"
 
 	"github.com/akmistry/cloud-util"
 )
 
+const (
+	// Interval between background removals of expired items.
+	sweepInterval = 10 * time.Second
+)
+
 type memItem struct {
-	key   string
-	value []byte
+	key     string
+	value   []byte
+	version uint64
+	// Zero if the item never expires.
+	expiry time.Time
+}
+
+func (i *memItem) expired(now time.Time) bool {
+	return !i.expiry.IsZero() && !now.Before(i.expiry)
+}
+
+func (i *memItem) pair(keysOnly bool) *cloud.KVPair {
+	p := &cloud.KVPair{
+		Key:       i.key,
+		LastIndex: i.version,
+	}
+	if !keysOnly {
+		p.Value = bytes.Clone(i.value)
+	}
+	return p
 }
 
 func memItemLessFunc(a, b *memItem) bool {
@@ -22,53 +47,210 @@ func memItemLessFunc(a, b *memItem) bool {
 type InMemoryStore struct {
 	t    *btree.BTreeG[*memItem]
 	lock sync.Mutex
+
+	// Version of the most recent write. Versions are never reused, even for
+	// deleted keys.
+	lastVersion uint64
+	// Incremented by every modification, including deletes, which don't
+	// change lastVersion.
+	modCount uint64
+
+	// Expired items are hidden from reads, and removed by a background
+	// sweeper which is started on the first write with a TTL.
+	sweeperStarted bool
+	stopSweeper    chan struct{}
+	sweeperDone    chan struct{}
+
+	watchers map[*memWatcher]struct{}
+}
+
+type memWatcher struct {
+	// Key being watched, or directory if tree is true.
+	key  string
+	tree bool
+	// Signalled after a matching key is modified.
+	notify chan struct{}
+}
+
+func (w *memWatcher) matches(key string) bool {
+	if w.tree {
+		return strings.HasPrefix(key, w.key)
+	}
+	return key == w.key
 }
 
 var _ = (cloud.OrderedStore)((*InMemoryStore)(nil))
 var _ = (cloud.AtomicUnorderedStore)((*InMemoryStore)(nil))
+var _ = (cloud.AtomicContextStore)((*InMemoryStore)(nil))
+var _ = (cloud.ContextKeysLister)((*InMemoryStore)(nil))
+var _ = (cloud.BatchStore)((*InMemoryStore)(nil))
+var _ = (cloud.ContextBatchStore)((*InMemoryStore)(nil))
+var _ = (cloud.Scanner)((*InMemoryStore)(nil))
+var _ = (cloud.ContextScanner)((*InMemoryStore)(nil))
+var _ = (cloud.Watcher)((*InMemoryStore)(nil))
+var _ = (cloud.TxnStore)((*InMemoryStore)(nil))
+var _ = (cloud.CapabilitiesProvider)((*InMemoryStore)(nil))
 
 func NewInMemoryStore() *InMemoryStore {
 	return &InMemoryStore{
-		t: btree.NewG(4, memItemLessFunc),
+		t:        btree.NewG(4, memItemLessFunc),
+		watchers: make(map[*memWatcher]struct{}),
 	}
 }
 
-func (s *InMemoryStore) Get(key string) (*cloud.KVPair, error) {
+func (s *InMemoryStore) Capabilities() cloud.Capabilities {
+	return cloud.Capabilities{
+		Atomic:      true,
+		Ordered:     true,
+		TTL:         true,
+		Batch:       true,
+		Watch:       true,
+		Txn:         true,
+		Consistency: cloud.ConsistencyStrong,
+	}
+}
+
+// Stops the background sweeper, if running. The store remains usable, and the
+// sweeper is restarted by the next write with a TTL.
+func (s *InMemoryStore) Close() error {
+	s.lock.Lock()
+	stop, done := s.stopSweeper, s.sweeperDone
+	s.stopSweeper = nil
+	s.sweeperStarted = false
+	s.lock.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	return nil
+}
+
+func (s *InMemoryStore) sweeper(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.sweep()
+		}
+	}
+}
+
+// Removes all expired items.
+func (s *InMemoryStore) sweep() {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	now := time.Now()
+	var expired []*memItem
+	s.t.Ascend(func(item *memItem) bool {
+		if item.expired(now) {
+			expired = append(expired, item)
+		}
+		return true
+	})
+	for _, item := range expired {
+		s.remove(item.key)
+	}
+}
+
+// Inserts a new version of key. Must be called with the lock held.
+func (s *InMemoryStore) insert(key string, value []byte, options *cloud.WriteOptions) *memItem {
+	s.lastVersion++
+	s.modCount++
+	item := &memItem{
+		key:     key,
+		value:   bytes.Clone(value),
+		version: s.lastVersion,
+		expiry:  cloud.Expiry(options),
+	}
+	s.t.ReplaceOrInsert(item)
+	s.notifyWatchers(key)
+
+	if !item.expiry.IsZero() {
+		s.startSweeper()
+	}
+	return item
+}
+
+// Must be called with the lock held.
+func (s *InMemoryStore) startSweeper() {
+	if s.sweeperStarted {
+		return
+	}
+	s.sweeperStarted = true
+	s.stopSweeper = make(chan struct{})
+	s.sweeperDone = make(chan struct{})
+	go s.sweeper(s.stopSweeper, s.sweeperDone)
+}
+
+// Removes key, if it exists. Must be called with the lock held.
+func (s *InMemoryStore) remove(key string) {
+	if _, ok := s.t.Delete(&memItem{key: key}); ok {
+		s.modCount++
+		s.notifyWatchers(key)
+	}
+}
+
+// Must be called with the lock held.
+func (s *InMemoryStore) notifyWatchers(key string) {
+	for w := range s.watchers {
+		if !w.matches(key) {
+			continue
+		}
+		select {
+		case w.notify <- struct{}{}:
+		default:
+			// Already signalled.
+		}
+	}
+}
+
+// Returns the unexpired item for key, removing it if it has expired. Must be
+// called with the lock held.
+func (s *InMemoryStore) get(key string) (*memItem, bool) {
 	keyItem := &memItem{
 		key: key,
 	}
 	item, ok := s.t.Get(keyItem)
+	if !ok {
+		return nil, false
+	} else if item.expired(time.Now()) {
+		s.remove(key)
+		return nil, false
+	}
+	return item, true
+}
+
+func (s *InMemoryStore) Get(key string) (*cloud.KVPair, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	item, ok := s.get(key)
 	if !ok {
 		return nil, cloud.ErrKeyNotFound
 	}
-	return &cloud.KVPair{
-		Key:   key,
-		Value: bytes.Clone(item.value),
-	}, nil
+	return item.pair(false), nil
 }
 
 func (s *InMemoryStore) Exists(key string) (bool, error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	keyItem := &memItem{
-		key: key,
-	}
-	return s.t.Has(keyItem), nil
+	_, ok := s.get(key)
+	return ok, nil
 }
 
 func (s *InMemoryStore) Put(key string, value []byte, options *cloud.WriteOptions) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	insertItem := &memItem{
-		key:   key,
-		value: bytes.Clone(value),
-	}
-	s.t.ReplaceOrInsert(insertItem)
+	s.insert(key, value, options)
 	return nil
 }
 
@@ -76,40 +258,66 @@ func (s *InMemoryStore) Delete(key string) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	keyItem := &memItem{
-		key: key,
+	s.remove(key)
+	return nil
+}
+
+func (s *InMemoryStore) GetMulti(keys []string) ([]*cloud.KVPair, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pairs := make([]*cloud.KVPair, len(keys))
+	for i, key := range keys {
+		item, ok := s.get(key)
+		if !ok {
+			continue
+		}
+		pairs[i] = item.pair(false)
+	}
+	return pairs, nil
+}
+
+func (s *InMemoryStore) PutMulti(pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, p := range pairs {
+		s.insert(p.Key, p.Value, options)
 	}
-	s.t.Delete(keyItem)
 	return nil
 }
 
-func (s *InMemoryStore) AtomicPut(key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+func (s *InMemoryStore) DeleteMulti(keys []string) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	keyItem := &memItem{
-		key: key,
+	for _, key := range keys {
+		s.remove(key)
 	}
-	item, ok := s.t.Get(keyItem)
+	return nil
+}
+
+func (s *InMemoryStore) AtomicPut(key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	item, ok := s.get(key)
 	if !ok {
 		if previous != nil {
 			return false, nil, cloud.ErrKeyNotFound
 		}
 	} else if previous == nil {
 		return false, nil, cloud.ErrKeyExists
-	} else if !bytes.Equal(item.value, previous.Value) {
+	} else if !cloud.MatchesPrevious(previous, item.value, item.version) {
 		return false, nil, cloud.ErrKeyModified
 	}
 
-	insertItem := &memItem{
-		key:   key,
-		value: bytes.Clone(value),
-	}
-	s.t.ReplaceOrInsert(insertItem)
+	item = s.insert(key, value, options)
 
 	updated := &cloud.KVPair{
-		Key:   key,
-		Value: value,
+		Key:       key,
+		Value:     value,
+		LastIndex: item.version,
 	}
 	return true, updated, nil
 }
@@ -122,20 +330,44 @@ func (s *InMemoryStore) AtomicDelete(key string, previous *cloud.KVPair) (bool,
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	keyItem := &memItem{
-		key: key,
-	}
-	item, ok := s.t.Get(keyItem)
+	item, ok := s.get(key)
 	if !ok {
 		return false, cloud.ErrKeyNotFound
-	} else if !bytes.Equal(item.value, previous.Value) {
+	} else if !cloud.MatchesPrevious(previous, item.value, item.version) {
 		return false, cloud.ErrKeyModified
 	}
 
-	s.t.Delete(keyItem)
+	s.remove(key)
 	return true, nil
 }
 
+func (s *InMemoryStore) Txn(txn *cloud.Txn) error {
+	if err := txn.Validate(); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, c := range txn.Compares {
+		item, ok := s.get(c.Key)
+		if ok != (c.Previous != nil) {
+			return cloud.ErrKeyModified
+		} else if ok && !cloud.MatchesPrevious(c.Previous, item.value, item.version) {
+			return cloud.ErrKeyModified
+		}
+	}
+
+	for _, op := range txn.Ops {
+		if op.Delete {
+			s.remove(op.Key)
+		} else {
+			s.insert(op.Key, op.Value, op.Options)
+		}
+	}
+	return nil
+}
+
 func (s *InMemoryStore) ListKeys(start string) ([]string, error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -144,10 +376,226 @@ func (s *InMemoryStore) ListKeys(start string) ([]string, error) {
 		key: start,
 	}
 
+	now := time.Now()
 	var keys []string
 	s.t.AscendGreaterOrEqual(keyItem, func(item *memItem) bool {
+		if item.expired(now) {
+			return true
+		}
 		keys = append(keys, item.key)
 		return len(keys) < 16
 	})
 	return keys, nil
 }
+
+func (s *InMemoryStore) Scan(start, end string, limit int, options *cloud.ScanOptions) ([]*cloud.KVPair, error) {
+	var opts cloud.ScanOptions
+	if options != nil {
+		opts = *options
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	var pairs []*cloud.KVPair
+	iterFunc := func(item *memItem) bool {
+		if opts.Reverse {
+			if item.key < start {
+				return false
+			} else if end != "" && item.key >= end {
+				// DescendLessOrEqual includes the end key.
+				return true
+			}
+		}
+		if item.expired(now) {
+			return true
+		}
+		pairs = append(pairs, item.pair(opts.KeysOnly))
+		return limit <= 0 || len(pairs) < limit
+	}
+
+	if opts.Reverse {
+		if end == "" {
+			s.t.Descend(iterFunc)
+		} else {
+			s.t.DescendLessOrEqual(&memItem{key: end}, iterFunc)
+		}
+	} else if end == "" {
+		s.t.AscendGreaterOrEqual(&memItem{key: start}, iterFunc)
+	} else {
+		s.t.AscendRange(&memItem{key: start}, &memItem{key: end}, iterFunc)
+	}
+	return pairs, nil
+}
+
+func (s *InMemoryStore) addWatcher(key string, tree bool) *memWatcher {
+	w := &memWatcher{
+		key:    key,
+		tree:   tree,
+		notify: make(chan struct{}, 1),
+	}
+	s.lock.Lock()
+	s.watchers[w] = struct{}{}
+	s.lock.Unlock()
+	return w
+}
+
+func (s *InMemoryStore) removeWatcher(w *memWatcher) {
+	s.lock.Lock()
+	delete(s.watchers, w)
+	s.lock.Unlock()
+}
+
+func (s *InMemoryStore) Watch(key string, stopCh <-chan struct{}) (<-chan *cloud.KVPair, error) {
+	w := s.addWatcher(key, false)
+	ch := make(chan *cloud.KVPair, 1)
+	go func() {
+		defer close(ch)
+		defer s.removeWatcher(w)
+
+		var lastVersion uint64
+		for {
+			s.lock.Lock()
+			item, ok := s.get(key)
+			var p *cloud.KVPair
+			if ok {
+				p = item.pair(false)
+			}
+			s.lock.Unlock()
+
+			// Versions are never reused, so a new version always indicates a
+			// change, even if the key was deleted and re-created.
+			var version uint64
+			if p != nil {
+				version = p.LastIndex
+			}
+			if p != nil && version != lastVersion {
+				select {
+				case ch <- p:
+				case <-stopCh:
+					return
+				}
+			}
+			lastVersion = version
+
+			select {
+			case <-w.notify:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (s *InMemoryStore) WatchTree(directory string, stopCh <-chan struct{}) (<-chan []*cloud.KVPair, error) {
+	w := s.addWatcher(directory, true)
+	ch := make(chan []*cloud.KVPair, 1)
+	go func() {
+		defer close(ch)
+		defer s.removeWatcher(w)
+
+		for {
+			// Every notification is for a change in the tree, so the tree is
+			// always sent.
+			pairs, _ := s.Scan(directory, cloud.PrefixEnd(directory), 0, nil)
+			select {
+			case ch <- pairs:
+			case <-stopCh:
+				return
+			}
+
+			select {
+			case <-w.notify:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (s *InMemoryStore) GetContext(ctx context.Context, key string) (*cloud.KVPair, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.Get(key)
+}
+
+func (s *InMemoryStore) ExistsContext(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.Exists(key)
+}
+
+func (s *InMemoryStore) PutContext(ctx context.Context, key string, value []byte, options *cloud.WriteOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Put(key, value, options)
+}
+
+func (s *InMemoryStore) DeleteContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(key)
+}
+
+func (s *InMemoryStore) AtomicPutContext(ctx context.Context, key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+	if err := ctx.Err(); err != nil {
+		return false, nil, err
+	}
+	return s.AtomicPut(key, value, previous, options)
+}
+
+func (s *InMemoryStore) AtomicDeleteContext(ctx context.Context, key string, previous *cloud.KVPair) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.AtomicDelete(key, previous)
+}
+
+func (s *InMemoryStore) ListKeysContext(ctx context.Context, start string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.ListKeys(start)
+}
+
+func (s *InMemoryStore) TxnContext(ctx context.Context, txn *cloud.Txn) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Txn(txn)
+}
+
+func (s *InMemoryStore) GetMultiContext(ctx context.Context, keys []string) ([]*cloud.KVPair, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.GetMulti(keys)
+}
+
+func (s *InMemoryStore) PutMultiContext(ctx context.Context, pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.PutMulti(pairs, options)
+}
+
+func (s *InMemoryStore) DeleteMultiContext(ctx context.Context, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.DeleteMulti(keys)
+}
+
+func (s *InMemoryStore) ScanContext(ctx context.Context, start, end string, limit int, options *cloud.ScanOptions) ([]*cloud.KVPair, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.Scan(start, end, limit, options)
+}
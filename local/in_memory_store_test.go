@@ -2,7 +2,9 @@ package local
 
 import (
 	"testing"
+	"time"
 
+	"github.com/akmistry/cloud-util"
 	"github.com/akmistry/cloud-util/test_util"
 )
 
@@ -13,4 +15,52 @@ func TestInMemoryStore(t *testing.T) {
 	// Test assumes stores are empty to start
 	s = NewInMemoryStore()
 	test_util.TestListKeys(t, s)
+
+	s = NewInMemoryStore()
+	test_util.TestScan(t, s)
+
+	s = NewInMemoryStore()
+	test_util.TestAtomicStore(t, s)
+
+	s = NewInMemoryStore()
+	test_util.TestTxn(t, s)
+
+	s = NewInMemoryStore()
+	defer s.Close()
+	test_util.TestTTL(t, s)
+
+	s = NewInMemoryStore()
+	test_util.TestWatch(t, s)
+
+	s = NewInMemoryStore()
+	test_util.TestLock(t, s)
+}
+
+func TestInMemoryStore_Sweep(t *testing.T) {
+	s := NewInMemoryStore()
+	defer s.Close()
+
+	s.Put("expiring", []byte("value"), &cloud.WriteOptions{TTL: time.Millisecond})
+	s.Put("permanent", []byte("value"), nil)
+	time.Sleep(10 * time.Millisecond)
+
+	s.sweep()
+	if s.t.Len() != 1 {
+		t.Errorf("Items after sweep %d != expected 1", s.t.Len())
+	}
+}
+
+func TestInMemoryStore_ReuseAfterClose(t *testing.T) {
+	s := NewInMemoryStore()
+	s.Put("expiring", []byte("value"), &cloud.WriteOptions{TTL: time.Hour})
+	s.Close()
+
+	s.Put("expiring2", []byte("value"), &cloud.WriteOptions{TTL: time.Hour})
+	s.lock.Lock()
+	running := s.stopSweeper != nil
+	s.lock.Unlock()
+	if !running {
+		t.Errorf("Sweeper not restarted after Close")
+	}
+	s.Close()
 }
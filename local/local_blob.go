@@ -1,25 +1,36 @@
 package local
 
 import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
 	"io/fs"
 	"log"
 	"net/url"
 	"os"
 	"path/filepath"
-	"strings"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	"golang.org/x/sys/unix"
 
 	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/util"
 )
 
 const (
 	// Use distinct prefixes for temp and actual blob files, so that old temp
-	// files can be clean up.
+	// files can be clean up. Blob file names are described in dir_layout.go.
 	tempFilePrefix = "t-"
-	blobPrefix     = "b-"
+	// Locked while updating blobs.
+	lockFileName = "lock"
 
 	dirScheme = "file"
 )
@@ -33,6 +44,9 @@ func init() {
 //
 //	file:///home/myhome/mydir -> /home/myhome/mydir (note the 3 /'s)
 //	file://mydir -> ./mydir (relative to current directory)
+//
+// The shards parameter sets the number of shard levels of a new store, as for
+// NewShardedDirBlobStore (e.g. file:///home/myhome/mydir?shards=2).
 func openDirBlobStore(path string) (cloud.BlobStore, error) {
 	u, err := url.Parse(path)
 	if err != nil {
@@ -50,40 +64,147 @@ func openDirBlobStore(path string) (cloud.BlobStore, error) {
 		name = filepath.Join(u.Host, name)
 	}
 
+	if shards := u.Query().Get("shards"); shards != "" {
+		levels, err := strconv.Atoi(shards)
+		if err != nil {
+			return nil, fmt.Errorf("cloud/local: invalid shards: %s", shards)
+		}
+		return NewShardedDirBlobStore(name, levels)
+	}
 	return NewDirBlobStore(name)
 }
 
 type DirBlobStore struct {
-	dir string
+	dir         string
+	shardLevels int
 }
 
 var _ = (cloud.BlobStore)((*DirBlobStore)(nil))
-
+var _ = (cloud.ContextBlobStore)((*DirBlobStore)(nil))
+var _ = (cloud.Lister)((*DirBlobStore)(nil))
+var _ = (cloud.BlobCapabilitiesProvider)((*DirBlobStore)(nil))
+var _ = (cloud.AttributesBlobStore)((*DirBlobStore)(nil))
+var _ = (cloud.PageLister)((*DirBlobStore)(nil))
+var _ = (cloud.Copier)((*DirBlobStore)(nil))
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// NewDirBlobStore opens the store in dir, creating it if it doesn't exist. An
+// existing store keeps the shard levels it was created with, and a new store
+// isn't sharded.
 func NewDirBlobStore(dir string) (*DirBlobStore, error) {
+	return newDirBlobStore(dir, -1)
+}
+
+// NewShardedDirBlobStore opens the store in dir, creating it with shardLevels
+// levels of subdirectories if it doesn't exist. Each level has a fan-out of
+// 256. Returns an error if an existing store has a different number of levels.
+func NewShardedDirBlobStore(dir string, shardLevels int) (*DirBlobStore, error) {
+	if shardLevels < 0 || shardLevels > MaxShardLevels {
+		return nil, fmt.Errorf("cloud/local: invalid shard levels: %d", shardLevels)
+	}
+	return newDirBlobStore(dir, shardLevels)
+}
+
+func newDirBlobStore(dir string, shardLevels int) (*DirBlobStore, error) {
 	err := os.MkdirAll(dir, 0750)
 	if err != nil {
 		return nil, err
 	}
 
-	// Intentionally don't delete any existsing temp files. They could be created
-	// by another process sharing the same store.
-
-	return &DirBlobStore{
+	s := &DirBlobStore{
 		dir: dir,
-	}, nil
+	}
+	err = s.initLayout(shardLevels)
+	if err != nil {
+		return nil, err
+	}
+
+	// Temp files in use by another process sharing the store are locked, and
+	// aren't removed.
+	stats, err := s.RemoveStaleTempFiles(util.DefaultTempFileMaxAge)
+	if err != nil {
+		log.Printf("cloud/local: error removing temp files in %s: %v", dir, err)
+	} else if stats.Files > 0 {
+		log.Printf("cloud/local: removed %d stale temp files (%d bytes) in %s", stats.Files, stats.Bytes, dir)
+	}
+	return s, nil
+}
+
+// RemoveStaleTempFiles removes temp files left by writers which crashed, or
+// were never closed, at least maxAge ago.
+func (s *DirBlobStore) RemoveStaleTempFiles(maxAge time.Duration) (util.TempFileStats, error) {
+	return util.RemoveStaleTempFiles(s.dir, tempFilePrefix, maxAge)
+}
+
+func (s *DirBlobStore) Capabilities() cloud.BlobCapabilities {
+	return cloud.BlobCapabilities{
+		List:          true,
+		Attributes:    true,
+		Preconditions: true,
+		Consistency:   cloud.ConsistencyStrong,
+	}
+}
+
+// Takes an exclusive lock on the store, which serialises blob updates between
+// processes. Returns a function to release the lock.
+func (s *DirBlobStore) lock() (func(), error) {
+	f, err := os.OpenFile(filepath.Join(s.dir, lockFileName), os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, err
+	}
+	err = unix.Flock(int(f.Fd()), unix.LOCK_EX)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	// Closing the file releases the lock.
+	return func() { f.Close() }, nil
+}
+
+// Atomically replaces the file at path with buf.
+func (s *DirBlobStore) writeFile(path string, buf []byte) error {
+	f, err := s.writeTempFile(buf)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	return os.Rename(f.Name(), path)
+}
+
+// Writes buf to a new temp file. The file is left open, which keeps it locked
+// against temp file GC until it is closed.
+func (s *DirBlobStore) writeTempFile(buf []byte) (*os.File, error) {
+	f, err := util.CreateTempFile(s.dir, tempFilePrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Write(buf)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
 }
 
-func (s *DirBlobStore) makeFilePath(key string) string {
-	// TODO: Consider encoding key to avoid issues with keys containing path
-	// elements such as '/', '.', and '..'
-	return filepath.Join(s.dir, blobPrefix+key)
+// The inode changes with every Put, since blobs are renamed into place.
+func fileETag(fi fs.FileInfo) string {
+	etag := strconv.FormatInt(fi.ModTime().UnixNano(), 16) + "-" + strconv.FormatInt(fi.Size(), 16)
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		etag += "-" + strconv.FormatUint(st.Ino, 16)
+	}
+	return etag
 }
 
 func (s *DirBlobStore) Size(key string) (int64, error) {
-	path := s.makeFilePath(key)
-	fi, err := os.Stat(path)
+	_, fi, err := s.findBlob(key)
 	if errors.Is(err, fs.ErrNotExist) {
-		return 0, fs.ErrNotExist
+		return 0, cloud.NewError("size", key, cloud.KindNotFound, fs.ErrNotExist)
 	} else if err != nil {
 		return 0, err
 	}
@@ -92,19 +213,28 @@ func (s *DirBlobStore) Size(key string) (int64, error) {
 
 type fileBlobReader struct {
 	*os.File
+	key  string
 	size int64
 
-	mmaps [][]byte
+	mmaps util.Mappings
+
+	// The attributes sidecar is loaded on the first read, and its block
+	// checksums used to verify blocks as they are read.
+	attrsPath    string
+	fi           fs.FileInfo
+	verifierOnce sync.Once
+	// nil if the blob has no block checksums.
+	verifier    *blockVerifier
+	verifierErr error
 }
 
+var _ = (cloud.MemMapper)((*fileBlobReader)(nil))
+
 func (r *fileBlobReader) Close() error {
-	for _, m := range r.mmaps {
-		err := unix.Munmap(m)
-		if err != nil {
-			log.Printf("cloud/local: Munmap error: %v", err)
-		}
+	err := r.mmaps.Unmap()
+	if err != nil {
+		log.Printf("cloud/local: Munmap error: %v", err)
 	}
-	r.mmaps = nil
 	return r.File.Close()
 }
 
@@ -112,19 +242,61 @@ func (r *fileBlobReader) Size() int64 {
 	return r.size
 }
 
+func (r *fileBlobReader) getVerifier() (*blockVerifier, error) {
+	r.verifierOnce.Do(func() {
+		stored, err := readAttrs(r.attrsPath, r.key, r.fi)
+		if err != nil {
+			r.verifierErr = err
+		} else if stored != nil && len(stored.BlockCRC32C) > 0 {
+			r.verifier = newBlockVerifier(stored.BlockSize, stored.BlockCRC32C, r.size)
+		}
+	})
+	return r.verifier, r.verifierErr
+}
+
+func (r *fileBlobReader) ReadAt(b []byte, off int64) (int, error) {
+	v, err := r.getVerifier()
+	if err != nil {
+		return 0, err
+	} else if v != nil {
+		return v.readAt(r.File, r.key, b, off)
+	}
+	return r.File.ReadAt(b, off)
+}
+
+// Blob files are never modified in place, so mappings are stable. Blocks are
+// verified from the mapping, which covers the whole blocks of the range.
 func (r *fileBlobReader) MemMap(offset int64, length int) ([]byte, error) {
-	mmap, err := unix.Mmap(int(r.File.Fd()), offset, length, unix.PROT_READ, unix.MAP_SHARED)
-	if len(mmap) > 0 {
-		r.mmaps = append(r.mmaps, mmap)
+	if offset < 0 || length < 0 || offset+int64(length) > r.size {
+		return nil, io.ErrUnexpectedEOF
 	}
-	return mmap, err
+	v, err := r.getVerifier()
+	if err != nil {
+		return nil, err
+	} else if v == nil || length == 0 {
+		return r.mmaps.Map(r.File, offset, length)
+	}
+
+	start, end := v.blockRange(offset, length)
+	m, err := r.mmaps.Map(r.File, start, int(end-start))
+	if err != nil {
+		return nil, err
+	}
+	err = v.check(r.key, m, start)
+	if err != nil {
+		return nil, err
+	}
+	return m[offset-start : offset-start+int64(length)], nil
 }
 
 func (s *DirBlobStore) Get(key string) (cloud.GetReader, error) {
-	path := s.makeFilePath(key)
-	f, err := os.Open(path)
+	paths, _, err := s.findBlob(key)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	f, err := os.Open(paths.blob)
 	if errors.Is(err, fs.ErrNotExist) {
-		return nil, fs.ErrNotExist
+		return nil, cloud.NewError("get", key, cloud.KindNotFound, fs.ErrNotExist)
 	} else if err != nil {
 		return nil, err
 	}
@@ -134,84 +306,472 @@ func (s *DirBlobStore) Get(key string) (cloud.GetReader, error) {
 		f.Close()
 		return nil, err
 	}
+	r := &fileBlobReader{
+		File:      f,
+		key:       key,
+		size:      fi.Size(),
+		attrsPath: paths.attrs,
+		fi:        fi,
+	}
+	return r, nil
+}
 
-	return &fileBlobReader{
-		File: f,
-		size: fi.Size(),
-	}, nil
+// Stored in the attributes sidecar file. The sidecar is moved into place after
+// the blob, so the blob's size and modification time are recorded to detect a
+// stale sidecar left by an interrupted update.
+type blobAttrs struct {
+	Size        int64             `json:"size"`
+	ModTime     time.Time         `json:"mtime"`
+	ContentType string            `json:"content_type,omitempty"`
+	MD5         []byte            `json:"md5"`
+	CRC32C      []byte            `json:"crc32c"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	// CRC32C of each BlockSize block of the blob, to verify reads. Absent for
+	// blobs written before block checksums were stored.
+	BlockSize   int64    `json:"block_size,omitempty"`
+	BlockCRC32C []uint32 `json:"block_crc32c,omitempty"`
+}
 
+// Returns the attributes stored in the sidecar at path, for the blob file with
+// info fi. Returns nil if there is no sidecar, or it is stale.
+func readAttrs(path, key string, fi fs.FileInfo) (*blobAttrs, error) {
+	// Blobs written before attributes were stored have no sidecar.
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var stored blobAttrs
+	err = json.Unmarshal(buf, &stored)
+	if err != nil {
+		return nil, fmt.Errorf("cloud/local: invalid attributes for %s: %w", key, err)
+	}
+	if stored.Size != fi.Size() || !stored.ModTime.Equal(fi.ModTime()) {
+		// Stale sidecar from an interrupted Put.
+		return nil, nil
+	}
+	return &stored, nil
 }
 
 type fileBlobWriter struct {
-	*os.File
-	path string
+	f         *os.File
+	s         *DirBlobStore
+	key       string
+	ctx       context.Context
+	options   *cloud.PutOptions
+	size      int64
+	md5Hash   hash.Hash
+	crc32Hash hash.Hash32
+	blockSums blockSummer
+}
+
+func (w *fileBlobWriter) Write(b []byte) (int, error) {
+	n, err := w.f.Write(b)
+	w.md5Hash.Write(b[:n])
+	w.crc32Hash.Write(b[:n])
+	w.blockSums.Write(b[:n])
+	w.size += int64(n)
+	return n, err
+}
+
+// Writes the blob's attributes to a temp file, to be moved into place once the
+// blob has been committed.
+func (w *fileBlobWriter) writeAttrs(modTime time.Time) (*os.File, error) {
+	attrs := blobAttrs{
+		Size:    w.size,
+		ModTime: modTime,
+		MD5:     w.md5Hash.Sum(nil),
+		CRC32C:  w.crc32Hash.Sum(nil),
+
+		BlockSize:   checksumBlockSize,
+		BlockCRC32C: w.blockSums.Sums(),
+	}
+	if w.options != nil {
+		attrs.ContentType = w.options.ContentType
+		attrs.Metadata = w.options.Metadata
+	}
+	buf, err := json.Marshal(&attrs)
+	if err != nil {
+		return nil, err
+	}
+	return w.s.writeTempFile(buf)
 }
 
 func (w *fileBlobWriter) Close() error {
-	defer os.Remove(w.File.Name())
+	if w.f == nil {
+		return nil
+	}
+	if err := w.ctx.Err(); err != nil {
+		w.Cancel()
+		return err
+	}
 
-	err := w.File.Sync()
+	defer os.Remove(w.f.Name())
+
+	err := w.f.Sync()
 	if err != nil {
 		// Close the file on sync error to avoid an FD leak
-		w.File.Close()
+		w.f.Close()
 		return err
 	}
-	err = w.File.Close()
+	fi, err := w.f.Stat()
 	if err != nil {
+		w.f.Close()
 		return err
 	}
-	err = os.Rename(w.File.Name(), w.path)
+	// Keep the file open, and locked against temp file GC, until it has been
+	// moved into place.
+	err = w.commit(fi.ModTime())
+	closeErr := w.f.Close()
 	if err != nil {
 		return err
+	} else if closeErr != nil {
+		return closeErr
 	}
-	w.File = nil
+	w.f = nil
 	return nil
 }
 
+// Checks preconditions, and moves the blob and its attributes into place.
+func (w *fileBlobWriter) commit(modTime time.Time) error {
+	unlock, err := w.s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if w.options.HasPreconditions() {
+		_, fi, err := w.s.findBlob(w.key)
+		exists := err == nil
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if w.options.IfNotExist && exists {
+			return cloud.ErrPreconditionFailed
+		} else if w.options.IfMatch != "" && (!exists || fileETag(fi) != w.options.IfMatch) {
+			return cloud.ErrPreconditionFailed
+		}
+	}
+
+	paths := w.s.makePaths(w.key)
+	err = os.MkdirAll(filepath.Dir(paths.blob), 0750)
+	if err != nil {
+		return err
+	}
+	// The attributes are only moved into place once the blob has been, so that
+	// a failed precondition leaves the existing blob's attributes intact.
+	attrsFile, err := w.writeAttrs(modTime)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(attrsFile.Name())
+	defer attrsFile.Close()
+	if w.options != nil && w.options.IfNotExist {
+		// Unlike rename, link fails if the blob has been created by a writer
+		// not holding the lock.
+		err = os.Link(w.f.Name(), paths.blob)
+		if errors.Is(err, fs.ErrExist) {
+			return cloud.ErrPreconditionFailed
+		}
+	} else {
+		err = os.Rename(w.f.Name(), paths.blob)
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Rename(attrsFile.Name(), paths.attrs)
+	if err != nil {
+		return err
+	}
+	return w.s.removeLegacy(w.key)
+}
+
 func (w *fileBlobWriter) Cancel() error {
-	if w.File == nil {
+	if w.f == nil {
 		// Writer has been closed successfully
 		return nil
 	}
-	err := w.File.Close()
+	err := w.f.Close()
 	if err != nil {
 		return err
 	}
-	return os.Remove(w.File.Name())
+	return os.Remove(w.f.Name())
 }
 
-func (s *DirBlobStore) Put(key string) (cloud.PutWriter, error) {
-	f, err := os.CreateTemp(s.dir, tempFilePrefix+"*")
+func (s *DirBlobStore) PutContext(ctx context.Context, key string) (cloud.PutWriter, error) {
+	return s.PutWithOptionsContext(ctx, key, nil)
+}
+
+func (s *DirBlobStore) PutWithOptionsContext(ctx context.Context, key string, options *cloud.PutOptions) (cloud.PutWriter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f, err := util.CreateTempFile(s.dir, tempFilePrefix+"*")
 	if err != nil {
 		return nil, err
 	}
 
 	return &fileBlobWriter{
-		File: f,
-		path: s.makeFilePath(key),
+		f:         f,
+		s:         s,
+		key:       key,
+		ctx:       ctx,
+		options:   options,
+		md5Hash:   md5.New(),
+		crc32Hash: crc32.New(crc32cTable),
 	}, nil
 }
 
+func (s *DirBlobStore) PutWithOptions(key string, options *cloud.PutOptions) (cloud.PutWriter, error) {
+	return s.PutWithOptionsContext(context.Background(), key, options)
+}
+
+func (s *DirBlobStore) Put(key string) (cloud.PutWriter, error) {
+	return s.PutContext(context.Background(), key)
+}
+
 func (s *DirBlobStore) Delete(key string) error {
-	path := s.makeFilePath(key)
-	err := os.Remove(path)
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	paths, _, err := s.findBlob(key)
 	if errors.Is(err, fs.ErrNotExist) {
-		return fs.ErrNotExist
+		return cloud.NewError("delete", key, cloud.KindNotFound, fs.ErrNotExist)
+	} else if err != nil {
+		return err
 	}
-	return err
+	err = os.Remove(paths.blob)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(paths.attrs)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("cloud/local: error removing attributes of %s: %v", key, err)
+	}
+	// Also remove any stale legacy copy, so that it doesn't reappear.
+	return s.removeLegacy(key)
 }
 
-func (s *DirBlobStore) List() ([]string, error) {
-	dirents, err := os.ReadDir(s.dir)
+func (s *DirBlobStore) Attributes(key string) (*cloud.BlobAttributes, error) {
+	paths, fi, err := s.findBlob(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, cloud.NewError("attributes", key, cloud.KindNotFound, fs.ErrNotExist)
+	} else if err != nil {
+		return nil, err
+	}
+	attrs := &cloud.BlobAttributes{
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		ETag:    fileETag(fi),
+	}
+
+	stored, err := readAttrs(paths.attrs, key, fi)
 	if err != nil {
 		return nil, err
+	} else if stored == nil {
+		return attrs, nil
+	}
+	attrs.ContentType = stored.ContentType
+	attrs.MD5 = stored.MD5
+	attrs.CRC32C = stored.CRC32C
+	attrs.Metadata = stored.Metadata
+	return attrs, nil
+}
+
+func (s *DirBlobStore) AttributesContext(ctx context.Context, key string) (*cloud.BlobAttributes, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	entries := make([]string, 0, len(dirents))
-	for _, d := range dirents {
-		if !strings.HasPrefix(d.Name(), blobPrefix) {
-			continue
+	return s.Attributes(key)
+}
+
+func (s *DirBlobStore) SizeContext(ctx context.Context, key string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.Size(key)
+}
+
+func (s *DirBlobStore) GetContext(ctx context.Context, key string) (cloud.GetReader, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.Get(key)
+}
+
+func (s *DirBlobStore) DeleteContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(key)
+}
+
+// Returns keys in sorted order.
+func (s *DirBlobStore) List() ([]string, error) {
+	return s.listKeys()
+}
+
+// The directory tree is read in full for every page, but only blobs in the
+// returned page are stat'd.
+func (s *DirBlobStore) ListPage(ctx context.Context, options *cloud.ListOptions, pageToken string) ([]cloud.BlobEntry, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	keys, err := s.List()
+	if err != nil {
+		return nil, "", err
+	}
+	entries, next := cloud.PageKeys(keys, options, pageToken)
+	out := entries[:0]
+	for _, e := range entries {
+		if !e.IsDir {
+			_, fi, err := s.findBlob(e.Key)
+			if errors.Is(err, fs.ErrNotExist) {
+				// Deleted since listing.
+				continue
+			} else if err != nil {
+				return nil, "", err
+			}
+			e.Size = fi.Size()
+			e.ModTime = fi.ModTime()
 		}
-		entries = append(entries, strings.TrimPrefix(d.Name(), blobPrefix))
+		out = append(out, e)
+	}
+	return out, next, nil
+}
+
+// Blobs are never modified in place, so a copy is a hard link to the same
+// file.
+func (s *DirBlobStore) Copy(src, dst string) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	srcPaths, _, err := s.findBlob(src)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cloud.NewError("copy", src, cloud.KindNotFound, fs.ErrNotExist)
+	} else if err != nil {
+		return err
+	}
+
+	dstPaths := s.makePaths(dst)
+	err = os.MkdirAll(filepath.Dir(dstPaths.blob), 0750)
+	if err != nil {
+		return err
+	}
+	attrsBuf, err := os.ReadFile(srcPaths.attrs)
+	if errors.Is(err, fs.ErrNotExist) {
+		attrsBuf = nil
+	} else if err != nil {
+		return err
+	}
+
+	// Link to a temp file first, since link doesn't replace an existing dst.
+	// The temp link has the source's mtime, so it must be locked against temp
+	// file GC until it has been renamed into place.
+	srcFile, err := util.LockFile(srcPaths.blob)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+	f, err := util.CreateTempFile(s.dir, tempFilePrefix+"*")
+	if err != nil {
+		return err
+	}
+	tempName := f.Name()
+	f.Close()
+	os.Remove(tempName)
+	err = os.Link(srcPaths.blob, tempName)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tempName, dstPaths.blob)
+	if err != nil {
+		os.Remove(tempName)
+		return err
+	}
+
+	// As with Put, the attributes are only moved into place once the blob has
+	// been.
+	if attrsBuf != nil {
+		err = s.writeFile(dstPaths.attrs, attrsBuf)
+	} else {
+		err = removeIfExists(dstPaths.attrs)
+	}
+	if err != nil {
+		// Don't leave dst's previous attributes next to the copied blob.
+		os.Remove(dstPaths.attrs)
+		return err
+	}
+	return s.removeLegacy(dst)
+}
+
+func removeIfExists(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *DirBlobStore) CopyContext(ctx context.Context, src, dst string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Copy(src, dst)
+}
+
+func (s *DirBlobStore) Rename(src, dst string) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	srcPaths, _, err := s.findBlob(src)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cloud.NewError("rename", src, cloud.KindNotFound, fs.ErrNotExist)
+	} else if err != nil {
+		return err
+	}
+
+	dstPaths := s.makePaths(dst)
+	err = os.MkdirAll(filepath.Dir(dstPaths.blob), 0750)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(srcPaths.blob, dstPaths.blob)
+	if err != nil {
+		return err
+	}
+
+	// As with Put, the attributes are only moved into place once the blob has
+	// been.
+	err = os.Rename(srcPaths.attrs, dstPaths.attrs)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = removeIfExists(dstPaths.attrs)
+	}
+	if err != nil {
+		// Don't leave dst's previous attributes next to the renamed blob, or
+		// src's attributes without a blob.
+		os.Remove(dstPaths.attrs)
+		os.Remove(srcPaths.attrs)
+		return err
+	}
+	return s.removeLegacy(dst)
+}
+
+func (s *DirBlobStore) RenameContext(ctx context.Context, src, dst string) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	return entries, nil
+	return s.Rename(src, dst)
 }
@@ -0,0 +1,279 @@
+package local
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/test_util"
+)
+
+func TestDirBlobStore(t *testing.T) {
+	s, err := NewDirBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	test_util.TestBlobStore(t, s)
+	test_util.TestBlobCancel(t, s)
+
+	test_util.TestBlobAttributes(t, s)
+	test_util.TestBlobPreconditions(t, s)
+	test_util.TestBlobCopy(t, s)
+	test_util.TestBlobMemMap(t, s)
+
+	// Test assumes stores are empty to start
+	s, err = NewDirBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	test_util.TestBlobList(t, s)
+}
+
+func TestDirBlobStore_Sharded(t *testing.T) {
+	s, err := NewShardedDirBlobStore(t.TempDir(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test_util.TestBlobStore(t, s)
+	test_util.TestBlobAttributes(t, s)
+	test_util.TestBlobPreconditions(t, s)
+	test_util.TestBlobCopy(t, s)
+
+	s, err = NewShardedDirBlobStore(t.TempDir(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test_util.TestBlobList(t, s)
+}
+
+func putBlob(t *testing.T, s cloud.BlobStore, key, data string) {
+	t.Helper()
+	w, err := s.Put(key)
+	if err != nil {
+		t.Fatalf("Put(%q) error = %v", key, err)
+	}
+	w.Write([]byte(data))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Put(%q) Close error = %v", key, err)
+	}
+}
+
+func readBlob(t *testing.T, s cloud.BlobStore, key string) string {
+	t.Helper()
+	r, err := s.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%q) error = %v", key, err)
+	}
+	defer r.Close()
+	buf, err := io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+	if err != nil {
+		t.Fatalf("Get(%q) read error = %v", key, err)
+	}
+	return string(buf)
+}
+
+func TestDirBlobStore_KeyEncoding(t *testing.T) {
+	dir := t.TempDir()
+	for _, levels := range []int{0, 1} {
+		storeDir := filepath.Join(dir, strings.Repeat("s", levels+1))
+		s, err := NewShardedDirBlobStore(storeDir, levels)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keys := []string{
+			"..",
+			".",
+			"../escape",
+			"a/b/c",
+			"/leading",
+			"Case",
+			"case",
+			"lock",
+			strings.Repeat("long", 200),
+		}
+		for _, k := range keys {
+			putBlob(t, s, k, "data-"+k)
+		}
+		for _, k := range keys {
+			if got := readBlob(t, s, k); got != "data-"+k {
+				t.Errorf("Get(%q) = %q", k, got)
+			}
+		}
+
+		listed, err := s.List()
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		expected := slices.Clone(keys)
+		slices.Sort(expected)
+		if !slices.Equal(listed, expected) {
+			t.Errorf("List() = %q, expected %q", listed, expected)
+		}
+
+		// Nothing escapes the store directory.
+		if _, err := os.Stat(filepath.Join(dir, "escape")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Stat(escape) error = %v", err)
+		}
+
+		for _, k := range keys {
+			if err := s.Delete(k); err != nil {
+				t.Errorf("Delete(%q) error = %v", k, err)
+			}
+		}
+		listed, err = s.List()
+		if err != nil || len(listed) != 0 {
+			t.Errorf("List() = %q, %v", listed, err)
+		}
+	}
+}
+
+func TestDirBlobStore_Layout(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewShardedDirBlobStore(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	putBlob(t, s, "key", "value")
+
+	// Reopening uses the stored layout.
+	s, err = NewDirBlobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readBlob(t, s, "key"); got != "value" {
+		t.Errorf("Get(key) = %q", got)
+	}
+	s2, err := cloud.OpenBlobStore("file://" + dir + "?shards=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readBlob(t, s2, "key"); got != "value" {
+		t.Errorf("Get(key) = %q", got)
+	}
+
+	_, err = NewShardedDirBlobStore(dir, 1)
+	if err == nil {
+		t.Errorf("NewShardedDirBlobStore with mismatched levels succeeded")
+	}
+	_, err = NewShardedDirBlobStore(t.TempDir(), MaxShardLevels+1)
+	if err == nil {
+		t.Errorf("NewShardedDirBlobStore with too many levels succeeded")
+	}
+}
+
+// Writes blobs as they were stored before keys were encoded.
+func writeLegacyBlob(t *testing.T, dir, key, data string) {
+	t.Helper()
+	err := os.WriteFile(filepath.Join(dir, legacyBlobPrefix+key), []byte(data), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirBlobStore_Legacy(t *testing.T) {
+	dir := t.TempDir()
+	writeLegacyBlob(t, dir, "old1", "value1")
+	writeLegacyBlob(t, dir, "old2", "value2")
+	writeLegacyBlob(t, dir, "old3", "value3")
+
+	s, err := NewShardedDirBlobStore(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	putBlob(t, s, "new", "value")
+
+	if got := readBlob(t, s, "old1"); got != "value1" {
+		t.Errorf("Get(old1) = %q", got)
+	}
+	if size, err := s.Size("old1"); err != nil || size != 6 {
+		t.Errorf("Size(old1) = %d, %v", size, err)
+	}
+	keys, err := s.List()
+	if err != nil || !slices.Equal(keys, []string{"new", "old1", "old2", "old3"}) {
+		t.Errorf("List() = %q, %v", keys, err)
+	}
+
+	// Overwriting moves the blob to the current layout.
+	putBlob(t, s, "old1", "updated")
+	if _, err := os.Stat(filepath.Join(dir, legacyBlobPrefix+"old1")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat(legacy old1) error = %v", err)
+	}
+	if got := readBlob(t, s, "old1"); got != "updated" {
+		t.Errorf("Get(old1) = %q", got)
+	}
+
+	err = s.Delete("old2")
+	if err != nil {
+		t.Errorf("Delete(old2) error = %v", err)
+	}
+	if _, err := s.Size("old2"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Size(old2) error = %v", err)
+	}
+
+	err = s.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	legacy, err := s.legacyKeys()
+	if err != nil || len(legacy) != 0 {
+		t.Errorf("legacyKeys() = %q, %v", legacy, err)
+	}
+	if got := readBlob(t, s, "old3"); got != "value3" {
+		t.Errorf("Get(old3) = %q", got)
+	}
+	keys, err = s.List()
+	if err != nil || !slices.Equal(keys, []string{"new", "old1", "old3"}) {
+		t.Errorf("List() = %q, %v", keys, err)
+	}
+}
+
+func TestDirBlobStore_FailedPrecondition(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewDirBlobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := s.PutWithOptions("blob", &cloud.PutOptions{ContentType: "text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("original"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err = s.PutWithOptions("blob", &cloud.PutOptions{ContentType: "text/html", IfNotExist: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("replacement"))
+	if err := w.Close(); !errors.Is(err, cloud.ErrPreconditionFailed) {
+		t.Fatalf("Close() error = %v, expected ErrPreconditionFailed", err)
+	}
+
+	// The existing blob and its attributes are unchanged.
+	attrs, err := s.Attributes("blob")
+	if err != nil {
+		t.Fatalf("Attributes(blob) error = %v", err)
+	} else if attrs.ContentType != "text/plain" || attrs.Size != int64(len("original")) {
+		t.Errorf("Attributes(blob) = %+v after failed precondition", attrs)
+	}
+	if data := readBlob(t, s, "blob"); data != "original" {
+		t.Errorf("Get(blob) = %q after failed precondition", data)
+	}
+	dirents, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range dirents {
+		if strings.HasPrefix(d.Name(), tempFilePrefix) {
+			t.Errorf("Temp file %s left behind", d.Name())
+		}
+	}
+}
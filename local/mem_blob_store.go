@@ -0,0 +1,334 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/akmistry/cloud-util"
+)
+
+// Blobs are immutable once written, so readers and copies share data.
+type memBlob struct {
+	data        []byte
+	modTime     time.Time
+	etag        string
+	contentType string
+	metadata    map[string]string
+	md5         []byte
+	crc32c      []byte
+}
+
+// In-memory BlobStore, for tests. Behaves like DirBlobStore, except that
+// blobs are lost when the store is garbage collected.
+type MemBlobStore struct {
+	lock  sync.Mutex
+	blobs map[string]*memBlob
+	// Incremented on every write, and used as the ETag.
+	generation uint64
+}
+
+var _ = (cloud.BlobStore)((*MemBlobStore)(nil))
+var _ = (cloud.ContextBlobStore)((*MemBlobStore)(nil))
+var _ = (cloud.Lister)((*MemBlobStore)(nil))
+var _ = (cloud.PageLister)((*MemBlobStore)(nil))
+var _ = (cloud.AttributesBlobStore)((*MemBlobStore)(nil))
+var _ = (cloud.Copier)((*MemBlobStore)(nil))
+var _ = (cloud.BlobCapabilitiesProvider)((*MemBlobStore)(nil))
+
+func NewMemBlobStore() *MemBlobStore {
+	return &MemBlobStore{
+		blobs: make(map[string]*memBlob),
+	}
+}
+
+func (s *MemBlobStore) Capabilities() cloud.BlobCapabilities {
+	return cloud.BlobCapabilities{
+		List:          true,
+		Attributes:    true,
+		Preconditions: true,
+		Consistency:   cloud.ConsistencyStrong,
+	}
+}
+
+func (s *MemBlobStore) get(key string) *memBlob {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.blobs[key]
+}
+
+// Must be called with the lock held.
+func (s *MemBlobStore) nextETag() string {
+	s.generation++
+	return strconv.FormatUint(s.generation, 16)
+}
+
+func (s *MemBlobStore) Size(key string) (int64, error) {
+	b := s.get(key)
+	if b == nil {
+		return 0, cloud.NewError("size", key, cloud.KindNotFound, fs.ErrNotExist)
+	}
+	return int64(len(b.data)), nil
+}
+
+type memBlobReader struct {
+	*bytes.Reader
+	data []byte
+}
+
+var _ = (cloud.MemMapper)((*memBlobReader)(nil))
+
+func (r *memBlobReader) Close() error {
+	return nil
+}
+
+// MemMap returns the blob's data without copying. The returned slice must not
+// be modified.
+func (r *memBlobReader) MemMap(offset int64, length int) ([]byte, error) {
+	if offset < 0 || length < 0 || offset+int64(length) > int64(len(r.data)) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return r.data[offset : offset+int64(length)], nil
+}
+
+func (s *MemBlobStore) Get(key string) (cloud.GetReader, error) {
+	b := s.get(key)
+	if b == nil {
+		return nil, cloud.NewError("get", key, cloud.KindNotFound, fs.ErrNotExist)
+	}
+	return &memBlobReader{Reader: bytes.NewReader(b.data), data: b.data}, nil
+}
+
+type memBlobWriter struct {
+	s       *MemBlobStore
+	key     string
+	ctx     context.Context
+	options *cloud.PutOptions
+	buf     bytes.Buffer
+	// Set once the writer is closed or cancelled.
+	closed bool
+}
+
+func (w *memBlobWriter) Write(b []byte) (int, error) {
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	return w.buf.Write(b)
+}
+
+func (w *memBlobWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	if err := w.ctx.Err(); err != nil {
+		w.Cancel()
+		return err
+	}
+
+	data := w.buf.Bytes()
+	md5Sum := md5.Sum(data)
+	b := &memBlob{
+		data:    data,
+		modTime: time.Now(),
+		md5:     md5Sum[:],
+		crc32c:  binary.BigEndian.AppendUint32(nil, crc32.Checksum(data, crc32cTable)),
+	}
+	if w.options != nil {
+		b.contentType = w.options.ContentType
+		b.metadata = maps.Clone(w.options.Metadata)
+	}
+
+	w.s.lock.Lock()
+	defer w.s.lock.Unlock()
+
+	w.closed = true
+	if w.options.HasPreconditions() {
+		old := w.s.blobs[w.key]
+		if w.options.IfNotExist && old != nil {
+			return cloud.ErrPreconditionFailed
+		} else if w.options.IfMatch != "" && (old == nil || old.etag != w.options.IfMatch) {
+			return cloud.ErrPreconditionFailed
+		}
+	}
+	b.etag = w.s.nextETag()
+	w.s.blobs[w.key] = b
+	return nil
+}
+
+func (w *memBlobWriter) Cancel() error {
+	if w.closed {
+		// Writer has been closed successfully
+		return nil
+	}
+	w.closed = true
+	w.buf = bytes.Buffer{}
+	return nil
+}
+
+func (s *MemBlobStore) Put(key string) (cloud.PutWriter, error) {
+	return s.PutWithOptionsContext(context.Background(), key, nil)
+}
+
+func (s *MemBlobStore) PutWithOptions(key string, options *cloud.PutOptions) (cloud.PutWriter, error) {
+	return s.PutWithOptionsContext(context.Background(), key, options)
+}
+
+func (s *MemBlobStore) PutContext(ctx context.Context, key string) (cloud.PutWriter, error) {
+	return s.PutWithOptionsContext(ctx, key, nil)
+}
+
+func (s *MemBlobStore) PutWithOptionsContext(ctx context.Context, key string, options *cloud.PutOptions) (cloud.PutWriter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &memBlobWriter{
+		s:       s,
+		key:     key,
+		ctx:     ctx,
+		options: options,
+	}, nil
+}
+
+func (s *MemBlobStore) Delete(key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.blobs[key]; !ok {
+		return cloud.NewError("delete", key, cloud.KindNotFound, fs.ErrNotExist)
+	}
+	delete(s.blobs, key)
+	return nil
+}
+
+func (s *MemBlobStore) Attributes(key string) (*cloud.BlobAttributes, error) {
+	b := s.get(key)
+	if b == nil {
+		return nil, cloud.NewError("attributes", key, cloud.KindNotFound, fs.ErrNotExist)
+	}
+	return &cloud.BlobAttributes{
+		Size:        int64(len(b.data)),
+		ContentType: b.contentType,
+		ETag:        b.etag,
+		ModTime:     b.modTime,
+		MD5:         b.md5,
+		CRC32C:      b.crc32c,
+		Metadata:    maps.Clone(b.metadata),
+	}, nil
+}
+
+func (s *MemBlobStore) List() ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.sortedKeys(), nil
+}
+
+// Must be called with the lock held.
+func (s *MemBlobStore) sortedKeys() []string {
+	keys := make([]string, 0, len(s.blobs))
+	for k := range s.blobs {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (s *MemBlobStore) ListPage(ctx context.Context, options *cloud.ListOptions, pageToken string) ([]cloud.BlobEntry, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entries, next := cloud.PageKeys(s.sortedKeys(), options, pageToken)
+	for i, e := range entries {
+		if !e.IsDir {
+			b := s.blobs[e.Key]
+			entries[i].Size = int64(len(b.data))
+			entries[i].ModTime = b.modTime
+		}
+	}
+	return entries, next, nil
+}
+
+// Blobs are immutable, so the copy shares the source's data.
+func (s *MemBlobStore) Copy(src, dst string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	b, ok := s.blobs[src]
+	if !ok {
+		return cloud.NewError("copy", src, cloud.KindNotFound, fs.ErrNotExist)
+	}
+	copied := *b
+	copied.modTime = time.Now()
+	copied.etag = s.nextETag()
+	s.blobs[dst] = &copied
+	return nil
+}
+
+func (s *MemBlobStore) Rename(src, dst string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	b, ok := s.blobs[src]
+	if !ok {
+		return cloud.NewError("rename", src, cloud.KindNotFound, fs.ErrNotExist)
+	}
+	delete(s.blobs, src)
+	s.blobs[dst] = b
+	return nil
+}
+
+func (s *MemBlobStore) AttributesContext(ctx context.Context, key string) (*cloud.BlobAttributes, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.Attributes(key)
+}
+
+func (s *MemBlobStore) SizeContext(ctx context.Context, key string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.Size(key)
+}
+
+func (s *MemBlobStore) GetContext(ctx context.Context, key string) (cloud.GetReader, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.Get(key)
+}
+
+func (s *MemBlobStore) DeleteContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(key)
+}
+
+func (s *MemBlobStore) CopyContext(ctx context.Context, src, dst string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Copy(src, dst)
+}
+
+func (s *MemBlobStore) RenameContext(ctx context.Context, src, dst string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Rename(src, dst)
+}
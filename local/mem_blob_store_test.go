@@ -0,0 +1,103 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/test_util"
+)
+
+func TestMemBlobStore(t *testing.T) {
+	s := NewMemBlobStore()
+	test_util.TestBlobStore(t, s)
+	test_util.TestBlobCancel(t, s)
+
+	test_util.TestBlobAttributes(t, s)
+	test_util.TestBlobPreconditions(t, s)
+	test_util.TestBlobCopy(t, s)
+	test_util.TestBlobMemMap(t, s)
+
+	// Test assumes stores are empty to start
+	test_util.TestBlobList(t, NewMemBlobStore())
+}
+
+func TestMemBlobStore_MemMap(t *testing.T) {
+	s := NewMemBlobStore()
+	w, _ := s.Put("blob")
+	w.Write([]byte("some blob data"))
+	w.Close()
+
+	r1, err := s.Get("blob")
+	if err != nil {
+		t.Fatalf("Get(blob) error = %v", err)
+	}
+	defer r1.Close()
+	r2, err := s.Get("blob")
+	if err != nil {
+		t.Fatalf("Get(blob) error = %v", err)
+	}
+	defer r2.Close()
+
+	type memMapper interface {
+		MemMap(offset int64, length int) ([]byte, error)
+	}
+	m1, err := r1.(memMapper).MemMap(5, 4)
+	if err != nil || string(m1) != "blob" {
+		t.Fatalf("MemMap(5, 4) = %q, %v", m1, err)
+	}
+	m2, _ := r2.(memMapper).MemMap(5, 4)
+	if &m1[0] != &m2[0] {
+		t.Errorf("Readers don't share data")
+	}
+	_, err = r1.(memMapper).MemMap(5, 100)
+	if err == nil {
+		t.Errorf("MemMap past end returned nil error")
+	}
+}
+
+func TestMemScheme(t *testing.T) {
+	defer ResetMemStores()
+
+	s1, err := cloud.OpenUnorderedStore("mem://test")
+	if err != nil {
+		t.Fatalf("OpenUnorderedStore error = %v", err)
+	}
+	s1.Put("key", []byte("value"), nil)
+	s2, err := cloud.OpenAtomicOrderedStore("mem://test")
+	if err != nil {
+		t.Fatalf("OpenAtomicOrderedStore error = %v", err)
+	}
+	if p, err := s2.Get("key"); err != nil || string(p.Value) != "value" {
+		t.Errorf("Get(key) from shared store = %v, %v", p, err)
+	}
+	other, _ := cloud.OpenUnorderedStore("mem://other")
+	if ok, _ := other.Exists("key"); ok {
+		t.Errorf("Store with different name shares keys")
+	}
+
+	bs1, err := cloud.OpenBlobStore("mem://test")
+	if err != nil {
+		t.Fatalf("OpenBlobStore error = %v", err)
+	}
+	w, _ := bs1.Put("blob")
+	w.Close()
+	bs2, _ := cloud.OpenBlobStore("mem://test")
+	if _, err := bs2.Size("blob"); err != nil {
+		t.Errorf("Size(blob) from shared blob store error = %v", err)
+	}
+
+	ResetMemStores()
+	s3, _ := cloud.OpenUnorderedStore("mem://test")
+	if ok, _ := s3.Exists("key"); ok {
+		t.Errorf("Store not reset")
+	}
+	bs3, _ := cloud.OpenBlobStore("mem://test")
+	if _, err := bs3.Size("blob"); err == nil {
+		t.Errorf("Blob store not reset")
+	}
+
+	_, err = cloud.OpenUnorderedStore("mem://")
+	if err == nil {
+		t.Errorf("OpenUnorderedStore(mem://) returned nil error")
+	}
+}
@@ -0,0 +1,86 @@
+package local
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/akmistry/cloud-util"
+)
+
+const (
+	memScheme = "mem"
+)
+
+var (
+	// Instances opened with mem:// URLs, by name.
+	memStores     = make(map[string]*InMemoryStore)
+	memBlobStores = make(map[string]*MemBlobStore)
+	memLock       sync.Mutex
+)
+
+func init() {
+	cloud.RegisterStoreScheme(memScheme, openMemStore)
+	cloud.RegisterBlobStoreScheme(memScheme, openMemBlobStore)
+}
+
+func parseMemName(path string) (string, error) {
+	name, ok := strings.CutPrefix(path, memScheme+"://")
+	if !ok {
+		return "", fmt.Errorf("cloud/local: invalid mem path: %s", path)
+	} else if name == "" {
+		return "", fmt.Errorf("cloud/local: empty mem store name")
+	}
+	return name, nil
+}
+
+// URL format: mem://<name>. Every open of the same name returns the same
+// InMemoryStore, until ResetMemStores is called.
+func openMemStore(path string) (cloud.UnorderedStore, error) {
+	name, err := parseMemName(path)
+	if err != nil {
+		return nil, err
+	}
+
+	memLock.Lock()
+	defer memLock.Unlock()
+	s := memStores[name]
+	if s == nil {
+		s = NewInMemoryStore()
+		memStores[name] = s
+	}
+	return s, nil
+}
+
+// URL format: mem://<name>. Every open of the same name returns the same
+// MemBlobStore, until ResetMemStores is called.
+func openMemBlobStore(path string) (cloud.BlobStore, error) {
+	name, err := parseMemName(path)
+	if err != nil {
+		return nil, err
+	}
+
+	memLock.Lock()
+	defer memLock.Unlock()
+	s := memBlobStores[name]
+	if s == nil {
+		s = NewMemBlobStore()
+		memBlobStores[name] = s
+	}
+	return s, nil
+}
+
+// ResetMemStores forgets all stores opened with mem:// URLs, so that
+// subsequent opens return new, empty stores. Stores which are already open
+// remain usable. Intended for use between tests.
+func ResetMemStores() {
+	memLock.Lock()
+	stores := memStores
+	memStores = make(map[string]*InMemoryStore)
+	memBlobStores = make(map[string]*MemBlobStore)
+	memLock.Unlock()
+
+	for _, s := range stores {
+		s.Close()
+	}
+}
@@ -0,0 +1,248 @@
+package cloud
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultLockTTL = 20 * time.Second
+)
+
+var (
+	ErrAbortTryLock = errors.New("cloud: lock acquisition aborted")
+	ErrLockNotHeld  = errors.New("cloud: lock not held")
+	ErrLockHeld     = errors.New("cloud: lock already held")
+)
+
+// Stored as the value of the lock key.
+type lockRecord struct {
+	// Random ID of the holder, for debugging.
+	Owner string `json:"owner"`
+	// Zero if the lock has been released.
+	Expiry time.Time `json:"expiry"`
+	Token  uint64    `json:"token"`
+	Value  []byte    `json:"value,omitempty"`
+}
+
+// StoreLock is a lease-based lock, stored in a single key of an
+// AtomicUnorderedStore. While held, the lease is renewed every TTL/3. Expiry
+// is based on the holder's clock, so clocks must be roughly synchronised.
+//
+// The lock key is not deleted by Unlock, so that fencing tokens continue to
+// increase across holders.
+type StoreLock struct {
+	s       AtomicUnorderedStore
+	key     string
+	value   []byte
+	ttl     time.Duration
+	renewCh <-chan struct{}
+	owner   string
+
+	lock     sync.Mutex
+	held     *KVPair
+	token    uint64
+	stopHold chan struct{}
+	holdDone chan struct{}
+}
+
+var _ = (Locker)((*StoreLock)(nil))
+
+// NewLock returns a lock on key. options.TTL is the lease duration, and
+// defaults to DefaultLockTTL. Sending on, or closing, options.RenewLock stops
+// renewal, after which the lock is considered lost.
+func NewLock(s AtomicUnorderedStore, key string, options *LockOptions) *StoreLock {
+	var idBuf [8]byte
+	rand.Read(idBuf[:])
+
+	l := &StoreLock{
+		s:     s,
+		key:   key,
+		ttl:   DefaultLockTTL,
+		owner: hex.EncodeToString(idBuf[:]),
+	}
+	if options != nil {
+		l.value = options.Value
+		if options.TTL > 0 {
+			l.ttl = options.TTL
+		}
+		l.renewCh = options.RenewLock
+	}
+	return l
+}
+
+func (l *StoreLock) record(token uint64) []byte {
+	buf, err := json.Marshal(&lockRecord{
+		Owner:  l.owner,
+		Expiry: time.Now().Add(l.ttl),
+		Token:  token,
+		Value:  l.value,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return buf
+}
+
+// Makes one attempt to acquire the lock. If the lock is held by someone else,
+// returns the time at which its lease expires.
+func (l *StoreLock) tryLock() (*KVPair, uint64, time.Time, error) {
+	pair, err := l.s.Get(l.key)
+	if err == ErrKeyNotFound {
+		_, pair, err = l.s.AtomicPut(l.key, l.record(1), nil, nil)
+		if err == ErrKeyExists {
+			return nil, 0, time.Now(), nil
+		}
+		return pair, 1, time.Time{}, err
+	} else if err != nil {
+		return nil, 0, time.Time{}, err
+	}
+
+	var rec lockRecord
+	err = json.Unmarshal(pair.Value, &rec)
+	if err != nil {
+		return nil, 0, time.Time{}, err
+	}
+	if time.Now().Before(rec.Expiry) {
+		return nil, 0, rec.Expiry, nil
+	}
+
+	token := rec.Token + 1
+	_, pair, err = l.s.AtomicPut(l.key, l.record(token), pair, nil)
+	if err == ErrKeyModified || err == ErrKeyNotFound {
+		// Lost the race with another locker.
+		return nil, 0, time.Now(), nil
+	}
+	return pair, token, time.Time{}, err
+}
+
+// Lock blocks until the lock is acquired, or stopChan is closed. The returned
+// channel is closed if the lock is lost, or after Unlock. Returns ErrLockHeld
+// if the lock is already held.
+func (l *StoreLock) Lock(stopChan chan struct{}) (<-chan struct{}, error) {
+	l.lock.Lock()
+	held := l.held != nil
+	l.lock.Unlock()
+	if held {
+		return nil, ErrLockHeld
+	}
+
+	for {
+		pair, token, expiry, err := l.tryLock()
+		if err != nil {
+			return nil, err
+		} else if pair != nil {
+			lost := make(chan struct{})
+			l.lock.Lock()
+			l.held = pair
+			l.token = token
+			l.stopHold = make(chan struct{})
+			l.holdDone = make(chan struct{})
+			go l.hold(pair, token, lost, l.stopHold, l.holdDone)
+			l.lock.Unlock()
+			return lost, nil
+		}
+
+		// Poll at least every TTL/3, since the lease may be released early.
+		wait := min(time.Until(expiry), l.ttl/3)
+		select {
+		case <-stopChan:
+			return nil, ErrAbortTryLock
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Renews the lease until stopped, or the lock is lost. Unless stopped, the
+// lock is no longer held once lost is closed.
+func (l *StoreLock) hold(pair *KVPair, token uint64, lost chan<- struct{}, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	defer close(lost)
+	stopped := false
+	defer func() {
+		if !stopped {
+			l.lock.Lock()
+			l.held = nil
+			l.lock.Unlock()
+		}
+	}()
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	expiry := time.Now().Add(l.ttl)
+	for {
+		select {
+		case <-stop:
+			stopped = true
+			return
+		case <-l.renewCh:
+			return
+		case <-ticker.C:
+		}
+
+		renewExpiry := time.Now().Add(l.ttl)
+		_, updated, err := l.s.AtomicPut(l.key, l.record(token), pair, nil)
+		if err == nil {
+			pair = updated
+			expiry = renewExpiry
+			l.lock.Lock()
+			l.held = updated
+			l.lock.Unlock()
+		} else if err == ErrKeyModified || err == ErrKeyNotFound || !time.Now().Before(expiry) {
+			// Other errors are retried until the lease expires.
+			return
+		}
+	}
+}
+
+// Token returns the fencing token of the current lease, or 0 if the lock is
+// not held. Tokens increase with every acquisition of the lock.
+func (l *StoreLock) Token() uint64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.held == nil {
+		return 0
+	}
+	return l.token
+}
+
+// Unlock releases the lock. Returns ErrLockNotHeld if the lock is not held,
+// including if it has been lost.
+func (l *StoreLock) Unlock() error {
+	l.lock.Lock()
+	stop, done := l.stopHold, l.holdDone
+	l.stopHold = nil
+	l.lock.Unlock()
+
+	if stop == nil {
+		return ErrLockNotHeld
+	}
+	close(stop)
+	<-done
+
+	l.lock.Lock()
+	pair, token := l.held, l.token
+	l.held = nil
+	l.lock.Unlock()
+
+	if pair == nil {
+		return ErrLockNotHeld
+	}
+	buf, err := json.Marshal(&lockRecord{
+		Owner: l.owner,
+		Token: token,
+	})
+	if err != nil {
+		panic(err)
+	}
+	_, _, err = l.s.AtomicPut(l.key, buf, pair, nil)
+	if err == ErrKeyModified || err == ErrKeyNotFound {
+		return ErrLockNotHeld
+	}
+	return err
+}
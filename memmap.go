@@ -0,0 +1,35 @@
+package cloud
+
+import (
+	"io"
+)
+
+// Optionally implemented by a GetReader which can return blob data without
+// copying, typically by memory mapping a local file.
+//
+// MemMap returns length bytes of the blob starting at offset, or
+// io.ErrUnexpectedEOF if the range extends past the end of the blob. The
+// returned slice must not be modified, and remains valid until the reader is
+// closed. Mapped data doesn't change if the blob is overwritten or deleted.
+type MemMapper interface {
+	MemMap(offset int64, length int) ([]byte, error)
+}
+
+// MemMap returns length bytes of r starting at offset. If r doesn't implement
+// MemMapper, the data is read into a new slice.
+func MemMap(r GetReader, offset int64, length int) ([]byte, error) {
+	if m, ok := r.(MemMapper); ok {
+		return m.MemMap(offset, length)
+	}
+	if offset < 0 || length < 0 || offset+int64(length) > r.Size() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	buf := make([]byte, length)
+	n, err := r.ReadAt(buf, offset)
+	if n == length {
+		return buf, nil
+	} else if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, err
+}
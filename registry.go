@@ -10,13 +10,21 @@ import (
 type StoreFunc func(path string) (UnorderedStore, error)
 type BlobStoreFunc func(path string) (BlobStore, error)
 
+// Wrapper functions are passed the arguments of the wrapper layer in the
+// store path, and the opened inner store.
+type StoreWrapperFunc func(args []string, s UnorderedStore) (UnorderedStore, error)
+type BlobStoreWrapperFunc func(args []string, bs BlobStore) (BlobStore, error)
+
 var (
-	ErrUnrecognisedScheme = errors.New("cloud: unrecognised store scheme")
-	ErrInvalidFormat      = errors.New("cloud: invalid store path format")
+	ErrUnrecognisedScheme  = errors.New("cloud: unrecognised store scheme")
+	ErrUnrecognisedWrapper = errors.New("cloud: unrecognised store wrapper")
+	ErrInvalidFormat       = errors.New("cloud: invalid store path format")
 
-	storeSchemeMap     = make(map[string]StoreFunc)
-	blobStoreSchemeMap = make(map[string]BlobStoreFunc)
-	schemeLock         sync.Mutex
+	storeSchemeMap      = make(map[string]StoreFunc)
+	blobStoreSchemeMap  = make(map[string]BlobStoreFunc)
+	storeWrapperMap     = make(map[string]StoreWrapperFunc)
+	blobStoreWrapperMap = make(map[string]BlobStoreWrapperFunc)
+	schemeLock          sync.Mutex
 )
 
 func RegisterStoreScheme(scheme string, fn StoreFunc) {
@@ -31,7 +39,68 @@ func RegisterBlobStoreScheme(scheme string, fn BlobStoreFunc) {
 	schemeLock.Unlock()
 }
 
+func RegisterStoreWrapper(name string, fn StoreWrapperFunc) {
+	schemeLock.Lock()
+	storeWrapperMap[name] = fn
+	schemeLock.Unlock()
+}
+
+func RegisterBlobStoreWrapper(name string, fn BlobStoreWrapperFunc) {
+	schemeLock.Lock()
+	blobStoreWrapperMap[name] = fn
+	schemeLock.Unlock()
+}
+
+// Splits a path of the form "name(arg1,arg2,...)|inner" into its wrapper
+// name, arguments and inner path. ok is false if path does not begin with a
+// wrapper layer.
+func parseWrapper(path string) (name string, args []string, inner string, ok bool, err error) {
+	open := strings.IndexByte(path, '(')
+	if open < 0 || strings.IndexByte(path[:open], ':') >= 0 {
+		// A scheme, not a wrapper.
+		return "", nil, "", false, nil
+	}
+	name = path[:open]
+	end := strings.IndexByte(path[open:], ')')
+	if name == "" || end < 0 || !strings.HasPrefix(path[open+end+1:], "|") {
+		return "", nil, "", false, ErrInvalidFormat
+	}
+	end += open
+
+	if argStr := path[open+1 : end]; argStr != "" {
+		args = strings.Split(argStr, ",")
+	}
+	return name, args, path[end+2:], true, nil
+}
+
+// OpenUnorderedStore opens the store at path, which is either a URL handled
+// by a registered scheme, or a wrapper layer of the form
+// "name(arg1,arg2,...)|inner", where inner is itself a store path. For
+// example: "prefix(app-)|rpc://localhost:1234/db".
 func OpenUnorderedStore(path string) (UnorderedStore, error) {
+	name, args, innerPath, isWrapper, err := parseWrapper(path)
+	if err != nil {
+		return nil, err
+	} else if isWrapper {
+		schemeLock.Lock()
+		wrapFn := storeWrapperMap[name]
+		schemeLock.Unlock()
+
+		if wrapFn == nil {
+			return nil, ErrUnrecognisedWrapper
+		}
+		inner, err := OpenUnorderedStore(innerPath)
+		if err != nil {
+			return nil, err
+		}
+		s, err := wrapFn(args, inner)
+		if err != nil {
+			DoStoreClose(inner)
+			return nil, err
+		}
+		return s, nil
+	}
+
 	i := strings.IndexByte(path, ':')
 	if i < 0 {
 		return nil, ErrInvalidFormat
@@ -55,13 +124,35 @@ func OpenAtomicOrderedStore(path string) (AtomicOrderedStore, error) {
 	}
 
 	aos, ok := us.(AtomicOrderedStore)
-	if !ok {
+	caps := GetCapabilities(us)
+	if !ok || !caps.Atomic || !caps.Ordered {
+		DoStoreClose(us)
 		return nil, fmt.Errorf("cloud: '%s' does not implement AtomicOrderedStore", path)
 	}
 	return aos, nil
 }
 
+// OpenBlobStore opens the blob store at path, which uses the same syntax as
+// OpenUnorderedStore.
 func OpenBlobStore(path string) (BlobStore, error) {
+	name, args, innerPath, isWrapper, err := parseWrapper(path)
+	if err != nil {
+		return nil, err
+	} else if isWrapper {
+		schemeLock.Lock()
+		wrapFn := blobStoreWrapperMap[name]
+		schemeLock.Unlock()
+
+		if wrapFn == nil {
+			return nil, ErrUnrecognisedWrapper
+		}
+		inner, err := OpenBlobStore(innerPath)
+		if err != nil {
+			return nil, err
+		}
+		return wrapFn(args, inner)
+	}
+
 	i := strings.IndexByte(path, ':')
 	if i < 0 {
 		return nil, ErrInvalidFormat
@@ -26,8 +26,15 @@ type StoreClient interface {
 	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error)
 	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
 	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error)
 	AtomicPut(ctx context.Context, in *AtomicPutRequest, opts ...grpc.CallOption) (*AtomicPutResponse, error)
 	AtomicDelete(ctx context.Context, in *AtomicDeleteRequest, opts ...grpc.CallOption) (*AtomicDeleteResponse, error)
+	GetMulti(ctx context.Context, in *GetMultiRequest, opts ...grpc.CallOption) (*GetMultiResponse, error)
+	PutMulti(ctx context.Context, in *PutMultiRequest, opts ...grpc.CallOption) (*PutMultiResponse, error)
+	DeleteMulti(ctx context.Context, in *DeleteMultiRequest, opts ...grpc.CallOption) (*DeleteMultiResponse, error)
+	Txn(ctx context.Context, in *TxnRequest, opts ...grpc.CallOption) (*TxnResponse, error)
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Store_WatchClient, error)
+	Capabilities(ctx context.Context, in *CapabilitiesRequest, opts ...grpc.CallOption) (*CapabilitiesResponse, error)
 }
 
 type storeClient struct {
@@ -74,6 +81,15 @@ func (c *storeClient) List(ctx context.Context, in *ListRequest, opts ...grpc.Ca
 	return out, nil
 }
 
+func (c *storeClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error) {
+	out := new(ScanResponse)
+	err := c.cc.Invoke(ctx, "/cloud_rpc_pb.Store/Scan", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storeClient) AtomicPut(ctx context.Context, in *AtomicPutRequest, opts ...grpc.CallOption) (*AtomicPutResponse, error) {
 	out := new(AtomicPutResponse)
 	err := c.cc.Invoke(ctx, "/cloud_rpc_pb.Store/AtomicPut", in, out, opts...)
@@ -92,6 +108,83 @@ func (c *storeClient) AtomicDelete(ctx context.Context, in *AtomicDeleteRequest,
 	return out, nil
 }
 
+func (c *storeClient) GetMulti(ctx context.Context, in *GetMultiRequest, opts ...grpc.CallOption) (*GetMultiResponse, error) {
+	out := new(GetMultiResponse)
+	err := c.cc.Invoke(ctx, "/cloud_rpc_pb.Store/GetMulti", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) PutMulti(ctx context.Context, in *PutMultiRequest, opts ...grpc.CallOption) (*PutMultiResponse, error) {
+	out := new(PutMultiResponse)
+	err := c.cc.Invoke(ctx, "/cloud_rpc_pb.Store/PutMulti", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) DeleteMulti(ctx context.Context, in *DeleteMultiRequest, opts ...grpc.CallOption) (*DeleteMultiResponse, error) {
+	out := new(DeleteMultiResponse)
+	err := c.cc.Invoke(ctx, "/cloud_rpc_pb.Store/DeleteMulti", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) Txn(ctx context.Context, in *TxnRequest, opts ...grpc.CallOption) (*TxnResponse, error) {
+	out := new(TxnResponse)
+	err := c.cc.Invoke(ctx, "/cloud_rpc_pb.Store/Txn", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Store_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Store_ServiceDesc.Streams[0], "/cloud_rpc_pb.Store/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &storeWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Store_WatchClient interface {
+	Recv() (*WatchResponse, error)
+	grpc.ClientStream
+}
+
+type storeWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *storeWatchClient) Recv() (*WatchResponse, error) {
+	m := new(WatchResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *storeClient) Capabilities(ctx context.Context, in *CapabilitiesRequest, opts ...grpc.CallOption) (*CapabilitiesResponse, error) {
+	out := new(CapabilitiesResponse)
+	err := c.cc.Invoke(ctx, "/cloud_rpc_pb.Store/Capabilities", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StoreServer is the server API for Store service.
 // All implementations must embed UnimplementedStoreServer
 // for forward compatibility
@@ -100,8 +193,15 @@ type StoreServer interface {
 	Put(context.Context, *PutRequest) (*PutResponse, error)
 	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
 	List(context.Context, *ListRequest) (*ListResponse, error)
+	Scan(context.Context, *ScanRequest) (*ScanResponse, error)
 	AtomicPut(context.Context, *AtomicPutRequest) (*AtomicPutResponse, error)
 	AtomicDelete(context.Context, *AtomicDeleteRequest) (*AtomicDeleteResponse, error)
+	GetMulti(context.Context, *GetMultiRequest) (*GetMultiResponse, error)
+	PutMulti(context.Context, *PutMultiRequest) (*PutMultiResponse, error)
+	DeleteMulti(context.Context, *DeleteMultiRequest) (*DeleteMultiResponse, error)
+	Txn(context.Context, *TxnRequest) (*TxnResponse, error)
+	Watch(*WatchRequest, Store_WatchServer) error
+	Capabilities(context.Context, *CapabilitiesRequest) (*CapabilitiesResponse, error)
 	mustEmbedUnimplementedStoreServer()
 }
 
@@ -121,12 +221,33 @@ func (UnimplementedStoreServer) Delete(context.Context, *DeleteRequest) (*Delete
 func (UnimplementedStoreServer) List(context.Context, *ListRequest) (*ListResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
 }
+func (UnimplementedStoreServer) Scan(context.Context, *ScanRequest) (*ScanResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
 func (UnimplementedStoreServer) AtomicPut(context.Context, *AtomicPutRequest) (*AtomicPutResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AtomicPut not implemented")
 }
 func (UnimplementedStoreServer) AtomicDelete(context.Context, *AtomicDeleteRequest) (*AtomicDeleteResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AtomicDelete not implemented")
 }
+func (UnimplementedStoreServer) GetMulti(context.Context, *GetMultiRequest) (*GetMultiResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMulti not implemented")
+}
+func (UnimplementedStoreServer) PutMulti(context.Context, *PutMultiRequest) (*PutMultiResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutMulti not implemented")
+}
+func (UnimplementedStoreServer) DeleteMulti(context.Context, *DeleteMultiRequest) (*DeleteMultiResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMulti not implemented")
+}
+func (UnimplementedStoreServer) Txn(context.Context, *TxnRequest) (*TxnResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Txn not implemented")
+}
+func (UnimplementedStoreServer) Watch(*WatchRequest, Store_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedStoreServer) Capabilities(context.Context, *CapabilitiesRequest) (*CapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Capabilities not implemented")
+}
 func (UnimplementedStoreServer) mustEmbedUnimplementedStoreServer() {}
 
 // UnsafeStoreServer may be embedded to opt out of forward compatibility for this service.
@@ -212,6 +333,24 @@ func _Store_List_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Store_Scan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).Scan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cloud_rpc_pb.Store/Scan",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).Scan(ctx, req.(*ScanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Store_AtomicPut_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(AtomicPutRequest)
 	if err := dec(in); err != nil {
@@ -248,6 +387,117 @@ func _Store_AtomicDelete_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Store_GetMulti_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMultiRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).GetMulti(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cloud_rpc_pb.Store/GetMulti",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).GetMulti(ctx, req.(*GetMultiRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Store_PutMulti_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutMultiRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).PutMulti(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cloud_rpc_pb.Store/PutMulti",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).PutMulti(ctx, req.(*PutMultiRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Store_DeleteMulti_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMultiRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).DeleteMulti(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cloud_rpc_pb.Store/DeleteMulti",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).DeleteMulti(ctx, req.(*DeleteMultiRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Store_Txn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TxnRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).Txn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cloud_rpc_pb.Store/Txn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).Txn(ctx, req.(*TxnRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Store_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StoreServer).Watch(m, &storeWatchServer{stream})
+}
+
+type Store_WatchServer interface {
+	Send(*WatchResponse) error
+	grpc.ServerStream
+}
+
+type storeWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *storeWatchServer) Send(m *WatchResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Store_Capabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).Capabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cloud_rpc_pb.Store/Capabilities",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).Capabilities(ctx, req.(*CapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Store_ServiceDesc is the grpc.ServiceDesc for Store service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -271,6 +521,10 @@ var Store_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "List",
 			Handler:    _Store_List_Handler,
 		},
+		{
+			MethodName: "Scan",
+			Handler:    _Store_Scan_Handler,
+		},
 		{
 			MethodName: "AtomicPut",
 			Handler:    _Store_AtomicPut_Handler,
@@ -279,7 +533,33 @@ var Store_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AtomicDelete",
 			Handler:    _Store_AtomicDelete_Handler,
 		},
+		{
+			MethodName: "GetMulti",
+			Handler:    _Store_GetMulti_Handler,
+		},
+		{
+			MethodName: "PutMulti",
+			Handler:    _Store_PutMulti_Handler,
+		},
+		{
+			MethodName: "DeleteMulti",
+			Handler:    _Store_DeleteMulti_Handler,
+		},
+		{
+			MethodName: "Txn",
+			Handler:    _Store_Txn_Handler,
+		},
+		{
+			MethodName: "Capabilities",
+			Handler:    _Store_Capabilities_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Store_Watch_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "cloud_rpc.proto",
 }
@@ -5,12 +5,14 @@ import (
 	"errors"
 	"log"
 	"sync"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/akmistry/cloud-util"
 	"github.com/akmistry/cloud-util/rpc/pb"
+	"github.com/akmistry/cloud-util/store_util"
 )
 
 type OpenStoreFunc func(string) (cloud.UnorderedStore, error)
@@ -74,6 +76,30 @@ func makeGrpcError(err error) error {
 		return status.Error(codes.AlreadyExists, err.Error())
 	} else if errors.Is(err, cloud.ErrKeyModified) {
 		return status.Error(codes.Aborted, err.Error())
+	} else if errors.Is(err, cloud.ErrCallNotSupported) {
+		return status.Error(codes.Unimplemented, err.Error())
+	}
+
+	switch cloud.KindOf(err) {
+	case cloud.KindNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case cloud.KindAlreadyExists:
+		return status.Error(codes.AlreadyExists, err.Error())
+	case cloud.KindPreconditionFailed:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case cloud.KindThrottled:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case cloud.KindUnavailable:
+		return status.Error(codes.Unavailable, err.Error())
+	case cloud.KindPermissionDenied:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case cloud.KindCanceled:
+		if errors.Is(err, context.DeadlineExceeded) {
+			return status.Error(codes.DeadlineExceeded, err.Error())
+		}
+		return status.Error(codes.Canceled, err.Error())
+	case cloud.KindCorrupt:
+		return status.Error(codes.DataLoss, err.Error())
 	}
 	return err
 }
@@ -85,12 +111,19 @@ func (s *Server) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse,
 	}
 
 	log.Printf("Fetching key: %s", req.Key)
-	item, err := store.store.Get(req.Key)
+	item, err := cloud.AsContextStore(store.store).GetContext(ctx, req.Key)
 	if err != nil {
 		return nil, makeGrpcError(err)
 	}
 
-	return &pb.GetResponse{Val: item.Value}, nil
+	return &pb.GetResponse{Val: item.Value, Version: item.LastIndex}, nil
+}
+
+func writeOptions(ttlNs int64) *cloud.WriteOptions {
+	if ttlNs <= 0 {
+		return nil
+	}
+	return &cloud.WriteOptions{TTL: time.Duration(ttlNs)}
 }
 
 func (s *Server) Put(ctx context.Context, req *pb.PutRequest) (*pb.PutResponse, error) {
@@ -99,7 +132,7 @@ func (s *Server) Put(ctx context.Context, req *pb.PutRequest) (*pb.PutResponse,
 		return nil, err
 	}
 
-	err = store.store.Put(req.Key, req.Val, nil)
+	err = cloud.AsContextStore(store.store).PutContext(ctx, req.Key, req.Val, writeOptions(req.TtlNs))
 	if err != nil {
 		return nil, makeGrpcError(err)
 	}
@@ -112,7 +145,7 @@ func (s *Server) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteR
 		return nil, err
 	}
 
-	err = store.store.Delete(req.Key)
+	err = cloud.AsContextStore(store.store).DeleteContext(ctx, req.Key)
 	if err != nil {
 		return nil, makeGrpcError(err)
 	}
@@ -131,18 +164,23 @@ func (s *Server) AtomicPut(ctx context.Context, req *pb.AtomicPutRequest) (*pb.A
 	}
 
 	var previous *cloud.KVPair
-	if req.OldVal != nil {
+	if req.OldVal != nil || req.OldVersion != 0 {
 		previous = &cloud.KVPair{
-			Key:   req.Key,
-			Value: req.OldVal,
+			Key:       req.Key,
+			Value:     req.OldVal,
+			LastIndex: req.OldVersion,
 		}
 	}
 
-	_, _, err = atomicStore.AtomicPut(req.Key, req.Val, previous, nil)
+	_, updated, err := cloud.AsAtomicContextStore(atomicStore).AtomicPutContext(ctx, req.Key, req.Val, previous, writeOptions(req.TtlNs))
 	if err != nil {
 		return nil, makeGrpcError(err)
 	}
-	return &pb.AtomicPutResponse{}, nil
+	resp := &pb.AtomicPutResponse{}
+	if updated != nil {
+		resp.Version = updated.LastIndex
+	}
+	return resp, nil
 }
 
 func (s *Server) AtomicDelete(ctx context.Context, req *pb.AtomicDeleteRequest) (*pb.AtomicDeleteResponse, error) {
@@ -156,16 +194,17 @@ func (s *Server) AtomicDelete(ctx context.Context, req *pb.AtomicDeleteRequest)
 		return nil, status.Error(codes.Unimplemented, "Store does not support atomic ops")
 	}
 
-	if req.OldVal == nil {
-		return nil, status.Error(codes.InvalidArgument, "old_val must be non-nil")
+	if req.OldVal == nil && req.OldVersion == 0 {
+		return nil, status.Error(codes.InvalidArgument, "old_val or old_version must be set")
 	}
 
 	previous := &cloud.KVPair{
-		Key:   req.Key,
-		Value: req.OldVal,
+		Key:       req.Key,
+		Value:     req.OldVal,
+		LastIndex: req.OldVersion,
 	}
 
-	_, err = atomicStore.AtomicDelete(req.Key, previous)
+	_, err = cloud.AsAtomicContextStore(atomicStore).AtomicDeleteContext(ctx, req.Key, previous)
 	if err != nil {
 		return nil, makeGrpcError(err)
 	}
@@ -183,7 +222,7 @@ func (s *Server) List(ctx context.Context, req *pb.ListRequest) (*pb.ListRespons
 		return nil, status.Error(codes.Unimplemented, "List not implemented")
 	}
 
-	keys, err := ordered.ListKeys(req.StartKey)
+	keys, err := cloud.ListKeysContext(ctx, ordered, req.StartKey)
 	if err != nil {
 		return nil, makeGrpcError(err)
 	}
@@ -193,3 +232,190 @@ func (s *Server) List(ctx context.Context, req *pb.ListRequest) (*pb.ListRespons
 	}
 	return resp, nil
 }
+
+func (s *Server) GetMulti(ctx context.Context, req *pb.GetMultiRequest) (*pb.GetMultiResponse, error) {
+	store, err := s.getStore(req.DbName)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, err := store_util.GetMultiContext(ctx, store.store, req.Keys)
+	if err != nil {
+		return nil, makeGrpcError(err)
+	}
+
+	resp := &pb.GetMultiResponse{}
+	for i, p := range pairs {
+		if p == nil {
+			continue
+		}
+		resp.Pairs = append(resp.Pairs, &pb.KeyValue{Key: req.Keys[i], Val: p.Value, Version: p.LastIndex})
+	}
+	return resp, nil
+}
+
+func (s *Server) PutMulti(ctx context.Context, req *pb.PutMultiRequest) (*pb.PutMultiResponse, error) {
+	store, err := s.getStore(req.DbName)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]*cloud.KVPair, len(req.Pairs))
+	for i, p := range req.Pairs {
+		pairs[i] = &cloud.KVPair{Key: p.Key, Value: p.Val}
+	}
+	err = store_util.PutMultiContext(ctx, store.store, pairs, writeOptions(req.TtlNs))
+	if err != nil {
+		return nil, makeGrpcError(err)
+	}
+	return &pb.PutMultiResponse{}, nil
+}
+
+func (s *Server) DeleteMulti(ctx context.Context, req *pb.DeleteMultiRequest) (*pb.DeleteMultiResponse, error) {
+	store, err := s.getStore(req.DbName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store_util.DeleteMultiContext(ctx, store.store, req.Keys)
+	if err != nil {
+		return nil, makeGrpcError(err)
+	}
+	return &pb.DeleteMultiResponse{}, nil
+}
+
+func (s *Server) Txn(ctx context.Context, req *pb.TxnRequest) (*pb.TxnResponse, error) {
+	store, err := s.getStore(req.DbName)
+	if err != nil {
+		return nil, err
+	}
+
+	txn := &cloud.Txn{
+		Compares: make([]cloud.TxnCompare, len(req.Compares)),
+		Ops:      make([]cloud.TxnOp, len(req.Ops)),
+	}
+	for i, c := range req.Compares {
+		txn.Compares[i].Key = c.Key
+		if c.Exists {
+			txn.Compares[i].Previous = &cloud.KVPair{
+				Key:       c.Key,
+				Value:     c.OldVal,
+				LastIndex: c.OldVersion,
+			}
+		}
+	}
+	for i, op := range req.Ops {
+		txn.Ops[i] = cloud.TxnOp{
+			Key:     op.Key,
+			Value:   op.Val,
+			Delete:  op.Delete,
+			Options: writeOptions(op.TtlNs),
+		}
+	}
+
+	err = cloud.TxnContext(ctx, store.store, txn)
+	if err != nil {
+		return nil, makeGrpcError(err)
+	}
+	return &pb.TxnResponse{}, nil
+}
+
+func (s *Server) Scan(ctx context.Context, req *pb.ScanRequest) (*pb.ScanResponse, error) {
+	store, err := s.getStore(req.DbName)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered, ok := store.store.(cloud.OrderedStore)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "Scan not implemented")
+	}
+
+	opts := &cloud.ScanOptions{
+		Reverse:  req.Reverse,
+		KeysOnly: req.KeysOnly,
+	}
+	pairs, err := store_util.ScanContext(ctx, ordered, req.StartKey, req.EndKey, int(req.Limit), opts)
+	if err != nil {
+		return nil, makeGrpcError(err)
+	}
+
+	resp := &pb.ScanResponse{
+		Pairs: makeKeyValues(pairs),
+	}
+	return resp, nil
+}
+
+func makeKeyValues(pairs []*cloud.KVPair) []*pb.KeyValue {
+	kvs := make([]*pb.KeyValue, len(pairs))
+	for i, p := range pairs {
+		kvs[i] = &pb.KeyValue{Key: p.Key, Val: p.Value, Version: p.LastIndex}
+	}
+	return kvs
+}
+
+// Sends an empty response to indicate the watch has started, followed by a
+// response for every value received on ch. On error, cancel is called to stop
+// the watch.
+func sendWatchResponses[T any](stream pb.Store_WatchServer, ch <-chan T, cancel func(), toPairs func(T) []*cloud.KVPair) error {
+	err := stream.Send(&pb.WatchResponse{})
+	for v := range ch {
+		if err != nil {
+			// Drain ch until the watch stops.
+			cancel()
+			continue
+		}
+		err = stream.Send(&pb.WatchResponse{Pairs: makeKeyValues(toPairs(v))})
+	}
+	return err
+}
+
+func (s *Server) Watch(req *pb.WatchRequest, stream pb.Store_WatchServer) error {
+	store, err := s.getStore(req.DbName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	if req.Tree {
+		ch, err := cloud.WatchTree(store.store, req.Key, ctx.Done())
+		if err != nil {
+			return makeGrpcError(err)
+		}
+		return sendWatchResponses(stream, ch, cancel, func(pairs []*cloud.KVPair) []*cloud.KVPair {
+			return pairs
+		})
+	}
+
+	ch, err := cloud.Watch(store.store, req.Key, ctx.Done())
+	if err != nil {
+		return makeGrpcError(err)
+	}
+	return sendWatchResponses(stream, ch, cancel, func(p *cloud.KVPair) []*cloud.KVPair {
+		return []*cloud.KVPair{p}
+	})
+}
+
+func (s *Server) Capabilities(ctx context.Context, req *pb.CapabilitiesRequest) (*pb.CapabilitiesResponse, error) {
+	store, err := s.getStore(req.DbName)
+	if err != nil {
+		return nil, err
+	}
+
+	caps := cloud.GetCapabilities(store.store)
+	resp := &pb.CapabilitiesResponse{
+		Atomic:       caps.Atomic,
+		Ordered:      caps.Ordered,
+		Ttl:          caps.TTL,
+		Batch:        caps.Batch,
+		Watch:        caps.Watch,
+		Txn:          caps.Txn,
+		MaxValueSize: caps.MaxValueSize,
+		MaxKeySize:   int32(caps.MaxKeySize),
+		// Enum values match cloud.Consistency.
+		Consistency: pb.Consistency(caps.Consistency),
+	}
+	return resp, nil
+}
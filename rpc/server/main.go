@@ -9,10 +9,10 @@ import (
 	"path/filepath"
 	"syscall"
 
-	"github.com/akmistry/go-util/badgerkv"
 	"google.golang.org/grpc"
 
 	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
 	"github.com/akmistry/cloud-util/rpc"
 	"github.com/akmistry/cloud-util/rpc/pb"
 )
@@ -43,7 +43,7 @@ func main() {
 
 	server := rpc.NewServer(func(name string) (cloud.UnorderedStore, error) {
 		name = filepath.Join(*rootDir, "badger-"+name)
-		return badgerkv.NewStore(name)
+		return local.NewBadgerStore(name)
 	})
 	defer server.Shutdown()
 	pb.RegisterStoreServer(grpcServer, server)
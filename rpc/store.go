@@ -3,7 +3,9 @@ package rpc
 import (
 	"context"
 	"fmt"
+	"log"
 	"net/url"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc"
@@ -16,14 +18,40 @@ import (
 
 const (
 	scheme = "rpc"
+
+	capabilitiesTimeout = 10 * time.Second
+	// Capabilities aren't fetched again for this long after a failure.
+	capabilitiesRetryInterval = time.Minute
 )
 
+// Capabilities of servers which predate the Capabilities RPC. Batch
+// operations are always sent as a single request.
+var legacyCapabilities = cloud.Capabilities{
+	Atomic:  true,
+	Ordered: true,
+	Batch:   true,
+}
+
 type Store struct {
 	name   string
 	client pb.StoreClient
+
+	// Fetched from the server on first use.
+	caps       *cloud.Capabilities
+	capsFailed time.Time
+	capsLock   sync.Mutex
 }
 
 var _ = (cloud.UnorderedStore)((*Store)(nil))
+var _ = (cloud.AtomicContextStore)((*Store)(nil))
+var _ = (cloud.ContextKeysLister)((*Store)(nil))
+var _ = (cloud.BatchStore)((*Store)(nil))
+var _ = (cloud.ContextBatchStore)((*Store)(nil))
+var _ = (cloud.Scanner)((*Store)(nil))
+var _ = (cloud.ContextScanner)((*Store)(nil))
+var _ = (cloud.Watcher)((*Store)(nil))
+var _ = (cloud.TxnStore)((*Store)(nil))
+var _ = (cloud.CapabilitiesProvider)((*Store)(nil))
 
 func init() {
 	cloud.RegisterStoreScheme(scheme, openStore)
@@ -69,11 +97,77 @@ func translateError(err error) error {
 		return cloud.ErrKeyModified
 	case codes.Unimplemented:
 		return cloud.ErrCallNotSupported
+	case codes.FailedPrecondition:
+		return cloud.NewError("", "", cloud.KindPreconditionFailed, err)
+	case codes.ResourceExhausted:
+		return cloud.NewError("", "", cloud.KindThrottled, err)
+	case codes.Unavailable:
+		return cloud.NewError("", "", cloud.KindUnavailable, err)
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return cloud.NewError("", "", cloud.KindPermissionDenied, err)
+	case codes.Canceled:
+		return cloud.NewError("", "", cloud.KindCanceled, context.Canceled)
+	case codes.DeadlineExceeded:
+		return cloud.NewError("", "", cloud.KindCanceled, context.DeadlineExceeded)
+	case codes.DataLoss:
+		return cloud.NewError("", "", cloud.KindCorrupt, err)
 	default:
 		return err
 	}
 }
 
+// Capabilities returns the capabilities of the store on the server. Servers
+// which predate the Capabilities RPC are assumed to support the operations
+// of the original protocol. If the capabilities can't be fetched, the same is
+// assumed, and fetching is retried after capabilitiesRetryInterval.
+func (s *Store) Capabilities() cloud.Capabilities {
+	return s.CapabilitiesContext(context.Background())
+}
+
+func (s *Store) CapabilitiesContext(ctx context.Context) cloud.Capabilities {
+	s.capsLock.Lock()
+	if s.caps != nil {
+		caps := *s.caps
+		s.capsLock.Unlock()
+		return caps
+	} else if time.Since(s.capsFailed) < capabilitiesRetryInterval {
+		s.capsLock.Unlock()
+		return legacyCapabilities
+	}
+	s.capsLock.Unlock()
+
+	req := &pb.CapabilitiesRequest{
+		DbName: s.name,
+	}
+	ctx, cancel := context.WithTimeout(ctx, capabilitiesTimeout)
+	defer cancel()
+	resp, err := s.client.Capabilities(ctx, req)
+
+	s.capsLock.Lock()
+	defer s.capsLock.Unlock()
+	if status.Code(err) == codes.Unimplemented {
+		caps := legacyCapabilities
+		s.caps = &caps
+		return caps
+	} else if err != nil {
+		log.Printf("cloud/rpc: error fetching capabilities: %v", err)
+		s.capsFailed = time.Now()
+		return legacyCapabilities
+	}
+	s.caps = &cloud.Capabilities{
+		Atomic:       resp.Atomic,
+		Ordered:      resp.Ordered,
+		TTL:          resp.Ttl,
+		Batch:        legacyCapabilities.Batch,
+		Watch:        resp.Watch,
+		Txn:          resp.Txn,
+		MaxValueSize: resp.MaxValueSize,
+		MaxKeySize:   int(resp.MaxKeySize),
+		Consistency:  cloud.Consistency(resp.Consistency),
+	}
+	return *s.caps
+}
+
 func (s *Store) GetContext(ctx context.Context, key string) (*cloud.KVPair, error) {
 	req := &pb.GetRequest{
 		DbName: s.name,
@@ -85,16 +179,16 @@ func (s *Store) GetContext(ctx context.Context, key string) (*cloud.KVPair, erro
 	} else if resp.Val == nil {
 		return nil, cloud.ErrKeyNotFound
 	}
-	return &cloud.KVPair{Key: key, Value: resp.Val}, nil
+	return &cloud.KVPair{Key: key, Value: resp.Val, LastIndex: resp.Version}, nil
 }
 
 func (s *Store) Get(key string) (*cloud.KVPair, error) {
 	return s.GetContext(context.Background(), key)
 }
 
-func (s *Store) Exists(key string) (bool, error) {
+func (s *Store) ExistsContext(ctx context.Context, key string) (bool, error) {
 	// TODO: Turn this into a RPC method to avoid transferring the value.
-	_, err := s.Get(key)
+	_, err := s.GetContext(ctx, key)
 	if err == cloud.ErrKeyNotFound {
 		return false, nil
 	} else if err != nil {
@@ -103,72 +197,319 @@ func (s *Store) Exists(key string) (bool, error) {
 	return true, nil
 }
 
-func (s *Store) Put(key string, value []byte, options *cloud.WriteOptions) error {
+func (s *Store) Exists(key string) (bool, error) {
+	return s.ExistsContext(context.Background(), key)
+}
+
+func ttlNs(options *cloud.WriteOptions) int64 {
+	if options == nil || options.TTL <= 0 {
+		return 0
+	}
+	return int64(options.TTL)
+}
+
+func (s *Store) PutContext(ctx context.Context, key string, value []byte, options *cloud.WriteOptions) error {
 	req := &pb.PutRequest{
 		DbName: s.name,
 		Key:    key,
 		Val:    value,
+		TtlNs:  ttlNs(options),
 	}
-	_, err := s.client.Put(context.TODO(), req)
+	_, err := s.client.Put(ctx, req)
 	return translateError(err)
 }
 
-func (s *Store) Delete(key string) error {
+func (s *Store) Put(key string, value []byte, options *cloud.WriteOptions) error {
+	return s.PutContext(context.Background(), key, value, options)
+}
+
+func (s *Store) DeleteContext(ctx context.Context, key string) error {
 	req := &pb.DeleteRequest{
 		DbName: s.name,
 		Key:    key,
 	}
-	_, err := s.client.Delete(context.TODO(), req)
+	_, err := s.client.Delete(ctx, req)
 	return translateError(err)
 }
 
-// TODO: Implement.
-func (s *Store) AtomicPut(key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+func (s *Store) Delete(key string) error {
+	return s.DeleteContext(context.Background(), key)
+}
+
+func (s *Store) AtomicPutContext(ctx context.Context, key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
 	req := &pb.AtomicPutRequest{
 		DbName: s.name,
 		Key:    key,
 		Val:    value,
+		TtlNs:  ttlNs(options),
 	}
 	if previous != nil {
 		req.OldVal = previous.Value
+		req.OldVersion = previous.LastIndex
 	}
-	_, err := s.client.AtomicPut(context.TODO(), req)
+	resp, err := s.client.AtomicPut(ctx, req)
 	if err != nil {
 		return false, nil, translateError(err)
 	}
 	updated := &cloud.KVPair{
-		Key:   key,
-		Value: value,
+		Key:       key,
+		Value:     value,
+		LastIndex: resp.Version,
 	}
 	return true, updated, nil
 }
 
-func (s *Store) AtomicDelete(key string, previous *cloud.KVPair) (bool, error) {
+func (s *Store) AtomicPut(key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+	return s.AtomicPutContext(context.Background(), key, value, previous, options)
+}
+
+func (s *Store) AtomicDeleteContext(ctx context.Context, key string, previous *cloud.KVPair) (bool, error) {
 	if previous == nil {
 		// Not specifying a previous is a programming error.
 		panic("previous == nil")
 	}
 
 	req := &pb.AtomicDeleteRequest{
-		DbName: s.name,
-		Key:    key,
-		OldVal: previous.Value,
+		DbName:     s.name,
+		Key:        key,
+		OldVal:     previous.Value,
+		OldVersion: previous.LastIndex,
 	}
-	_, err := s.client.AtomicDelete(context.TODO(), req)
+	_, err := s.client.AtomicDelete(ctx, req)
 	if err != nil {
 		return false, translateError(err)
 	}
 	return true, nil
 }
 
-func (s *Store) ListKeys(start string) (keys []string, err error) {
+func (s *Store) AtomicDelete(key string, previous *cloud.KVPair) (bool, error) {
+	return s.AtomicDeleteContext(context.Background(), key, previous)
+}
+
+func (s *Store) ListKeysContext(ctx context.Context, start string) (keys []string, err error) {
 	req := &pb.ListRequest{
 		DbName:   s.name,
 		StartKey: start,
 	}
-	resp, err := s.client.List(context.TODO(), req)
+	resp, err := s.client.List(ctx, req)
 	if err != nil {
 		return nil, translateError(err)
 	}
 	return resp.Keys, nil
 }
+
+func (s *Store) ListKeys(start string) (keys []string, err error) {
+	return s.ListKeysContext(context.Background(), start)
+}
+
+func (s *Store) GetMulti(keys []string) ([]*cloud.KVPair, error) {
+	return s.GetMultiContext(context.Background(), keys)
+}
+
+func (s *Store) GetMultiContext(ctx context.Context, keys []string) ([]*cloud.KVPair, error) {
+	req := &pb.GetMultiRequest{
+		DbName: s.name,
+		Keys:   keys,
+	}
+	resp, err := s.client.GetMulti(ctx, req)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	found := make(map[string]*pb.KeyValue, len(resp.Pairs))
+	for _, p := range resp.Pairs {
+		found[p.Key] = p
+	}
+	pairs := make([]*cloud.KVPair, len(keys))
+	for i, k := range keys {
+		if p, ok := found[k]; ok {
+			pairs[i] = &cloud.KVPair{Key: k, Value: p.Val, LastIndex: p.Version}
+		}
+	}
+	return pairs, nil
+}
+
+func (s *Store) PutMulti(pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	return s.PutMultiContext(context.Background(), pairs, options)
+}
+
+func (s *Store) PutMultiContext(ctx context.Context, pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	req := &pb.PutMultiRequest{
+		DbName: s.name,
+		Pairs:  make([]*pb.KeyValue, len(pairs)),
+		TtlNs:  ttlNs(options),
+	}
+	for i, p := range pairs {
+		req.Pairs[i] = &pb.KeyValue{Key: p.Key, Val: p.Value}
+	}
+	_, err := s.client.PutMulti(ctx, req)
+	return translateError(err)
+}
+
+func (s *Store) DeleteMulti(keys []string) error {
+	return s.DeleteMultiContext(context.Background(), keys)
+}
+
+func (s *Store) DeleteMultiContext(ctx context.Context, keys []string) error {
+	req := &pb.DeleteMultiRequest{
+		DbName: s.name,
+		Keys:   keys,
+	}
+	_, err := s.client.DeleteMulti(ctx, req)
+	return translateError(err)
+}
+
+func (s *Store) TxnContext(ctx context.Context, txn *cloud.Txn) error {
+	if !s.CapabilitiesContext(ctx).Txn {
+		return cloud.ErrCallNotSupported
+	}
+	req := &pb.TxnRequest{
+		DbName:   s.name,
+		Compares: make([]*pb.TxnCompare, len(txn.Compares)),
+		Ops:      make([]*pb.TxnOp, len(txn.Ops)),
+	}
+	for i, c := range txn.Compares {
+		req.Compares[i] = &pb.TxnCompare{Key: c.Key}
+		if c.Previous != nil {
+			req.Compares[i].Exists = true
+			req.Compares[i].OldVal = c.Previous.Value
+			req.Compares[i].OldVersion = c.Previous.LastIndex
+		}
+	}
+	for i, op := range txn.Ops {
+		req.Ops[i] = &pb.TxnOp{
+			Key:    op.Key,
+			Val:    op.Value,
+			Delete: op.Delete,
+			TtlNs:  ttlNs(op.Options),
+		}
+	}
+	_, err := s.client.Txn(ctx, req)
+	return translateError(err)
+}
+
+func (s *Store) Txn(txn *cloud.Txn) error {
+	return s.TxnContext(context.Background(), txn)
+}
+
+func (s *Store) Scan(start, end string, limit int, options *cloud.ScanOptions) ([]*cloud.KVPair, error) {
+	return s.ScanContext(context.Background(), start, end, limit, options)
+}
+
+func (s *Store) ScanContext(ctx context.Context, start, end string, limit int, options *cloud.ScanOptions) ([]*cloud.KVPair, error) {
+	req := &pb.ScanRequest{
+		DbName:   s.name,
+		StartKey: start,
+		EndKey:   end,
+		Limit:    int32(limit),
+	}
+	if options != nil {
+		req.Reverse = options.Reverse
+		req.KeysOnly = options.KeysOnly
+	}
+	resp, err := s.client.Scan(ctx, req)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	pairs := make([]*cloud.KVPair, len(resp.Pairs))
+	for i, p := range resp.Pairs {
+		pairs[i] = &cloud.KVPair{Key: p.Key, Value: p.Val, LastIndex: p.Version}
+	}
+	return pairs, nil
+}
+
+// Starts a watch, and waits for the server to acknowledge it. Responses are
+// passed to send until the stream fails, stopCh is closed, or send returns
+// false, after which done is called.
+func (s *Store) watch(req *pb.WatchRequest, stopCh <-chan struct{}, send func(*pb.WatchResponse) bool, done func()) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := s.client.Watch(ctx, req)
+	if err != nil {
+		cancel()
+		return translateError(err)
+	}
+	_, err = stream.Recv()
+	if err != nil {
+		cancel()
+		return translateError(err)
+	}
+
+	go func() {
+		select {
+		case <-stopCh:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+	go func() {
+		defer done()
+		defer cancel()
+		for {
+			resp, err := stream.Recv()
+			if err != nil || !send(resp) {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func makePairs(kvs []*pb.KeyValue) []*cloud.KVPair {
+	pairs := make([]*cloud.KVPair, len(kvs))
+	for i, kv := range kvs {
+		pairs[i] = &cloud.KVPair{Key: kv.Key, Value: kv.Val, LastIndex: kv.Version}
+	}
+	return pairs
+}
+
+func (s *Store) Watch(key string, stopCh <-chan struct{}) (<-chan *cloud.KVPair, error) {
+	if !s.Capabilities().Watch {
+		return nil, cloud.ErrCallNotSupported
+	}
+	req := &pb.WatchRequest{
+		DbName: s.name,
+		Key:    key,
+	}
+	ch := make(chan *cloud.KVPair, 1)
+	send := func(resp *pb.WatchResponse) bool {
+		for _, p := range makePairs(resp.Pairs) {
+			select {
+			case ch <- p:
+			case <-stopCh:
+				return false
+			}
+		}
+		return true
+	}
+	err := s.watch(req, stopCh, send, func() { close(ch) })
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+func (s *Store) WatchTree(directory string, stopCh <-chan struct{}) (<-chan []*cloud.KVPair, error) {
+	if !s.Capabilities().Watch {
+		return nil, cloud.ErrCallNotSupported
+	}
+	req := &pb.WatchRequest{
+		DbName: s.name,
+		Key:    directory,
+		Tree:   true,
+	}
+	ch := make(chan []*cloud.KVPair, 1)
+	send := func(resp *pb.WatchResponse) bool {
+		select {
+		case ch <- makePairs(resp.Pairs):
+			return true
+		case <-stopCh:
+			return false
+		}
+	}
+	err := s.watch(req, stopCh, send, func() { close(ch) })
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
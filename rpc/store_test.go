@@ -0,0 +1,243 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
+	"github.com/akmistry/cloud-util/rpc/pb"
+	"github.com/akmistry/cloud-util/store_util"
+	"github.com/akmistry/cloud-util/test_util"
+)
+
+// Creates a client Store connected to an in-process server. Each DB name
+// is backed by a new InMemoryStore.
+func newTestStore(t *testing.T, name string) *Store {
+	lis := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	server := NewServer(func(string) (cloud.UnorderedStore, error) {
+		return local.NewInMemoryStore(), nil
+	})
+	pb.RegisterStoreServer(grpcServer, server)
+	go grpcServer.Serve(lis)
+	t.Cleanup(func() {
+		grpcServer.Stop()
+		server.Shutdown()
+	})
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.Dial error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &Store{name: name, client: pb.NewStoreClient(conn)}
+}
+
+func TestStore(t *testing.T) {
+	test_util.TestUnorderedStore(t, newTestStore(t, "test"))
+	test_util.TestListKeys(t, newTestStore(t, "test"))
+	test_util.TestScan(t, newTestStore(t, "test"))
+	test_util.TestAtomicStore(t, newTestStore(t, "test"))
+	test_util.TestTxn(t, newTestStore(t, "test"))
+	test_util.TestTTL(t, newTestStore(t, "test"))
+	test_util.TestWatch(t, newTestStore(t, "test"))
+	test_util.TestLock(t, newTestStore(t, "test"))
+}
+
+func TestStore_Batch(t *testing.T) {
+	s := newTestStore(t, "test")
+
+	pairs := []*cloud.KVPair{
+		{Key: "a", Value: []byte("1")},
+		{Key: "b", Value: []byte("2")},
+	}
+	err := s.PutMulti(pairs, nil)
+	if err != nil {
+		t.Fatalf("PutMulti error = %v", err)
+	}
+
+	got, err := s.GetMulti([]string{"b", "missing", "a"})
+	if err != nil {
+		t.Fatalf("GetMulti error = %v", err)
+	}
+	if len(got) != 3 || got[0] == nil || string(got[0].Value) != "2" ||
+		got[1] != nil || got[2] == nil || string(got[2].Value) != "1" {
+		t.Errorf("GetMulti returned unexpected pairs %v", got)
+	}
+
+	err = s.DeleteMulti([]string{"a", "b"})
+	if err != nil {
+		t.Fatalf("DeleteMulti error = %v", err)
+	}
+	got, err = s.GetMulti([]string{"a", "b"})
+	if err != nil {
+		t.Fatalf("GetMulti error = %v", err)
+	}
+	if got[0] != nil || got[1] != nil {
+		t.Errorf("GetMulti returned deleted pairs %v", got)
+	}
+}
+
+func TestStore_CanceledContext(t *testing.T) {
+	s := newTestStore(t, "test")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	pairs := []*cloud.KVPair{{Key: "a", Value: []byte("1")}}
+	if err := s.PutMultiContext(ctx, pairs, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("PutMultiContext error = %v", err)
+	}
+	if _, err := s.GetMultiContext(ctx, []string{"a"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetMultiContext error = %v", err)
+	}
+	if err := s.DeleteMultiContext(ctx, []string{"a"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("DeleteMultiContext error = %v", err)
+	}
+	if _, err := s.ScanContext(ctx, "", "", 0, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("ScanContext error = %v", err)
+	}
+	if ok, err := s.Exists("a"); err != nil || ok {
+		t.Errorf("Exists(a) = %v, %v after canceled PutMultiContext", ok, err)
+	}
+}
+
+func TestStore_Capabilities(t *testing.T) {
+	caps := newTestStore(t, "test").Capabilities()
+	expected := local.NewInMemoryStore().Capabilities()
+	if caps != expected {
+		t.Errorf("Capabilities() %+v != expected %+v", caps, expected)
+	}
+}
+
+// Fails Capabilities requests, and counts them.
+type failingCapsClient struct {
+	pb.StoreClient
+	calls int
+}
+
+func (c *failingCapsClient) Capabilities(ctx context.Context, req *pb.CapabilitiesRequest, opts ...grpc.CallOption) (*pb.CapabilitiesResponse, error) {
+	c.calls++
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, status.Error(codes.Internal, "no deadline")
+	}
+	return nil, status.Error(codes.Unavailable, "unavailable")
+}
+
+func TestStore_CapabilitiesError(t *testing.T) {
+	client := &failingCapsClient{}
+	s := &Store{name: "test", client: client}
+	// Transient failures don't prevent use as an atomic ordered store.
+	expected := cloud.Capabilities{Atomic: true, Ordered: true, Batch: true}
+	for i := 0; i < 2; i++ {
+		if caps := s.Capabilities(); caps != expected {
+			t.Errorf("Capabilities() %+v != expected %+v", caps, expected)
+		}
+	}
+	if client.calls != 1 {
+		t.Errorf("Capabilities RPCs %d != expected 1", client.calls)
+	}
+}
+
+// Simulates a server which predates the Capabilities RPC.
+type legacyCapsClient struct {
+	pb.StoreClient
+	calls int
+}
+
+func (c *legacyCapsClient) Capabilities(ctx context.Context, req *pb.CapabilitiesRequest, opts ...grpc.CallOption) (*pb.CapabilitiesResponse, error) {
+	c.calls++
+	return nil, status.Error(codes.Unimplemented, "unimplemented")
+}
+
+func TestStore_LegacyServer(t *testing.T) {
+	client := &legacyCapsClient{StoreClient: newTestStore(t, "test").client}
+	s := &Store{name: "test", client: client}
+	expected := cloud.Capabilities{Atomic: true, Ordered: true, Batch: true}
+	if caps := s.Capabilities(); caps != expected {
+		t.Errorf("Capabilities() %+v != expected %+v", caps, expected)
+	}
+
+	// Txn and Watch fall back to AtomicPut and polling.
+	if err := s.Txn(&cloud.Txn{}); err != cloud.ErrCallNotSupported {
+		t.Errorf("Txn() error %v != expected cloud.ErrCallNotSupported", err)
+	}
+	err := store_util.ReadModifyWrite(context.Background(), s, []string{"key"},
+		func([]*cloud.KVPair) ([]cloud.TxnOp, error) {
+			return []cloud.TxnOp{{Key: "key", Value: []byte("value")}}, nil
+		})
+	if err != nil {
+		t.Errorf("ReadModifyWrite error = %v", err)
+	}
+	if _, err := s.Watch("key", nil); err != cloud.ErrCallNotSupported {
+		t.Errorf("Watch() error %v != expected cloud.ErrCallNotSupported", err)
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	ch, err := cloud.Watch(s, "key", stopCh)
+	if err != nil {
+		t.Fatalf("cloud.Watch error = %v", err)
+	}
+	select {
+	case p := <-ch:
+		if string(p.Value) != "value" {
+			t.Errorf("Watch value %s != expected value", p.Value)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Timed out waiting for watch")
+	}
+
+	if client.calls != 1 {
+		t.Errorf("Capabilities RPCs %d != expected 1", client.calls)
+	}
+}
+
+func TestErrorRoundTrip(t *testing.T) {
+	kinds := []cloud.ErrorKind{
+		cloud.KindNotFound,
+		cloud.KindAlreadyExists,
+		cloud.KindPreconditionFailed,
+		cloud.KindThrottled,
+		cloud.KindUnavailable,
+		cloud.KindPermissionDenied,
+		cloud.KindCanceled,
+		cloud.KindCorrupt,
+	}
+	for _, kind := range kinds {
+		err := translateError(makeGrpcError(cloud.NewError("get", "a", kind, nil)))
+		if k := cloud.KindOf(err); k != kind {
+			t.Errorf("Round-tripped kind %v != expected %v", k, kind)
+		}
+	}
+
+	sentinels := []error{
+		cloud.ErrKeyNotFound,
+		cloud.ErrKeyExists,
+		cloud.ErrKeyModified,
+		cloud.ErrCallNotSupported,
+	}
+	for _, e := range sentinels {
+		if err := translateError(makeGrpcError(e)); err != e {
+			t.Errorf("Round-tripped error %v != expected %v", err, e)
+		}
+	}
+
+	err := translateError(makeGrpcError(context.DeadlineExceeded))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Round-tripped error %v is not context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,30 @@
+package cloud
+
+import "context"
+
+type ScanOptions struct {
+	// Return pairs in descending key order, beginning with the largest key
+	// less than end.
+	Reverse bool
+	// Only populate the Key field of returned pairs.
+	KeysOnly bool
+}
+
+// Optionally implemented by ordered stores to efficiently return key/value
+// pairs in a range.
+type Scanner interface {
+	// Returns up to limit pairs with keys in the range [start, end), in key
+	// order. An empty end does not bound the range, and a limit <= 0 returns
+	// all pairs in the range. options may be nil.
+	Scan(start, end string, limit int, options *ScanOptions) ([]*KVPair, error)
+}
+
+// Context-aware variant of Scanner.
+type ContextScanner interface {
+	ScanContext(ctx context.Context, start, end string, limit int, options *ScanOptions) ([]*KVPair, error)
+}
+
+type ScanStore interface {
+	OrderedStore
+	Scanner
+}
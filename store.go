@@ -1,7 +1,9 @@
 package cloud
 
 import (
+	"bytes"
 	"io"
+	"time"
 
 	"github.com/docker/libkv/store"
 )
@@ -32,6 +34,10 @@ type UnorderedStore interface {
 	Delete(key string) error
 }
 
+// Pairs returned by Get and AtomicPut have LastIndex set to a version which
+// increases on every write to the key. AtomicPut and AtomicDelete compare the
+// stored version against previous.LastIndex, or against previous.Value if
+// LastIndex is 0.
 type AtomicUnorderedStore interface {
 	UnorderedStore
 
@@ -53,6 +59,26 @@ type AtomicOrderedStore interface {
 	KeysLister
 }
 
+// MatchesPrevious reports whether the stored value and version of a key match
+// the previous pair passed to AtomicPut or AtomicDelete. If previous.LastIndex
+// is set, only the versions are compared. Otherwise, the values are compared,
+// for callers which construct previous themselves.
+func MatchesPrevious(previous *KVPair, value []byte, version uint64) bool {
+	if previous.LastIndex != 0 {
+		return previous.LastIndex == version
+	}
+	return bytes.Equal(previous.Value, value)
+}
+
+// Expiry returns the time at which a value written with options expires, or
+// the zero time if options does not specify a TTL.
+func Expiry(options *WriteOptions) time.Time {
+	if options == nil || options.TTL <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(options.TTL)
+}
+
 func DoStoreClose(s UnorderedStore) error {
 	type libkvCloser interface {
 		Close()
@@ -0,0 +1,113 @@
+package store_util
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/akmistry/cloud-util"
+)
+
+const (
+	// Maximum number of concurrent requests used to emulate a batch operation
+	// on stores without native batching.
+	MaxBatchWorkers = 16
+)
+
+// GetMulti uses s.GetMulti if s implements cloud.BatchStore. Otherwise, keys
+// are fetched individually using a bounded number of concurrent requests.
+func GetMulti(s cloud.UnorderedStore, keys []string) ([]*cloud.KVPair, error) {
+	return GetMultiContext(context.Background(), s, keys)
+}
+
+// GetMultiContext is the context-aware variant of GetMulti. If s implements
+// cloud.BatchStore but not cloud.ContextBatchStore, the context is only
+// checked before the batch is started.
+func GetMultiContext(ctx context.Context, s cloud.UnorderedStore, keys []string) ([]*cloud.KVPair, error) {
+	if bs, ok := s.(cloud.ContextBatchStore); ok {
+		return bs.GetMultiContext(ctx, keys)
+	} else if bs, ok := s.(cloud.BatchStore); ok {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return bs.GetMulti(keys)
+	}
+
+	cs := cloud.AsContextStore(s)
+	pairs := make([]*cloud.KVPair, len(keys))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(MaxBatchWorkers)
+	for i, k := range keys {
+		g.Go(func() error {
+			p, err := cs.GetContext(gctx, k)
+			if err == cloud.ErrKeyNotFound {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			pairs[i] = p
+			return nil
+		})
+	}
+	err := g.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// PutMulti uses s.PutMulti if s implements cloud.BatchStore. Otherwise, pairs
+// are written individually using a bounded number of concurrent requests.
+func PutMulti(s cloud.UnorderedStore, pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	return PutMultiContext(context.Background(), s, pairs, options)
+}
+
+// PutMultiContext is the context-aware variant of PutMulti.
+func PutMultiContext(ctx context.Context, s cloud.UnorderedStore, pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	if bs, ok := s.(cloud.ContextBatchStore); ok {
+		return bs.PutMultiContext(ctx, pairs, options)
+	} else if bs, ok := s.(cloud.BatchStore); ok {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return bs.PutMulti(pairs, options)
+	}
+
+	cs := cloud.AsContextStore(s)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(MaxBatchWorkers)
+	for _, p := range pairs {
+		g.Go(func() error {
+			return cs.PutContext(gctx, p.Key, p.Value, options)
+		})
+	}
+	return g.Wait()
+}
+
+// DeleteMulti uses s.DeleteMulti if s implements cloud.BatchStore. Otherwise,
+// keys are deleted individually using a bounded number of concurrent requests.
+func DeleteMulti(s cloud.UnorderedStore, keys []string) error {
+	return DeleteMultiContext(context.Background(), s, keys)
+}
+
+// DeleteMultiContext is the context-aware variant of DeleteMulti.
+func DeleteMultiContext(ctx context.Context, s cloud.UnorderedStore, keys []string) error {
+	if bs, ok := s.(cloud.ContextBatchStore); ok {
+		return bs.DeleteMultiContext(ctx, keys)
+	} else if bs, ok := s.(cloud.BatchStore); ok {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return bs.DeleteMulti(keys)
+	}
+
+	cs := cloud.AsContextStore(s)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(MaxBatchWorkers)
+	for _, k := range keys {
+		g.Go(func() error {
+			return cs.DeleteContext(gctx, k)
+		})
+	}
+	return g.Wait()
+}
@@ -0,0 +1,113 @@
+package store_util
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
+	"github.com/akmistry/cloud-util/test_util"
+)
+
+func batchHelper(t *testing.T, s cloud.UnorderedStore) {
+	const NumTestItems = 100
+
+	sortedKeys, kv := test_util.PopulateTestItems(t, s, NumTestItems)
+
+	keys := append([]string{"missing-key"}, sortedKeys...)
+	pairs, err := GetMulti(s, keys)
+	if err != nil {
+		t.Fatalf("GetMulti err = %v", err)
+	} else if len(pairs) != len(keys) {
+		t.Fatalf("len(pairs) %d != expected %d", len(pairs), len(keys))
+	}
+	if pairs[0] != nil {
+		t.Errorf("pairs[0] = %v, expected nil", pairs[0])
+	}
+	for i, p := range pairs[1:] {
+		if p == nil {
+			t.Errorf("Key %s not found", keys[i+1])
+		} else if p.Key != keys[i+1] {
+			t.Errorf("Pair key %s != expected %s", p.Key, keys[i+1])
+		} else if !bytes.Equal(p.Value, kv[keys[i+1]]) {
+			t.Errorf("Key %s value %v != expected %v", keys[i+1], p.Value, kv[keys[i+1]])
+		}
+	}
+
+	newPairs := make([]*cloud.KVPair, len(sortedKeys))
+	for i, k := range sortedKeys {
+		newPairs[i] = &cloud.KVPair{Key: k, Value: []byte("new-" + k)}
+	}
+	err = PutMulti(s, newPairs, nil)
+	if err != nil {
+		t.Fatalf("PutMulti err = %v", err)
+	}
+	pairs, err = GetMulti(s, sortedKeys)
+	if err != nil {
+		t.Fatalf("GetMulti err = %v", err)
+	}
+	for i, p := range pairs {
+		if p == nil || !bytes.Equal(p.Value, newPairs[i].Value) {
+			t.Errorf("Key %s pair %v != expected value %v", sortedKeys[i], p, newPairs[i].Value)
+		}
+	}
+
+	err = DeleteMulti(s, sortedKeys[:NumTestItems/2])
+	if err != nil {
+		t.Fatalf("DeleteMulti err = %v", err)
+	}
+	pairs, err = GetMulti(s, sortedKeys)
+	if err != nil {
+		t.Fatalf("GetMulti err = %v", err)
+	}
+	for i, p := range pairs {
+		if i < NumTestItems/2 && p != nil {
+			t.Errorf("Deleted key %s still exists", sortedKeys[i])
+		} else if i >= NumTestItems/2 && p == nil {
+			t.Errorf("Key %s not found", sortedKeys[i])
+		}
+	}
+}
+
+func TestBatch_Native(t *testing.T) {
+	batchHelper(t, local.NewInMemoryStore())
+}
+
+func TestBatch_Fallback(t *testing.T) {
+	// The inner store does not implement cloud.BatchStore.
+	batchHelper(t, NewDelayStore(unorderedStore{local.NewInMemoryStore()}, 0))
+}
+
+func TestBatch_PrefixStore(t *testing.T) {
+	batchHelper(t, NewPrefixStore(local.NewInMemoryStore(), "prefix-"))
+}
+
+func TestBatch_CanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stores := map[string]cloud.UnorderedStore{
+		"native":   local.NewInMemoryStore(),
+		"fallback": NewDelayStore(unorderedStore{local.NewInMemoryStore()}, 0),
+		"prefix":   NewPrefixStore(local.NewInMemoryStore(), "prefix-"),
+	}
+	for name, s := range stores {
+		if err := s.Put("key", []byte("value"), nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := GetMultiContext(ctx, s, []string{"key"}); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: GetMultiContext error = %v, expected context.Canceled", name, err)
+		}
+		pairs := []*cloud.KVPair{{Key: "key", Value: []byte("new")}}
+		if err := PutMultiContext(ctx, s, pairs, nil); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: PutMultiContext error = %v, expected context.Canceled", name, err)
+		}
+		if err := DeleteMultiContext(ctx, s, []string{"key"}); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: DeleteMultiContext error = %v, expected context.Canceled", name, err)
+		}
+		if p, err := s.Get("key"); err != nil || string(p.Value) != "value" {
+			t.Errorf("%s: Get(key) = %v, %v after canceled writes", name, p, err)
+		}
+	}
+}
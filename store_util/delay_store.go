@@ -1,6 +1,7 @@
 package store_util
 
 import (
+	"context"
 	"time"
 
 	"github.com/akmistry/cloud-util"
@@ -12,6 +13,10 @@ type DelayStore struct {
 }
 
 var _ = (cloud.UnorderedStore)((*DelayStore)(nil))
+var _ = (cloud.AtomicContextStore)((*DelayStore)(nil))
+var _ = (cloud.ContextBatchStore)((*DelayStore)(nil))
+var _ = (cloud.TxnStore)((*DelayStore)(nil))
+var _ = (cloud.CapabilitiesProvider)((*DelayStore)(nil))
 
 func NewDelayStore(s cloud.UnorderedStore, delay time.Duration) *DelayStore {
 	return &DelayStore{
@@ -20,6 +25,12 @@ func NewDelayStore(s cloud.UnorderedStore, delay time.Duration) *DelayStore {
 	}
 }
 
+func (s *DelayStore) Capabilities() cloud.Capabilities {
+	caps := cloud.GetCapabilities(s.s)
+	caps.Watch = false
+	return caps
+}
+
 func (s *DelayStore) Close() {
 	cloud.DoStoreClose(s.s)
 }
@@ -68,3 +79,109 @@ func (s *DelayStore) ListKeys(start string) ([]string, error) {
 	time.Sleep(s.delay)
 	return lister.ListKeys(start)
 }
+
+func (s *DelayStore) sleep(ctx context.Context) error {
+	t := time.NewTimer(s.delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *DelayStore) GetContext(ctx context.Context, key string) (*cloud.KVPair, error) {
+	if err := s.sleep(ctx); err != nil {
+		return nil, err
+	}
+	return cloud.AsContextStore(s.s).GetContext(ctx, key)
+}
+
+func (s *DelayStore) ExistsContext(ctx context.Context, key string) (bool, error) {
+	if err := s.sleep(ctx); err != nil {
+		return false, err
+	}
+	return cloud.AsContextStore(s.s).ExistsContext(ctx, key)
+}
+
+func (s *DelayStore) PutContext(ctx context.Context, key string, value []byte, options *cloud.WriteOptions) error {
+	if err := s.sleep(ctx); err != nil {
+		return err
+	}
+	return cloud.AsContextStore(s.s).PutContext(ctx, key, value, options)
+}
+
+func (s *DelayStore) DeleteContext(ctx context.Context, key string) error {
+	if err := s.sleep(ctx); err != nil {
+		return err
+	}
+	return cloud.AsContextStore(s.s).DeleteContext(ctx, key)
+}
+
+func (s *DelayStore) AtomicPutContext(ctx context.Context, key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+	if as, ok := s.s.(cloud.AtomicUnorderedStore); ok {
+		if err := s.sleep(ctx); err != nil {
+			return false, nil, err
+		}
+		return cloud.AsAtomicContextStore(as).AtomicPutContext(ctx, key, value, previous, options)
+	}
+	return false, nil, cloud.ErrCallNotSupported
+}
+
+func (s *DelayStore) AtomicDeleteContext(ctx context.Context, key string, previous *cloud.KVPair) (bool, error) {
+	if as, ok := s.s.(cloud.AtomicUnorderedStore); ok {
+		if err := s.sleep(ctx); err != nil {
+			return false, err
+		}
+		return cloud.AsAtomicContextStore(as).AtomicDeleteContext(ctx, key, previous)
+	}
+	return false, cloud.ErrCallNotSupported
+}
+
+func (s *DelayStore) Txn(txn *cloud.Txn) error {
+	return s.TxnContext(context.Background(), txn)
+}
+
+func (s *DelayStore) TxnContext(ctx context.Context, txn *cloud.Txn) error {
+	if _, ok := s.s.(cloud.TxnStore); !ok {
+		return cloud.ErrCallNotSupported
+	}
+	if err := s.sleep(ctx); err != nil {
+		return err
+	}
+	return cloud.TxnContext(ctx, s.s, txn)
+}
+
+func (s *DelayStore) ListKeysContext(ctx context.Context, start string) ([]string, error) {
+	lister, ok := s.s.(cloud.OrderedStore)
+	if !ok {
+		return nil, cloud.ErrCallNotSupported
+	}
+	if err := s.sleep(ctx); err != nil {
+		return nil, err
+	}
+	return cloud.ListKeysContext(ctx, lister, start)
+}
+
+// Batches are delayed once, and passed through to the underlying store.
+func (s *DelayStore) GetMultiContext(ctx context.Context, keys []string) ([]*cloud.KVPair, error) {
+	if err := s.sleep(ctx); err != nil {
+		return nil, err
+	}
+	return GetMultiContext(ctx, s.s, keys)
+}
+
+func (s *DelayStore) PutMultiContext(ctx context.Context, pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	if err := s.sleep(ctx); err != nil {
+		return err
+	}
+	return PutMultiContext(ctx, s.s, pairs, options)
+}
+
+func (s *DelayStore) DeleteMultiContext(ctx context.Context, keys []string) error {
+	if err := s.sleep(ctx); err != nil {
+		return err
+	}
+	return DeleteMultiContext(ctx, s.s, keys)
+}
@@ -1,14 +1,20 @@
 package store_util
 
 import (
+	"context"
+
 	"github.com/akmistry/cloud-util"
 )
 
 func IterateKeys(s cloud.OrderedStore, startKey string, f func(key string) bool) error {
+	return IterateKeysContext(context.Background(), s, startKey, f)
+}
+
+func IterateKeysContext(ctx context.Context, s cloud.OrderedStore, startKey string, f func(key string) bool) error {
 	skipStartKey := false
 	endLoop := false
 	for !endLoop {
-		keys, err := s.ListKeys(startKey)
+		keys, err := cloud.ListKeysContext(ctx, s, startKey)
 		if err != nil {
 			return err
 		} else if len(keys) == 0 {
@@ -1,6 +1,7 @@
 package store_util
 
 import (
+	"context"
 	"strings"
 
 	"github.com/akmistry/cloud-util"
@@ -12,6 +13,13 @@ type PrefixStore struct {
 }
 
 var _ = (cloud.UnorderedStore)((*PrefixStore)(nil))
+var _ = (cloud.AtomicContextStore)((*PrefixStore)(nil))
+var _ = (cloud.BatchStore)((*PrefixStore)(nil))
+var _ = (cloud.ContextBatchStore)((*PrefixStore)(nil))
+var _ = (cloud.Scanner)((*PrefixStore)(nil))
+var _ = (cloud.ContextScanner)((*PrefixStore)(nil))
+var _ = (cloud.TxnStore)((*PrefixStore)(nil))
+var _ = (cloud.CapabilitiesProvider)((*PrefixStore)(nil))
 
 func NewPrefixStore(s cloud.UnorderedStore, prefix string) *PrefixStore {
 	return &PrefixStore{
@@ -20,6 +28,15 @@ func NewPrefixStore(s cloud.UnorderedStore, prefix string) *PrefixStore {
 	}
 }
 
+func (s *PrefixStore) Capabilities() cloud.Capabilities {
+	caps := cloud.GetCapabilities(s.s)
+	caps.Watch = false
+	if caps.MaxKeySize > 0 {
+		caps.MaxKeySize = max(caps.MaxKeySize-len(s.prefix), 0)
+	}
+	return caps
+}
+
 func (s *PrefixStore) makeKey(key string) string {
 	return s.prefix + key
 }
@@ -55,13 +72,47 @@ func (s *PrefixStore) AtomicDelete(key string, previous *cloud.KVPair) (bool, er
 }
 
 func (s *PrefixStore) ListKeys(start string) ([]string, error) {
+	return s.ListKeysContext(context.Background(), start)
+}
+
+func (s *PrefixStore) GetContext(ctx context.Context, key string) (*cloud.KVPair, error) {
+	return cloud.AsContextStore(s.s).GetContext(ctx, s.makeKey(key))
+}
+
+func (s *PrefixStore) ExistsContext(ctx context.Context, key string) (bool, error) {
+	return cloud.AsContextStore(s.s).ExistsContext(ctx, s.makeKey(key))
+}
+
+func (s *PrefixStore) PutContext(ctx context.Context, key string, value []byte, options *cloud.WriteOptions) error {
+	return cloud.AsContextStore(s.s).PutContext(ctx, s.makeKey(key), value, options)
+}
+
+func (s *PrefixStore) DeleteContext(ctx context.Context, key string) error {
+	return cloud.AsContextStore(s.s).DeleteContext(ctx, s.makeKey(key))
+}
+
+func (s *PrefixStore) AtomicPutContext(ctx context.Context, key string, value []byte, previous *cloud.KVPair, options *cloud.WriteOptions) (bool, *cloud.KVPair, error) {
+	if as, ok := s.s.(cloud.AtomicUnorderedStore); ok {
+		return cloud.AsAtomicContextStore(as).AtomicPutContext(ctx, s.makeKey(key), value, previous, options)
+	}
+	return false, nil, cloud.ErrCallNotSupported
+}
+
+func (s *PrefixStore) AtomicDeleteContext(ctx context.Context, key string, previous *cloud.KVPair) (bool, error) {
+	if as, ok := s.s.(cloud.AtomicUnorderedStore); ok {
+		return cloud.AsAtomicContextStore(as).AtomicDeleteContext(ctx, s.makeKey(key), previous)
+	}
+	return false, cloud.ErrCallNotSupported
+}
+
+func (s *PrefixStore) ListKeysContext(ctx context.Context, start string) ([]string, error) {
 	lister, ok := s.s.(cloud.OrderedStore)
 	if !ok {
 		return nil, cloud.ErrCallNotSupported
 	}
 
 	startKey := s.makeKey(start)
-	keys, err := lister.ListKeys(startKey)
+	keys, err := cloud.ListKeysContext(ctx, lister, startKey)
 	if err != nil {
 		return nil, err
 	}
@@ -73,3 +124,92 @@ func (s *PrefixStore) ListKeys(start string) ([]string, error) {
 	}
 	return keys, nil
 }
+
+func (s *PrefixStore) Txn(txn *cloud.Txn) error {
+	return s.TxnContext(context.Background(), txn)
+}
+
+func (s *PrefixStore) TxnContext(ctx context.Context, txn *cloud.Txn) error {
+	innerTxn := &cloud.Txn{
+		Compares: make([]cloud.TxnCompare, len(txn.Compares)),
+		Ops:      make([]cloud.TxnOp, len(txn.Ops)),
+	}
+	for i, c := range txn.Compares {
+		innerTxn.Compares[i] = c
+		innerTxn.Compares[i].Key = s.makeKey(c.Key)
+	}
+	for i, op := range txn.Ops {
+		innerTxn.Ops[i] = op
+		innerTxn.Ops[i].Key = s.makeKey(op.Key)
+	}
+	return cloud.TxnContext(ctx, s.s, innerTxn)
+}
+
+func (s *PrefixStore) makeKeys(keys []string) []string {
+	innerKeys := make([]string, len(keys))
+	for i, k := range keys {
+		innerKeys[i] = s.makeKey(k)
+	}
+	return innerKeys
+}
+
+func (s *PrefixStore) GetMulti(keys []string) ([]*cloud.KVPair, error) {
+	return s.GetMultiContext(context.Background(), keys)
+}
+
+func (s *PrefixStore) GetMultiContext(ctx context.Context, keys []string) ([]*cloud.KVPair, error) {
+	pairs, err := GetMultiContext(ctx, s.s, s.makeKeys(keys))
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pairs {
+		if p != nil {
+			p.Key = strings.TrimPrefix(p.Key, s.prefix)
+		}
+	}
+	return pairs, nil
+}
+
+func (s *PrefixStore) PutMulti(pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	return s.PutMultiContext(context.Background(), pairs, options)
+}
+
+func (s *PrefixStore) PutMultiContext(ctx context.Context, pairs []*cloud.KVPair, options *cloud.WriteOptions) error {
+	innerPairs := make([]*cloud.KVPair, len(pairs))
+	for i, p := range pairs {
+		innerPairs[i] = &cloud.KVPair{Key: s.makeKey(p.Key), Value: p.Value}
+	}
+	return PutMultiContext(ctx, s.s, innerPairs, options)
+}
+
+func (s *PrefixStore) DeleteMulti(keys []string) error {
+	return s.DeleteMultiContext(context.Background(), keys)
+}
+
+func (s *PrefixStore) DeleteMultiContext(ctx context.Context, keys []string) error {
+	return DeleteMultiContext(ctx, s.s, s.makeKeys(keys))
+}
+
+func (s *PrefixStore) Scan(start, end string, limit int, options *cloud.ScanOptions) ([]*cloud.KVPair, error) {
+	return s.ScanContext(context.Background(), start, end, limit, options)
+}
+
+func (s *PrefixStore) ScanContext(ctx context.Context, start, end string, limit int, options *cloud.ScanOptions) ([]*cloud.KVPair, error) {
+	ordered, ok := s.s.(cloud.OrderedStore)
+	if !ok {
+		return nil, cloud.ErrCallNotSupported
+	}
+
+	innerEnd := cloud.PrefixEnd(s.prefix)
+	if end != "" {
+		innerEnd = s.makeKey(end)
+	}
+	pairs, err := ScanContext(ctx, ordered, s.makeKey(start), innerEnd, limit, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pairs {
+		p.Key = strings.TrimPrefix(p.Key, s.prefix)
+	}
+	return pairs, nil
+}
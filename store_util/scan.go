@@ -0,0 +1,74 @@
+package store_util
+
+import (
+	"context"
+	"slices"
+
+	"github.com/akmistry/cloud-util"
+)
+
+// Scan uses s.Scan if s implements cloud.Scanner. Otherwise, the scan is
+// emulated using ListKeys and GetMulti. Emulated reverse scans need to list
+// every key in the range, and keys deleted between being listed and fetched
+// are omitted from the result.
+func Scan(s cloud.OrderedStore, start, end string, limit int, options *cloud.ScanOptions) ([]*cloud.KVPair, error) {
+	return ScanContext(context.Background(), s, start, end, limit, options)
+}
+
+// ScanContext is the context-aware variant of Scan. If s implements
+// cloud.Scanner but not cloud.ContextScanner, the context is only checked
+// before the scan is started.
+func ScanContext(ctx context.Context, s cloud.OrderedStore, start, end string, limit int, options *cloud.ScanOptions) ([]*cloud.KVPair, error) {
+	if scanner, ok := s.(cloud.ContextScanner); ok {
+		return scanner.ScanContext(ctx, start, end, limit, options)
+	} else if scanner, ok := s.(cloud.Scanner); ok {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return scanner.Scan(start, end, limit, options)
+	}
+
+	var opts cloud.ScanOptions
+	if options != nil {
+		opts = *options
+	}
+
+	var keys []string
+	err := IterateKeysContext(ctx, s, start, func(key string) bool {
+		if end != "" && key >= end {
+			return false
+		}
+		keys = append(keys, key)
+		return opts.Reverse || limit <= 0 || len(keys) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Reverse {
+		if limit > 0 && len(keys) > limit {
+			keys = keys[len(keys)-limit:]
+		}
+		slices.Reverse(keys)
+	}
+
+	if opts.KeysOnly {
+		pairs := make([]*cloud.KVPair, len(keys))
+		for i, k := range keys {
+			pairs[i] = &cloud.KVPair{Key: k}
+		}
+		return pairs, nil
+	}
+
+	fetched, err := GetMultiContext(ctx, s, keys)
+	if err != nil {
+		return nil, err
+	}
+	pairs := fetched[:0]
+	for _, p := range fetched {
+		if p != nil {
+			pairs = append(pairs, p)
+		}
+	}
+	return pairs, nil
+}
@@ -0,0 +1,33 @@
+package store_util
+
+import (
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
+	"github.com/akmistry/cloud-util/test_util"
+)
+
+// Forces use of the emulated scan, since DelayStore does not implement
+// cloud.Scanner.
+type fallbackScanStore struct {
+	cloud.OrderedStore
+}
+
+func (s *fallbackScanStore) Scan(start, end string, limit int, options *cloud.ScanOptions) ([]*cloud.KVPair, error) {
+	return Scan(s.OrderedStore, start, end, limit, options)
+}
+
+func TestScan_Fallback(t *testing.T) {
+	s := &fallbackScanStore{NewDelayStore(local.NewInMemoryStore(), 0)}
+	test_util.TestScan(t, s)
+}
+
+func TestScan_PrefixStore(t *testing.T) {
+	inner := local.NewInMemoryStore()
+	// Keys outside the prefix must not be returned.
+	inner.Put("a", []byte("a"), nil)
+	inner.Put("z", []byte("z"), nil)
+
+	test_util.TestScan(t, NewPrefixStore(inner, "prefix-"))
+}
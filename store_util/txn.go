@@ -0,0 +1,89 @@
+package store_util
+
+import (
+	"context"
+
+	"github.com/akmistry/cloud-util"
+)
+
+const (
+	// Maximum number of attempts made by ReadModifyWrite.
+	MaxReadModifyWriteTries = 16
+)
+
+// Called by ReadModifyWrite with the current pairs for the keys being read,
+// in the same order. Pairs for keys which do not exist are nil. Returns the
+// ops to commit, which may be empty.
+type ModifyFunc func(pairs []*cloud.KVPair) ([]cloud.TxnOp, error)
+
+// ReadModifyWrite reads keys from s, and commits the ops returned by fn in a
+// transaction which requires none of the keys to have been modified since
+// they were read. If any were, the keys are re-read and fn called again, up
+// to MaxReadModifyWriteTries times, after which cloud.ErrKeyModified is
+// returned. Errors returned by fn are returned as-is.
+//
+// If s does not implement cloud.TxnStore, but is atomic, a single key may be
+// read and modified using AtomicPut or AtomicDelete.
+func ReadModifyWrite(ctx context.Context, s cloud.UnorderedStore, keys []string, fn ModifyFunc) error {
+	cs := cloud.AsContextStore(s)
+	for i := 0; i < MaxReadModifyWriteTries; i++ {
+		pairs := make([]*cloud.KVPair, len(keys))
+		for j, k := range keys {
+			p, err := cs.GetContext(ctx, k)
+			if err == cloud.ErrKeyNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+			pairs[j] = p
+		}
+
+		ops, err := fn(pairs)
+		if err != nil {
+			return err
+		} else if len(ops) == 0 {
+			return nil
+		}
+
+		txn := &cloud.Txn{Ops: ops}
+		for j, k := range keys {
+			txn.Compare(k, pairs[j])
+		}
+		err = commitTxn(ctx, s, txn)
+		if err != cloud.ErrKeyModified {
+			return err
+		}
+	}
+	return cloud.ErrKeyModified
+}
+
+// Commits txn using s.TxnContext, or AtomicPut/AtomicDelete if txn only
+// compares and modifies a single key. Capabilities are checked rather than
+// interfaces, since wrappers implement TxnStore even if the wrapped store
+// doesn't support transactions.
+func commitTxn(ctx context.Context, s cloud.UnorderedStore, txn *cloud.Txn) error {
+	caps := cloud.GetCapabilities(s)
+	if caps.Txn {
+		return cloud.TxnContext(ctx, s, txn)
+	}
+
+	as, ok := s.(cloud.AtomicUnorderedStore)
+	if !ok || !caps.Atomic || len(txn.Compares) != 1 || len(txn.Ops) != 1 || txn.Compares[0].Key != txn.Ops[0].Key {
+		return cloud.ErrCallNotSupported
+	}
+	previous := txn.Compares[0].Previous
+	op := txn.Ops[0]
+	acs := cloud.AsAtomicContextStore(as)
+
+	var err error
+	if !op.Delete {
+		_, _, err = acs.AtomicPutContext(ctx, op.Key, op.Value, previous, op.Options)
+	} else if previous != nil {
+		_, err = acs.AtomicDeleteContext(ctx, op.Key, previous)
+	}
+	// Map the single-key errors onto the TxnStore error.
+	if err == cloud.ErrKeyNotFound || err == cloud.ErrKeyExists {
+		err = cloud.ErrKeyModified
+	}
+	return err
+}
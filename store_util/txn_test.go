@@ -0,0 +1,114 @@
+package store_util
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
+)
+
+// Hides the TxnStore implementation of the wrapped store.
+type atomicOnlyStore struct {
+	cloud.AtomicUnorderedStore
+}
+
+// Concurrently increments a counter, which must not lose any increments.
+func incrementHelper(t *testing.T, s cloud.UnorderedStore) {
+	const (
+		workers    = 8
+		increments = 20
+	)
+
+	increment := func(pairs []*cloud.KVPair) ([]cloud.TxnOp, error) {
+		count := 0
+		if pairs[0] != nil {
+			var err error
+			count, err = strconv.Atoi(string(pairs[0].Value))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return []cloud.TxnOp{
+			{Key: "counter", Value: []byte(strconv.Itoa(count + 1))},
+		}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				err := ReadModifyWrite(context.Background(), s, []string{"counter"}, increment)
+				if err != nil {
+					t.Errorf("ReadModifyWrite error = %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	p, err := s.Get("counter")
+	if err != nil {
+		t.Fatalf("Get(counter) error = %v", err)
+	} else if string(p.Value) != strconv.Itoa(workers*increments) {
+		t.Errorf("counter %s != expected %d", p.Value, workers*increments)
+	}
+}
+
+func TestReadModifyWrite(t *testing.T) {
+	incrementHelper(t, local.NewInMemoryStore())
+	incrementHelper(t, atomicOnlyStore{local.NewInMemoryStore()})
+
+	// Wrappers fall back to AtomicPut if the wrapped store has no Txn.
+	incrementHelper(t, NewPrefixStore(atomicOnlyStore{local.NewInMemoryStore()}, "prefix/"))
+	incrementHelper(t, NewDelayStore(atomicOnlyStore{local.NewInMemoryStore()}, 0))
+}
+
+func TestReadModifyWrite_MultiKey(t *testing.T) {
+	ctx := context.Background()
+	s := local.NewInMemoryStore()
+	s.Put("record", []byte("r1"), nil)
+
+	// Move the record, and update its index.
+	err := ReadModifyWrite(ctx, s, []string{"record", "index"}, func(pairs []*cloud.KVPair) ([]cloud.TxnOp, error) {
+		if pairs[0] == nil || pairs[1] != nil {
+			t.Errorf("Unexpected pairs %v", pairs)
+		}
+		return []cloud.TxnOp{
+			{Key: "record", Delete: true},
+			{Key: "moved", Value: pairs[0].Value},
+			{Key: "index", Value: []byte("moved")},
+		}, nil
+	})
+	if err != nil {
+		t.Fatalf("ReadModifyWrite error = %v", err)
+	}
+	if ok, _ := s.Exists("record"); ok {
+		t.Errorf("record exists")
+	}
+	if p, err := s.Get("index"); err != nil || string(p.Value) != "moved" {
+		t.Errorf("Get(index) = %v, %v", p, err)
+	}
+
+	// Multiple keys can't be modified atomically without a TxnStore.
+	err = ReadModifyWrite(ctx, atomicOnlyStore{s}, []string{"moved", "index"}, func([]*cloud.KVPair) ([]cloud.TxnOp, error) {
+		return []cloud.TxnOp{{Key: "moved", Delete: true}, {Key: "index", Delete: true}}, nil
+	})
+	if err != cloud.ErrCallNotSupported {
+		t.Errorf("ReadModifyWrite error %v != expected cloud.ErrCallNotSupported", err)
+	}
+
+	fnErr := errors.New("fn error")
+	err = ReadModifyWrite(ctx, s, []string{"index"}, func([]*cloud.KVPair) ([]cloud.TxnOp, error) {
+		return nil, fnErr
+	})
+	if err != fnErr {
+		t.Errorf("ReadModifyWrite error %v != expected %v", err, fnErr)
+	}
+}
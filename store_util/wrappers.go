@@ -0,0 +1,33 @@
+package store_util
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/akmistry/cloud-util"
+)
+
+func init() {
+	cloud.RegisterStoreWrapper("prefix", wrapPrefixStore)
+	cloud.RegisterStoreWrapper("delay", wrapDelayStore)
+}
+
+// Path format: prefix(<prefix>)|<inner store>
+func wrapPrefixStore(args []string, s cloud.UnorderedStore) (cloud.UnorderedStore, error) {
+	if len(args) != 1 || args[0] == "" {
+		return nil, fmt.Errorf("cloud/store_util: prefix wrapper expects a prefix")
+	}
+	return NewPrefixStore(s, args[0]), nil
+}
+
+// Path format: delay(<duration>)|<inner store>
+func wrapDelayStore(args []string, s cloud.UnorderedStore) (cloud.UnorderedStore, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("cloud/store_util: delay wrapper expects a duration")
+	}
+	delay, err := time.ParseDuration(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("cloud/store_util: invalid delay: %v", err)
+	}
+	return NewDelayStore(s, delay), nil
+}
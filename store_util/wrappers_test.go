@@ -0,0 +1,83 @@
+package store_util
+
+import (
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
+)
+
+func TestOpenWrappedStore(t *testing.T) {
+	inner := local.NewInMemoryStore()
+	cloud.RegisterStoreScheme("wrappertest", func(string) (cloud.UnorderedStore, error) {
+		return inner, nil
+	})
+
+	s, err := cloud.OpenUnorderedStore("prefix(outer-)|delay(1ms)|prefix(inner-)|wrappertest://")
+	if err != nil {
+		t.Fatalf("OpenUnorderedStore error = %v", err)
+	}
+	if _, ok := s.(*PrefixStore); !ok {
+		t.Errorf("Opened store %T, expected *PrefixStore", s)
+	}
+	err = s.Put("key", []byte("value"), nil)
+	if err != nil {
+		t.Fatalf("Put error = %v", err)
+	}
+	p, err := inner.Get("inner-outer-key")
+	if err != nil {
+		t.Errorf("inner.Get error = %v", err)
+	} else if string(p.Value) != "value" {
+		t.Errorf("inner.Get value %s != expected value", p.Value)
+	}
+
+	badPaths := map[string]error{
+		"prefix(a)wrappertest://":       cloud.ErrInvalidFormat,
+		"prefix(a|wrappertest://":       cloud.ErrInvalidFormat,
+		"(a)|wrappertest://":            cloud.ErrInvalidFormat,
+		"unknown(a)|wrappertest://":     cloud.ErrUnrecognisedWrapper,
+		"prefix(a)|unknown://":          cloud.ErrUnrecognisedScheme,
+		"prefix()|wrappertest://":       nil,
+		"delay(forever)|wrappertest://": nil,
+	}
+	for path, expectedErr := range badPaths {
+		_, err := cloud.OpenUnorderedStore(path)
+		if err == nil {
+			t.Errorf("OpenUnorderedStore(%s) succeeded", path)
+		} else if expectedErr != nil && err != expectedErr {
+			t.Errorf("OpenUnorderedStore(%s) error %v != expected %v", path, err, expectedErr)
+		}
+	}
+}
+
+// Implements the atomic methods, but not ListKeys.
+type unorderedStore struct {
+	cloud.AtomicUnorderedStore
+}
+
+func TestWrapperCapabilities(t *testing.T) {
+	caps := cloud.GetCapabilities(NewPrefixStore(local.NewInMemoryStore(), "p-"))
+	if !caps.Atomic || !caps.Ordered || !caps.Batch || caps.Watch {
+		t.Errorf("PrefixStore capabilities %+v, expected atomic, ordered and batch", caps)
+	}
+	caps = cloud.GetCapabilities(NewDelayStore(unorderedStore{local.NewInMemoryStore()}, 0))
+	if !caps.Atomic || caps.Ordered || caps.Batch {
+		t.Errorf("DelayStore capabilities %+v, expected only atomic", caps)
+	}
+
+	cloud.RegisterStoreScheme("unorderedtest", func(string) (cloud.UnorderedStore, error) {
+		return unorderedStore{local.NewInMemoryStore()}, nil
+	})
+	cloud.RegisterStoreScheme("orderedtest", func(string) (cloud.UnorderedStore, error) {
+		return local.NewInMemoryStore(), nil
+	})
+	// PrefixStore implements AtomicOrderedStore, but the inner store doesn't.
+	_, err := cloud.OpenAtomicOrderedStore("prefix(p-)|unorderedtest://")
+	if err == nil {
+		t.Errorf("OpenAtomicOrderedStore(unordered) succeeded")
+	}
+	_, err = cloud.OpenAtomicOrderedStore("prefix(p-)|orderedtest://")
+	if err != nil {
+		t.Errorf("OpenAtomicOrderedStore error = %v", err)
+	}
+}
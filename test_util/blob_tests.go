@@ -0,0 +1,443 @@
+package test_util
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/akmistry/cloud-util"
+)
+
+func putBlob(t *testing.T, bs cloud.BlobStore, key string, data []byte, options *cloud.PutOptions) {
+	t.Helper()
+
+	w, err := cloud.PutWithOptionsContext(context.Background(), bs, key, options)
+	if err != nil {
+		t.Fatalf("Put(%s) error = %v", key, err)
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		w.Cancel()
+		t.Fatalf("Write(%s) error = %v", key, err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("Close(%s) error = %v", key, err)
+	}
+}
+
+func checkBlob(t *testing.T, bs cloud.BlobStore, key string, data []byte) {
+	t.Helper()
+
+	size, err := bs.Size(key)
+	if err != nil {
+		t.Errorf("Size(%s) error = %v", key, err)
+	} else if size != int64(len(data)) {
+		t.Errorf("Size(%s) %d != expected %d", key, size, len(data))
+	}
+
+	r, err := bs.Get(key)
+	if err != nil {
+		t.Errorf("Get(%s) error = %v", key, err)
+		return
+	}
+	defer r.Close()
+	buf, err := io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+	if err != nil {
+		t.Errorf("Read(%s) error = %v", key, err)
+	} else if !bytes.Equal(buf, data) {
+		t.Errorf("Read(%s) %q != expected %q", key, buf, data)
+	}
+}
+
+func TestBlobStore(t *testing.T, bs cloud.BlobStore) {
+	data := []byte("some blob data")
+	putBlob(t, bs, "blob", data, nil)
+	checkBlob(t, bs, "blob", data)
+
+	// Overwrite
+	data = []byte("new data")
+	putBlob(t, bs, "blob", data, nil)
+	checkBlob(t, bs, "blob", data)
+
+	err := bs.Delete("blob")
+	if err != nil {
+		t.Errorf("Delete(blob) error = %v", err)
+	}
+	_, err = bs.Size("blob")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Size(blob) error %v != expected os.ErrNotExist", err)
+	}
+	if kind := cloud.KindOf(err); kind != cloud.KindNotFound {
+		t.Errorf("KindOf(Size(blob) error) %v != expected KindNotFound", kind)
+	}
+	_, err = bs.Get("blob")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Get(blob) error %v != expected os.ErrNotExist", err)
+	}
+}
+
+// Tests that cancelled writes are discarded, and that cancelling after a
+// successful Close is a no-op.
+func TestBlobCancel(t *testing.T, bs cloud.BlobStore) {
+	w, err := bs.Put("cancel")
+	if err != nil {
+		t.Fatalf("Put(cancel) error = %v", err)
+	}
+	_, err = w.Write([]byte("cancelled"))
+	if err != nil {
+		t.Fatalf("Write(cancel) error = %v", err)
+	}
+	err = w.Cancel()
+	if err != nil {
+		t.Errorf("Cancel(cancel) error = %v", err)
+	}
+	_, err = bs.Size("cancel")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Size(cancel) error %v != expected os.ErrNotExist", err)
+	}
+
+	// Cancelling an overwrite leaves the existing blob.
+	data := []byte("some blob data")
+	putBlob(t, bs, "cancel", data, nil)
+	w, err = bs.Put("cancel")
+	if err != nil {
+		t.Fatalf("Put(cancel) error = %v", err)
+	}
+	_, err = w.Write([]byte("cancelled"))
+	if err != nil {
+		t.Fatalf("Write(cancel) error = %v", err)
+	}
+	err = w.Cancel()
+	if err != nil {
+		t.Errorf("Cancel(cancel) error = %v", err)
+	}
+	checkBlob(t, bs, "cancel", data)
+
+	data = []byte("new data")
+	w, err = bs.Put("cancel")
+	if err != nil {
+		t.Fatalf("Put(cancel) error = %v", err)
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		t.Fatalf("Write(cancel) error = %v", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("Close(cancel) error = %v", err)
+	}
+	err = w.Cancel()
+	if err != nil {
+		t.Errorf("Cancel(cancel) after Close error = %v", err)
+	}
+	checkBlob(t, bs, "cancel", data)
+
+	err = bs.Delete("cancel")
+	if err != nil {
+		t.Errorf("Delete(cancel) error = %v", err)
+	}
+}
+
+func TestBlobAttributes(t *testing.T, bs cloud.BlobStore) {
+	data := []byte("some blob data")
+	options := &cloud.PutOptions{
+		ContentType: "text/plain",
+		Metadata:    map[string]string{"owner": "test", "version": "1"},
+	}
+	putBlob(t, bs, "blob", data, options)
+	checkBlob(t, bs, "blob", data)
+
+	attrs, err := cloud.AttributesContext(context.Background(), bs, "blob")
+	if err != nil {
+		t.Fatalf("Attributes(blob) error = %v", err)
+	}
+	if attrs.Size != int64(len(data)) {
+		t.Errorf("Attributes(blob).Size %d != expected %d", attrs.Size, len(data))
+	}
+	if attrs.ContentType != options.ContentType {
+		t.Errorf("Attributes(blob).ContentType %q != expected %q", attrs.ContentType, options.ContentType)
+	}
+	if len(attrs.Metadata) != len(options.Metadata) {
+		t.Errorf("Attributes(blob).Metadata %v != expected %v", attrs.Metadata, options.Metadata)
+	}
+	for k, v := range options.Metadata {
+		if attrs.Metadata[k] != v {
+			t.Errorf("Attributes(blob).Metadata[%s] %q != expected %q", k, attrs.Metadata[k], v)
+		}
+	}
+	if attrs.MD5 != nil {
+		sum := md5.Sum(data)
+		if !bytes.Equal(attrs.MD5, sum[:]) {
+			t.Errorf("Attributes(blob).MD5 %x != expected %x", attrs.MD5, sum)
+		}
+	}
+
+	// Rewriting without options clears them.
+	putBlob(t, bs, "blob", []byte("new"), nil)
+	newAttrs, err := cloud.AttributesContext(context.Background(), bs, "blob")
+	if err != nil {
+		t.Fatalf("Attributes(blob) error = %v", err)
+	}
+	if newAttrs.Size != 3 {
+		t.Errorf("Attributes(blob).Size %d != expected 3", newAttrs.Size)
+	}
+	if len(newAttrs.Metadata) != 0 {
+		t.Errorf("Attributes(blob).Metadata %v != expected empty", newAttrs.Metadata)
+	}
+	if attrs.ETag != "" && attrs.ETag == newAttrs.ETag {
+		t.Errorf("Attributes(blob).ETag %s unchanged after rewrite", attrs.ETag)
+	}
+
+	err = bs.Delete("blob")
+	if err != nil {
+		t.Errorf("Delete(blob) error = %v", err)
+	}
+	_, err = cloud.AttributesContext(context.Background(), bs, "blob")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Attributes(blob) error %v != expected os.ErrNotExist", err)
+	}
+}
+
+func listKeys(t *testing.T, bs cloud.BlobStore, options *cloud.ListOptions) []string {
+	t.Helper()
+
+	var keys []string
+	it := cloud.NewListIterator(context.Background(), bs, options)
+	for {
+		e, err := it.Next()
+		if err == io.EOF {
+			return keys
+		} else if err != nil {
+			t.Fatalf("ListIterator.Next() error = %v", err)
+		}
+		key := e.Key
+		if e.IsDir {
+			key += "(dir)"
+		}
+		keys = append(keys, key)
+	}
+}
+
+func checkList(t *testing.T, bs cloud.BlobStore, options *cloud.ListOptions, expected ...string) {
+	t.Helper()
+
+	keys := listKeys(t, bs, options)
+	if !slices.Equal(keys, expected) {
+		t.Errorf("List(%+v) %v != expected %v", *options, keys, expected)
+	}
+}
+
+// Assumes the store is empty to start.
+func TestBlobList(t *testing.T, bs cloud.BlobStore) {
+	keys := []string{"a.1", "a.2", "a.b.3", "b", "c.1", "d"}
+	for _, key := range keys {
+		putBlob(t, bs, key, []byte(key), nil)
+	}
+
+	for _, pageSize := range []int{0, 1, 2, 100} {
+		checkList(t, bs, &cloud.ListOptions{PageSize: pageSize}, keys...)
+		checkList(t, bs, &cloud.ListOptions{PageSize: pageSize, Prefix: "a."},
+			"a.1", "a.2", "a.b.3")
+		checkList(t, bs, &cloud.ListOptions{PageSize: pageSize, StartAfter: "a.2"},
+			"a.b.3", "b", "c.1", "d")
+		checkList(t, bs, &cloud.ListOptions{PageSize: pageSize, Delimiter: "."},
+			"a.(dir)", "b", "c.(dir)", "d")
+		checkList(t, bs, &cloud.ListOptions{PageSize: pageSize, Prefix: "a.", Delimiter: "."},
+			"a.1", "a.2", "a.b.(dir)")
+		checkList(t, bs, &cloud.ListOptions{PageSize: pageSize, Prefix: "e"})
+	}
+
+	entries, _, err := cloud.ListPage(context.Background(), bs, &cloud.ListOptions{Prefix: "c."}, "")
+	if err != nil {
+		t.Fatalf("ListPage() error = %v", err)
+	} else if len(entries) != 1 {
+		t.Fatalf("ListPage() returned %d entries != expected 1", len(entries))
+	} else if entries[0].Size != 3 {
+		t.Errorf("ListPage() entry %s size %d != expected 3", entries[0].Key, entries[0].Size)
+	}
+}
+
+func putBlobErr(bs cloud.BlobStore, key string, data []byte, options *cloud.PutOptions) error {
+	w, err := cloud.PutWithOptionsContext(context.Background(), bs, key, options)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		w.Cancel()
+		return err
+	}
+	return w.Close()
+}
+
+func TestBlobPreconditions(t *testing.T, bs cloud.BlobStore) {
+	createOnly := &cloud.PutOptions{IfNotExist: true}
+	err := putBlobErr(bs, "blob", []byte("v1"), createOnly)
+	if err != nil {
+		t.Fatalf("Put(blob, IfNotExist) error = %v", err)
+	}
+	err = putBlobErr(bs, "blob", []byte("v2"), createOnly)
+	if err != cloud.ErrPreconditionFailed {
+		t.Errorf("Put(blob, IfNotExist) error %v != expected ErrPreconditionFailed", err)
+	}
+	checkBlob(t, bs, "blob", []byte("v1"))
+
+	attrs, err := cloud.AttributesContext(context.Background(), bs, "blob")
+	if err != nil {
+		t.Fatalf("Attributes(blob) error = %v", err)
+	} else if attrs.ETag == "" {
+		t.Fatalf("Attributes(blob).ETag empty")
+	}
+	err = putBlobErr(bs, "blob", []byte("v2"), &cloud.PutOptions{IfMatch: attrs.ETag})
+	if err != nil {
+		t.Fatalf("Put(blob, IfMatch) error = %v", err)
+	}
+	checkBlob(t, bs, "blob", []byte("v2"))
+
+	// ETag is now stale.
+	err = putBlobErr(bs, "blob", []byte("v3"), &cloud.PutOptions{IfMatch: attrs.ETag})
+	if err != cloud.ErrPreconditionFailed {
+		t.Errorf("Put(blob, IfMatch) error %v != expected ErrPreconditionFailed", err)
+	}
+	checkBlob(t, bs, "blob", []byte("v2"))
+
+	// Unconditional writes overwrite.
+	putBlob(t, bs, "blob", []byte("v4"), nil)
+	checkBlob(t, bs, "blob", []byte("v4"))
+
+	err = putBlobErr(bs, "missing", []byte("v1"), &cloud.PutOptions{IfMatch: attrs.ETag})
+	if err != cloud.ErrPreconditionFailed {
+		t.Errorf("Put(missing, IfMatch) error %v != expected ErrPreconditionFailed", err)
+	}
+	_, err = bs.Size("missing")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Size(missing) error %v != expected os.ErrNotExist", err)
+	}
+
+	err = bs.Delete("blob")
+	if err != nil {
+		t.Errorf("Delete(blob) error = %v", err)
+	}
+}
+
+func TestBlobCopy(t *testing.T, bs cloud.BlobStore) {
+	ctx := context.Background()
+	data := []byte("some blob data")
+	var options *cloud.PutOptions
+	if cloud.GetBlobCapabilities(bs).Attributes {
+		options = &cloud.PutOptions{
+			ContentType: "text/plain",
+			Metadata:    map[string]string{"owner": "test"},
+		}
+	}
+	putBlob(t, bs, "src", data, options)
+	// Copies overwrite.
+	putBlob(t, bs, "dst", []byte("old"), nil)
+
+	err := cloud.CopyContext(ctx, bs, "src", "dst")
+	if err != nil {
+		t.Fatalf("Copy(src, dst) error = %v", err)
+	}
+	checkBlob(t, bs, "src", data)
+	checkBlob(t, bs, "dst", data)
+	if options != nil {
+		attrs, err := cloud.AttributesContext(ctx, bs, "dst")
+		if err != nil {
+			t.Errorf("Attributes(dst) error = %v", err)
+		} else if attrs.ContentType != options.ContentType || attrs.Metadata["owner"] != "test" {
+			t.Errorf("Attributes(dst) %+v not copied from %+v", attrs, options)
+		}
+	}
+
+	err = cloud.RenameContext(ctx, bs, "src", "moved")
+	if err != nil {
+		t.Fatalf("Rename(src, moved) error = %v", err)
+	}
+	checkBlob(t, bs, "moved", data)
+	if options != nil {
+		attrs, err := cloud.AttributesContext(ctx, bs, "moved")
+		if err != nil {
+			t.Errorf("Attributes(moved) error = %v", err)
+		} else if attrs.ContentType != options.ContentType || attrs.Metadata["owner"] != "test" {
+			t.Errorf("Attributes(moved) %+v not moved from %+v", attrs, options)
+		}
+	}
+	_, err = bs.Size("src")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Size(src) error %v != expected os.ErrNotExist", err)
+	}
+
+	err = cloud.CopyContext(ctx, bs, "missing", "dst")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Copy(missing, dst) error %v != expected os.ErrNotExist", err)
+	}
+	err = cloud.RenameContext(ctx, bs, "missing", "dst")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Rename(missing, dst) error %v != expected os.ErrNotExist", err)
+	}
+	checkBlob(t, bs, "dst", data)
+
+	for _, key := range []string{"dst", "moved"} {
+		err = bs.Delete(key)
+		if err != nil {
+			t.Errorf("Delete(%s) error = %v", key, err)
+		}
+	}
+}
+
+// Data is mapped using cloud.MemMap, so bs needn't implement MemMapper.
+func TestBlobMemMap(t *testing.T, bs cloud.BlobStore) {
+	// Spans multiple pages and cache blocks.
+	data := make([]byte, 2<<20+4321)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	putBlob(t, bs, "mapped", data, nil)
+
+	r, err := bs.Get("mapped")
+	if err != nil {
+		t.Fatalf("Get(mapped) error = %v", err)
+	}
+	defer r.Close()
+
+	ranges := []struct {
+		offset int64
+		length int
+	}{
+		{0, len(data)},
+		{0, 10},
+		{4097, 100},
+		{1<<20 - 10, 20},
+		{1 << 20, 1 << 20},
+		{int64(len(data)) - 5, 5},
+		{int64(len(data)), 0},
+	}
+	for _, rg := range ranges {
+		buf, err := cloud.MemMap(r, rg.offset, rg.length)
+		if err != nil {
+			t.Errorf("MemMap(%d, %d) error = %v", rg.offset, rg.length, err)
+		} else if !bytes.Equal(buf, data[rg.offset:rg.offset+int64(rg.length)]) {
+			t.Errorf("MemMap(%d, %d) returned unexpected data", rg.offset, rg.length)
+		}
+	}
+
+	_, err = cloud.MemMap(r, int64(len(data))-5, 6)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("MemMap past end error %v != expected io.ErrUnexpectedEOF", err)
+	}
+	_, err = cloud.MemMap(r, -1, 1)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("MemMap(-1, 1) error %v != expected io.ErrUnexpectedEOF", err)
+	}
+
+	err = bs.Delete("mapped")
+	if err != nil {
+		t.Errorf("Delete(mapped) error = %v", err)
+	}
+}
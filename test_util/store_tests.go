@@ -2,10 +2,12 @@ package test_util
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math/rand"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/akmistry/cloud-util"
 )
@@ -134,3 +136,494 @@ func TestListKeys(t *testing.T, s cloud.OrderedStore) {
 		}
 	}
 }
+
+func checkScan(t *testing.T, s cloud.ScanStore, kv map[string][]byte, expectedKeys []string, start, end string, limit int, opts *cloud.ScanOptions) {
+	t.Helper()
+
+	if limit > 0 && len(expectedKeys) > limit {
+		expectedKeys = expectedKeys[:limit]
+	}
+
+	pairs, err := s.Scan(start, end, limit, opts)
+	if err != nil {
+		t.Errorf("Scan(%s, %s, %d, %+v) error = %v", start, end, limit, opts, err)
+		return
+	} else if len(pairs) != len(expectedKeys) {
+		t.Errorf("Scan(%s, %s, %d, %+v) len(pairs) %d != expected %d",
+			start, end, limit, opts, len(pairs), len(expectedKeys))
+		return
+	}
+
+	for i, p := range pairs {
+		if p.Key != expectedKeys[i] {
+			t.Errorf("key %s != expected %s", p.Key, expectedKeys[i])
+		}
+		if opts.KeysOnly {
+			if p.Value != nil {
+				t.Errorf("Scan(KeysOnly) returned value for key %s", p.Key)
+			}
+		} else if !bytes.Equal(p.Value, kv[p.Key]) {
+			t.Errorf("key %s value %v != expected %v", p.Key, p.Value, kv[p.Key])
+		}
+	}
+}
+
+func TestScan(t *testing.T, s cloud.ScanStore) {
+	const ScanIterations = 100
+
+	// Generate and insert items
+	sortedKeys, kv := PopulateTestItems(t, s, NumTestItems)
+
+	reversedKeys := make([]string, len(sortedKeys))
+	for i, k := range sortedKeys {
+		reversedKeys[len(sortedKeys)-1-i] = k
+	}
+
+	for _, opts := range []*cloud.ScanOptions{
+		{},
+		{KeysOnly: true},
+		{Reverse: true},
+		{Reverse: true, KeysOnly: true},
+	} {
+		expected := sortedKeys
+		if opts.Reverse {
+			expected = reversedKeys
+		}
+		checkScan(t, s, kv, expected, "", "", 0, opts)
+		checkScan(t, s, kv, expected, "", "", 10, opts)
+
+		for i := 0; i < ScanIterations; i++ {
+			startIndex := rand.Intn(len(sortedKeys))
+			endIndex := startIndex + rand.Intn(len(sortedKeys)-startIndex)
+			start := sortedKeys[startIndex]
+			end := sortedKeys[endIndex]
+			limit := rand.Intn(20)
+
+			expected := sortedKeys[startIndex:endIndex]
+			if opts.Reverse {
+				expected = reversedKeys[len(sortedKeys)-endIndex : len(sortedKeys)-startIndex]
+			}
+			checkScan(t, s, kv, expected, start, end, limit, opts)
+		}
+	}
+}
+
+func TestAtomicStore(t *testing.T, s cloud.AtomicUnorderedStore) {
+	const key = "atomic-key"
+
+	ok, created, err := s.AtomicPut(key, []byte("v1"), nil, nil)
+	if err != nil || !ok {
+		t.Fatalf("AtomicPut(%s, nil previous) = %v, %v", key, ok, err)
+	} else if created.LastIndex == 0 {
+		t.Errorf("AtomicPut(%s) LastIndex == 0", key)
+	}
+
+	_, _, err = s.AtomicPut(key, []byte("v1"), nil, nil)
+	if err != cloud.ErrKeyExists {
+		t.Errorf("AtomicPut(%s, nil previous) error %v != expected cloud.ErrKeyExists", key, err)
+	}
+	_, _, err = s.AtomicPut("missing-key", []byte("v1"), created, nil)
+	if err != cloud.ErrKeyNotFound {
+		t.Errorf("AtomicPut(missing-key) error %v != expected cloud.ErrKeyNotFound", err)
+	}
+
+	v1, err := s.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%s) error = %v", key, err)
+	} else if v1.LastIndex != created.LastIndex {
+		t.Errorf("Get(%s).LastIndex %d != AtomicPut LastIndex %d", key, v1.LastIndex, created.LastIndex)
+	}
+
+	ok, v2, err := s.AtomicPut(key, []byte("v2"), v1, nil)
+	if err != nil || !ok {
+		t.Fatalf("AtomicPut(%s) = %v, %v", key, ok, err)
+	} else if v2.LastIndex <= v1.LastIndex {
+		t.Errorf("AtomicPut(%s) LastIndex %d <= previous %d", key, v2.LastIndex, v1.LastIndex)
+	}
+
+	// Rewrite the original value. The version check must still detect the
+	// intermediate modification.
+	ok, v3, err := s.AtomicPut(key, []byte("v1"), v2, nil)
+	if err != nil || !ok {
+		t.Fatalf("AtomicPut(%s) = %v, %v", key, ok, err)
+	}
+	_, _, err = s.AtomicPut(key, []byte("v4"), v1, nil)
+	if err != cloud.ErrKeyModified {
+		t.Errorf("AtomicPut(%s, stale previous) error %v != expected cloud.ErrKeyModified", key, err)
+	}
+	_, err = s.AtomicDelete(key, v1)
+	if err != cloud.ErrKeyModified {
+		t.Errorf("AtomicDelete(%s, stale previous) error %v != expected cloud.ErrKeyModified", key, err)
+	}
+
+	// Without a version, values are compared.
+	_, _, err = s.AtomicPut(key, []byte("v4"), &cloud.KVPair{Key: key, Value: []byte("v2")}, nil)
+	if err != cloud.ErrKeyModified {
+		t.Errorf("AtomicPut(%s, wrong value) error %v != expected cloud.ErrKeyModified", key, err)
+	}
+	ok, v4, err := s.AtomicPut(key, []byte("v4"), &cloud.KVPair{Key: key, Value: []byte("v1")}, nil)
+	if err != nil || !ok {
+		t.Fatalf("AtomicPut(%s, value previous) = %v, %v", key, ok, err)
+	} else if v4.LastIndex <= v3.LastIndex {
+		t.Errorf("AtomicPut(%s) LastIndex %d <= previous %d", key, v4.LastIndex, v3.LastIndex)
+	}
+
+	ok, err = s.AtomicDelete(key, v4)
+	if err != nil || !ok {
+		t.Fatalf("AtomicDelete(%s) = %v, %v", key, ok, err)
+	}
+	checkNotExists(t, s, key)
+	_, err = s.AtomicDelete(key, v4)
+	if err != cloud.ErrKeyNotFound {
+		t.Errorf("AtomicDelete(%s, deleted) error %v != expected cloud.ErrKeyNotFound", key, err)
+	}
+}
+
+// Tests multi-key transactions. s must implement cloud.TxnStore.
+func TestTxn(t *testing.T, s cloud.UnorderedStore) {
+	ctx := context.Background()
+
+	// Create an index and its record together.
+	txn := new(cloud.Txn).
+		Compare("record", nil).
+		Compare("index", nil).
+		Put("record", []byte("r1"), nil).
+		Put("index", []byte("record"), nil)
+	err := cloud.TxnContext(ctx, s, txn)
+	if err != nil {
+		t.Fatalf("Txn(create) error = %v", err)
+	}
+	checkExists(t, s, "record", []byte("r1"))
+	checkExists(t, s, "index", []byte("record"))
+
+	// A failed comparison on one key must prevent writes to all keys.
+	err = cloud.TxnContext(ctx, s, txn)
+	if err != cloud.ErrKeyModified {
+		t.Errorf("Txn(create existing) error %v != expected cloud.ErrKeyModified", err)
+	}
+	record, err := s.Get("record")
+	if err != nil {
+		t.Fatalf("Get(record) error = %v", err)
+	}
+	stale := &cloud.KVPair{Key: "index", Value: []byte("other")}
+	txn = new(cloud.Txn).
+		Compare("record", record).
+		Compare("index", stale).
+		Put("record", []byte("r2"), nil).
+		Delete("index")
+	err = cloud.TxnContext(ctx, s, txn)
+	if err != cloud.ErrKeyModified {
+		t.Errorf("Txn(stale index) error %v != expected cloud.ErrKeyModified", err)
+	}
+	checkExists(t, s, "record", []byte("r1"))
+	checkExists(t, s, "index", []byte("record"))
+
+	// Comparisons don't need a matching op, and ops don't need a matching
+	// comparison.
+	index, err := s.Get("index")
+	if err != nil {
+		t.Fatalf("Get(index) error = %v", err)
+	}
+	txn = new(cloud.Txn).
+		Compare("index", index).
+		Put("record", []byte("r2"), nil).
+		Delete("missing")
+	err = cloud.TxnContext(ctx, s, txn)
+	if err != nil {
+		t.Fatalf("Txn(update) error = %v", err)
+	}
+	checkExists(t, s, "record", []byte("r2"))
+
+	// Versions are updated by a transaction.
+	txn = new(cloud.Txn).
+		Compare("record", record).
+		Delete("record")
+	err = cloud.TxnContext(ctx, s, txn)
+	if err != cloud.ErrKeyModified {
+		t.Errorf("Txn(stale record) error %v != expected cloud.ErrKeyModified", err)
+	}
+	record, err = s.Get("record")
+	if err != nil {
+		t.Fatalf("Get(record) error = %v", err)
+	}
+	txn = new(cloud.Txn).
+		Compare("record", record).
+		Compare("index", index).
+		Delete("record").
+		Delete("index")
+	err = cloud.TxnContext(ctx, s, txn)
+	if err != nil {
+		t.Fatalf("Txn(delete) error = %v", err)
+	}
+	checkNotExists(t, s, "record")
+	checkNotExists(t, s, "index")
+
+	txn = new(cloud.Txn).
+		Put("a", []byte("1"), nil).
+		Delete("a")
+	err = cloud.TxnContext(ctx, s, txn)
+	if err == nil {
+		t.Errorf("Txn(duplicate op) returned nil error")
+	}
+}
+
+// Tests expiry of keys written with a TTL. Also tests listing, scanning and
+// atomic creation over expired keys, if supported by s.
+func TestTTL(t *testing.T, s cloud.UnorderedStore) {
+	const ttl = 100 * time.Millisecond
+	opts := &cloud.WriteOptions{TTL: ttl}
+
+	err := s.Put("ttl-expired", []byte("expired"), opts)
+	if err != nil {
+		t.Fatalf("Put(ttl-expired) error = %v", err)
+	}
+	err = s.Put("ttl-cleared", []byte("cleared"), opts)
+	if err != nil {
+		t.Fatalf("Put(ttl-cleared) error = %v", err)
+	}
+	// Overwriting without a TTL removes the expiry.
+	err = s.Put("ttl-cleared", []byte("cleared"), nil)
+	if err != nil {
+		t.Fatalf("Put(ttl-cleared) error = %v", err)
+	}
+	err = s.Put("ttl-none", []byte("none"), &cloud.WriteOptions{})
+	if err != nil {
+		t.Fatalf("Put(ttl-none) error = %v", err)
+	}
+	checkExists(t, s, "ttl-expired", []byte("expired"))
+
+	time.Sleep(2 * ttl)
+
+	checkNotExists(t, s, "ttl-expired")
+	checkExists(t, s, "ttl-cleared", []byte("cleared"))
+	checkExists(t, s, "ttl-none", []byte("none"))
+
+	if os, ok := s.(cloud.OrderedStore); ok {
+		keys, err := os.ListKeys("")
+		if err != nil {
+			t.Errorf("ListKeys error = %v", err)
+		} else if len(keys) != 2 || keys[0] != "ttl-cleared" || keys[1] != "ttl-none" {
+			t.Errorf("ListKeys() = %v, expected [ttl-cleared ttl-none]", keys)
+		}
+	}
+	if ss, ok := s.(cloud.ScanStore); ok {
+		pairs, err := ss.Scan("", "", 0, nil)
+		if err != nil {
+			t.Errorf("Scan error = %v", err)
+		} else if len(pairs) != 2 || pairs[0].Key != "ttl-cleared" || pairs[1].Key != "ttl-none" {
+			t.Errorf("Scan() returned %d pairs, expected [ttl-cleared ttl-none]", len(pairs))
+		}
+	}
+
+	if as, ok := s.(cloud.AtomicUnorderedStore); ok {
+		ok, _, err := as.AtomicPut("ttl-atomic", []byte("atomic"), nil, opts)
+		if err != nil || !ok {
+			t.Fatalf("AtomicPut(ttl-atomic) = %v, %v", ok, err)
+		}
+		time.Sleep(2 * ttl)
+		checkNotExists(t, s, "ttl-atomic")
+
+		// Creating an expired key succeeds.
+		ok, _, err = as.AtomicPut("ttl-atomic", []byte("atomic"), nil, nil)
+		if err != nil || !ok {
+			t.Fatalf("AtomicPut(ttl-atomic) over expired key = %v, %v", ok, err)
+		}
+		checkExists(t, s, "ttl-atomic", []byte("atomic"))
+	}
+}
+
+const watchTimeout = 5 * time.Second
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	select {
+	case v, ok := <-ch:
+		if !ok {
+			t.Fatalf("Watch channel closed")
+		}
+		return v
+	case <-time.After(watchTimeout):
+		t.Fatalf("Timed out waiting for watch")
+	}
+	var zero T
+	return zero
+}
+
+func waitClosed(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	case <-time.After(watchTimeout):
+		t.Fatalf("Timed out waiting for channel close")
+	}
+}
+
+// Receives from ch until a tree with the expected keys is sent.
+func receiveTree(t *testing.T, ch <-chan []*cloud.KVPair, expectedKeys ...string) {
+	t.Helper()
+
+	for {
+		pairs := receive(t, ch)
+		var keys []string
+		for _, p := range pairs {
+			keys = append(keys, p.Key)
+		}
+		if fmt.Sprint(keys) == fmt.Sprint(expectedKeys) {
+			return
+		}
+	}
+}
+
+// Tests cloud.Watch and cloud.WatchTree, using the store's native watch
+// support if available.
+func TestWatch(t *testing.T, s cloud.UnorderedStore) {
+	err := s.Put("watch/a", []byte("1"), nil)
+	if err != nil {
+		t.Fatalf("Put error = %v", err)
+	}
+
+	stopCh := make(chan struct{})
+	keyCh, err := cloud.Watch(s, "watch/a", stopCh)
+	if err != nil {
+		t.Fatalf("Watch error = %v", err)
+	}
+	treeCh, err := cloud.WatchTree(s, "watch/", stopCh)
+	if err != nil {
+		t.Fatalf("WatchTree error = %v", err)
+	}
+
+	p := receive(t, keyCh)
+	if p.Key != "watch/a" || string(p.Value) != "1" {
+		t.Errorf("Watch pair %s = %s, expected watch/a = 1", p.Key, p.Value)
+	}
+	receiveTree(t, treeCh, "watch/a")
+
+	err = s.Put("watch/a", []byte("2"), nil)
+	if err != nil {
+		t.Fatalf("Put error = %v", err)
+	}
+	p = receive(t, keyCh)
+	if string(p.Value) != "2" {
+		t.Errorf("Watch value %s != expected 2", p.Value)
+	}
+
+	// Keys outside the directory don't affect the tree.
+	err = s.Put("watcher", []byte("x"), nil)
+	if err != nil {
+		t.Fatalf("Put error = %v", err)
+	}
+	err = s.Put("watch/b", []byte("1"), nil)
+	if err != nil {
+		t.Fatalf("Put error = %v", err)
+	}
+	receiveTree(t, treeCh, "watch/a", "watch/b")
+
+	err = s.Delete("watch/a")
+	if err != nil {
+		t.Fatalf("Delete error = %v", err)
+	}
+	receiveTree(t, treeCh, "watch/b")
+
+	close(stopCh)
+	for range keyCh {
+	}
+	for range treeCh {
+	}
+}
+
+// Tests cloud.StoreLock, using s to store the lock.
+func TestLock(t *testing.T, s cloud.AtomicUnorderedStore) {
+	const ttl = 100 * time.Millisecond
+	opts := &cloud.LockOptions{Value: []byte("value"), TTL: ttl}
+
+	l1 := cloud.NewLock(s, "lock", opts)
+	err := l1.Unlock()
+	if err != cloud.ErrLockNotHeld {
+		t.Errorf("Unlock() error %v != expected cloud.ErrLockNotHeld", err)
+	}
+
+	lost1, err := l1.Lock(nil)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	token1 := l1.Token()
+	if token1 == 0 {
+		t.Errorf("Token() == 0 while lock held")
+	}
+
+	// The lock is held past its TTL, since it is renewed.
+	l2 := cloud.NewLock(s, "lock", opts)
+	stopCh := make(chan struct{})
+	time.AfterFunc(3*ttl, func() { close(stopCh) })
+	_, err = l2.Lock(stopCh)
+	if err != cloud.ErrAbortTryLock {
+		t.Errorf("Lock() error %v != expected cloud.ErrAbortTryLock", err)
+	}
+
+	err = l1.Unlock()
+	if err != nil {
+		t.Errorf("Unlock() error = %v", err)
+	}
+	waitClosed(t, lost1)
+	if l1.Token() != 0 {
+		t.Errorf("Token() = %d after Unlock", l1.Token())
+	}
+
+	lost2, err := l2.Lock(nil)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	token2 := l2.Token()
+	if token2 <= token1 {
+		t.Errorf("Token() %d <= previous token %d", token2, token1)
+	}
+
+	// Overwriting the lock causes it to be lost.
+	err = s.Put("lock", []byte("stolen"), nil)
+	if err != nil {
+		t.Fatalf("Put error = %v", err)
+	}
+	waitClosed(t, lost2)
+	err = l2.Unlock()
+	if err != cloud.ErrLockNotHeld {
+		t.Errorf("Unlock() error %v != expected cloud.ErrLockNotHeld", err)
+	}
+	s.Delete("lock")
+
+	// Stop renewing, and have another locker take over the expired lease.
+	renewCh := make(chan struct{})
+	l3 := cloud.NewLock(s, "lock", &cloud.LockOptions{TTL: ttl, RenewLock: renewCh})
+	lost3, err := l3.Lock(nil)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	close(renewCh)
+	waitClosed(t, lost3)
+	if l3.Token() != 0 {
+		t.Errorf("Token() = %d after lock lost", l3.Token())
+	}
+	err = l3.Unlock()
+	if err != cloud.ErrLockNotHeld {
+		t.Errorf("Unlock() error %v != expected cloud.ErrLockNotHeld", err)
+	}
+
+	l4 := cloud.NewLock(s, "lock", opts)
+	_, err = l4.Lock(nil)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	_, err = l4.Lock(nil)
+	if err != cloud.ErrLockHeld {
+		t.Errorf("Lock() while held error %v != expected cloud.ErrLockHeld", err)
+	}
+	err = l3.Unlock()
+	if err != cloud.ErrLockNotHeld {
+		t.Errorf("Unlock() error %v != expected cloud.ErrLockNotHeld", err)
+	}
+	err = l4.Unlock()
+	if err != nil {
+		t.Errorf("Unlock() error = %v", err)
+	}
+}
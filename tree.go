@@ -0,0 +1,105 @@
+package cloud
+
+import (
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// Maximum number of concurrent requests used to fetch or delete the keys
+	// of a tree, on stores without native batching.
+	maxTreeWorkers = 16
+)
+
+// Returns all keys beginning with prefix, in key order.
+func listPrefixKeys(s OrderedStore, prefix string) ([]string, error) {
+	var keys []string
+	start := prefix
+	skipStart := false
+	for {
+		listed, err := s.ListKeys(start)
+		if err != nil {
+			return nil, err
+		}
+		if skipStart && len(listed) > 0 && listed[0] == start {
+			listed = listed[1:]
+		}
+		if len(listed) == 0 {
+			return keys, nil
+		}
+
+		for _, k := range listed {
+			if !strings.HasPrefix(k, prefix) {
+				return keys, nil
+			}
+			keys = append(keys, k)
+		}
+		start = listed[len(listed)-1]
+		skipStart = true
+	}
+}
+
+// Returns all pairs with keys beginning with prefix, in key order.
+func listPrefix(s OrderedStore, prefix string) ([]*KVPair, error) {
+	if scanner, ok := s.(Scanner); ok {
+		return scanner.Scan(prefix, PrefixEnd(prefix), 0, nil)
+	}
+
+	keys, err := listPrefixKeys(s, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var fetched []*KVPair
+	if bs, ok := s.(BatchStore); ok {
+		fetched, err = bs.GetMulti(keys)
+	} else {
+		fetched = make([]*KVPair, len(keys))
+		var g errgroup.Group
+		g.SetLimit(maxTreeWorkers)
+		for i, k := range keys {
+			g.Go(func() error {
+				p, err := s.Get(k)
+				if err == ErrKeyNotFound {
+					return nil
+				}
+				fetched[i] = p
+				return err
+			})
+		}
+		err = g.Wait()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys deleted since being listed are omitted.
+	pairs := fetched[:0]
+	for _, p := range fetched {
+		if p != nil {
+			pairs = append(pairs, p)
+		}
+	}
+	return pairs, nil
+}
+
+// Deletes all keys beginning with prefix.
+func deletePrefix(s OrderedStore, prefix string) error {
+	keys, err := listPrefixKeys(s, prefix)
+	if err != nil {
+		return err
+	}
+	if bs, ok := s.(BatchStore); ok {
+		return bs.DeleteMulti(keys)
+	}
+
+	var g errgroup.Group
+	g.SetLimit(maxTreeWorkers)
+	for _, k := range keys {
+		g.Go(func() error {
+			return s.Delete(k)
+		})
+	}
+	return g.Wait()
+}
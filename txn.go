@@ -0,0 +1,87 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+)
+
+// Condition on the state of a key, checked when a Txn is committed.
+type TxnCompare struct {
+	Key string
+	// If nil, the key must not exist. Otherwise, the key must exist and match
+	// Previous, as for AtomicPut.
+	Previous *KVPair
+}
+
+// Write applied when a Txn is committed.
+type TxnOp struct {
+	Key   string
+	Value []byte
+	// Delete the key, rather than putting Value. Deleting a key which does not
+	// exist is not an error.
+	Delete  bool
+	Options *WriteOptions
+}
+
+// A set of writes which are applied all-or-nothing, if all comparisons
+// succeed. A key may appear in at most one comparison, and at most one op.
+type Txn struct {
+	Compares []TxnCompare
+	Ops      []TxnOp
+}
+
+// Compare adds a comparison of key against previous.
+func (t *Txn) Compare(key string, previous *KVPair) *Txn {
+	t.Compares = append(t.Compares, TxnCompare{Key: key, Previous: previous})
+	return t
+}
+
+// Put adds a put of key.
+func (t *Txn) Put(key string, value []byte, options *WriteOptions) *Txn {
+	t.Ops = append(t.Ops, TxnOp{Key: key, Value: value, Options: options})
+	return t
+}
+
+// Delete adds a delete of key.
+func (t *Txn) Delete(key string) *Txn {
+	t.Ops = append(t.Ops, TxnOp{Key: key, Delete: true})
+	return t
+}
+
+// Validate returns an error if a key appears in more than one comparison, or
+// more than one op.
+func (t *Txn) Validate() error {
+	compareKeys := make(map[string]bool, len(t.Compares))
+	for _, c := range t.Compares {
+		if compareKeys[c.Key] {
+			return fmt.Errorf("cloud: duplicate txn comparison of key %s", c.Key)
+		}
+		compareKeys[c.Key] = true
+	}
+	opKeys := make(map[string]bool, len(t.Ops))
+	for _, op := range t.Ops {
+		if opKeys[op.Key] {
+			return fmt.Errorf("cloud: duplicate txn op on key %s", op.Key)
+		}
+		opKeys[op.Key] = true
+	}
+	return nil
+}
+
+// Optionally implemented by stores which support multi-key transactions.
+// Txn applies all of txn.Ops if every comparison in txn.Compares succeeds.
+// Otherwise, no ops are applied and ErrKeyModified is returned.
+type TxnStore interface {
+	Txn(txn *Txn) error
+	TxnContext(ctx context.Context, txn *Txn) error
+}
+
+// TxnContext calls s.TxnContext if s implements TxnStore. Otherwise,
+// ErrCallNotSupported is returned.
+func TxnContext(ctx context.Context, s UnorderedStore, txn *Txn) error {
+	ts, ok := s.(TxnStore)
+	if !ok {
+		return ErrCallNotSupported
+	}
+	return ts.TxnContext(ctx, txn)
+}
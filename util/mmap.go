@@ -0,0 +1,52 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// Read-only memory mappings of files, which are unmapped together. Safe for
+// concurrent use.
+type Mappings struct {
+	lock  sync.Mutex
+	mmaps [][]byte
+}
+
+// Map returns length bytes of f starting at offset, which needn't be page
+// aligned. The range must be within the file. The returned slice is valid
+// until Unmap is called, even if f is closed.
+func (m *Mappings) Map(f *os.File, offset int64, length int) ([]byte, error) {
+	if length == 0 {
+		return []byte{}, nil
+	}
+
+	// Mappings must start on a page boundary.
+	pageOff := offset % int64(os.Getpagesize())
+	mmap, err := unix.Mmap(int(f.Fd()), offset-pageOff, int(pageOff)+length, unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	m.mmaps = append(m.mmaps, mmap)
+	m.lock.Unlock()
+	return mmap[pageOff:], nil
+}
+
+// Unmap unmaps all mappings. Slices returned by Map must not be used
+// afterwards.
+func (m *Mappings) Unmap() error {
+	m.lock.Lock()
+	mmaps := m.mmaps
+	m.mmaps = nil
+	m.lock.Unlock()
+
+	var errs []error
+	for _, mmap := range mmaps {
+		errs = append(errs, unix.Munmap(mmap))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,133 @@
+package util
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Temp files which are unlocked and haven't been modified for this long are
+	// assumed to have been leaked by a crashed writer.
+	DefaultTempFileMaxAge = time.Hour
+)
+
+// CreateTempFile is like os.CreateTemp, except that the file is locked with an
+// advisory lock until it is closed. While locked, the file is never removed by
+// RemoveStaleTempFiles, even if it is shared with other processes.
+func CreateTempFile(dir, pattern string) (*os.File, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	err = unix.Flock(int(f.Fd()), unix.LOCK_EX)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// LockFile opens the existing file at path, and locks it as for CreateTempFile
+// until it is closed. Since the lock is held on the file rather than the path,
+// it also protects temp links to the file.
+func LockFile(path string) (*os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	err = unix.Flock(int(f.Fd()), unix.LOCK_EX)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Summary of temp files removed by RemoveStaleTempFiles.
+type TempFileStats struct {
+	Files int
+	Bytes int64
+}
+
+// RemoveStaleTempFiles removes files in dir whose name starts with prefix, which
+// haven't been modified for at least maxAge and aren't locked by a writer
+// created with CreateTempFile. Files which can't be removed are logged and
+// skipped.
+func RemoveStaleTempFiles(dir, prefix string, maxAge time.Duration) (TempFileStats, error) {
+	var stats TempFileStats
+	dirents, err := os.ReadDir(dir)
+	if err != nil {
+		return stats, err
+	}
+	now := time.Now()
+	for _, d := range dirents {
+		if d.IsDir() || !strings.HasPrefix(d.Name(), prefix) {
+			continue
+		}
+		path := filepath.Join(dir, d.Name())
+		size, err := removeIfStale(path, now.Add(-maxAge))
+		if err != nil {
+			log.Printf("cloud/util: error removing temp file %s: %v", path, err)
+			continue
+		} else if size >= 0 {
+			stats.Files++
+			stats.Bytes += size
+		}
+	}
+	return stats, nil
+}
+
+// Returns the size of the removed file, or -1 if the file wasn't removed.
+func removeIfStale(path string, cutoff time.Time) (int64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Renamed or removed by the writer.
+		return -1, nil
+	} else if err != nil {
+		return -1, err
+	}
+	// Closing the file releases the lock.
+	defer f.Close()
+
+	err = unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		// In use.
+		return -1, nil
+	} else if err != nil {
+		return -1, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return -1, err
+	} else if fi.ModTime().After(cutoff) {
+		return -1, nil
+	}
+
+	// The writer may have renamed the file into place, and released its lock,
+	// between opening and locking the file. Only remove path if it still
+	// refers to the locked file.
+	pathFi, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return -1, nil
+	} else if err != nil {
+		return -1, err
+	} else if !os.SameFile(fi, pathFi) {
+		return -1, nil
+	}
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return -1, nil
+	} else if err != nil {
+		return -1, err
+	}
+	return fi.Size(), nil
+}
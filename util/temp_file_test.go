@@ -0,0 +1,98 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRemoveStaleTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * time.Hour)
+
+	makeTemp := func(data string) *os.File {
+		f, err := CreateTempFile(dir, "temp-*")
+		if err != nil {
+			t.Fatalf("CreateTempFile error = %v", err)
+		}
+		f.Write([]byte(data))
+		if err := os.Chtimes(f.Name(), old, old); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	inUse := makeTemp("in use")
+	defer inUse.Close()
+	leaked := makeTemp("leaked")
+	leaked.Close()
+	recent, err := CreateTempFile(dir, "temp-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recent.Close()
+	// Files without the prefix are never removed.
+	if err := os.WriteFile(dir+"/other", []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dir+"/other", old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := RemoveStaleTempFiles(dir, "temp-", time.Hour)
+	if err != nil {
+		t.Fatalf("RemoveStaleTempFiles error = %v", err)
+	} else if stats.Files != 1 || stats.Bytes != int64(len("leaked")) {
+		t.Errorf("RemoveStaleTempFiles = %+v, expected 1 file of %d bytes", stats, len("leaked"))
+	}
+
+	if _, err := os.Stat(leaked.Name()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat(leaked) error = %v", err)
+	}
+	for _, name := range []string{inUse.Name(), recent.Name(), dir + "/other"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("Stat(%s) error = %v", name, err)
+		}
+	}
+
+	// Once closed, the file is no longer locked.
+	inUse.Close()
+	stats, err = RemoveStaleTempFiles(dir, "temp-", time.Hour)
+	if err != nil || stats.Files != 1 {
+		t.Errorf("RemoveStaleTempFiles = %+v, %v", stats, err)
+	}
+}
+
+func TestLockFile(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.WriteFile(dir+"/blob", []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dir+"/blob", old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// Temp links to a locked file aren't removed.
+	f, err := LockFile(dir + "/blob")
+	if err != nil {
+		t.Fatalf("LockFile error = %v", err)
+	}
+	if err := os.Link(dir+"/blob", dir+"/temp-link"); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := RemoveStaleTempFiles(dir, "temp-", time.Hour)
+	if err != nil || stats.Files != 0 {
+		t.Errorf("RemoveStaleTempFiles = %+v, %v with file locked", stats, err)
+	}
+
+	f.Close()
+	stats, err = RemoveStaleTempFiles(dir, "temp-", time.Hour)
+	if err != nil || stats.Files != 1 {
+		t.Errorf("RemoveStaleTempFiles = %+v, %v after unlock", stats, err)
+	}
+	if _, err := os.Stat(dir + "/blob"); err != nil {
+		t.Errorf("Linked file removed: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package cloud
+
+import (
+	"bytes"
+	"time"
+)
+
+// Interval at which stores which don't implement Watcher are polled by Watch
+// and WatchTree.
+var WatchPollInterval = time.Second
+
+// Optionally implemented by stores which can notify watchers of changes.
+// Watches run until stopCh is closed, after which the returned channel is
+// closed. Changes made in quick succession may be coalesced, and only the
+// latest state sent.
+type Watcher interface {
+	// Sends the pair for key when the watch starts, if the key exists, and
+	// again after every change to the key. Deleting the key is not reported.
+	Watch(key string, stopCh <-chan struct{}) (<-chan *KVPair, error)
+	// Sends all pairs with keys beginning with directory when the watch
+	// starts, and again after every change to those keys, including deletes.
+	WatchTree(directory string, stopCh <-chan struct{}) (<-chan []*KVPair, error)
+}
+
+// Watch uses s.Watch if s implements Watcher, and its capabilities include
+// Watch. Otherwise, key is polled every WatchPollInterval.
+func Watch(s UnorderedStore, key string, stopCh <-chan struct{}) (<-chan *KVPair, error) {
+	if w, ok := s.(Watcher); ok && GetCapabilities(s).Watch {
+		return w.Watch(key, stopCh)
+	}
+
+	read := func() (*KVPair, error) {
+		p, err := s.Get(key)
+		if err == ErrKeyNotFound {
+			return nil, nil
+		}
+		return p, err
+	}
+	skip := func(p *KVPair) bool { return p == nil }
+	return pollChanges(stopCh, read, samePair, skip)
+}
+
+// WatchTree is the tree equivalent of Watch. Polling lists the tree every
+// WatchPollInterval, which requires s to be an OrderedStore.
+func WatchTree(s UnorderedStore, directory string, stopCh <-chan struct{}) (<-chan []*KVPair, error) {
+	if w, ok := s.(Watcher); ok && GetCapabilities(s).Watch {
+		return w.WatchTree(directory, stopCh)
+	}
+	os, ok := s.(OrderedStore)
+	if !ok {
+		return nil, ErrCallNotSupported
+	}
+
+	read := func() ([]*KVPair, error) {
+		return listPrefix(os, directory)
+	}
+	equal := func(a, b []*KVPair) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !samePair(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	skip := func([]*KVPair) bool { return false }
+	return pollChanges(stopCh, read, equal, skip)
+}
+
+func samePair(a, b *KVPair) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Key == b.Key && a.LastIndex == b.LastIndex && bytes.Equal(a.Value, b.Value)
+}
+
+// Calls read every WatchPollInterval, and sends the result whenever it
+// differs from the previous result, unless skip returns true. The first read
+// is done synchronously, so that errors can be returned to the caller.
+func pollChanges[T any](stopCh <-chan struct{}, read func() (T, error), equal func(a, b T) bool, skip func(T) bool) (<-chan T, error) {
+	last, err := read()
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan T, 1)
+	if !skip(last) {
+		ch <- last
+	}
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(WatchPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+			}
+
+			v, err := read()
+			if err != nil || equal(last, v) {
+				// Errors are assumed to be transient, and the read retried on the
+				// next poll.
+				continue
+			}
+			last = v
+			if skip(v) {
+				continue
+			}
+			select {
+			case ch <- v:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// PrefixEnd returns the smallest key greater than every key beginning with
+// prefix, or "" if there is no such key.
+func PrefixEnd(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package cloud_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/akmistry/cloud-util"
+	"github.com/akmistry/cloud-util/local"
+	"github.com/akmistry/cloud-util/test_util"
+)
+
+// Hides the optional interfaces (i.e. Watcher, Scanner and BatchStore) of
+// the wrapped store.
+type plainStore struct {
+	cloud.OrderedStore
+}
+
+func TestWatch_Polling(t *testing.T) {
+	oldInterval := cloud.WatchPollInterval
+	cloud.WatchPollInterval = 10 * time.Millisecond
+	defer func() { cloud.WatchPollInterval = oldInterval }()
+
+	test_util.TestWatch(t, plainStore{local.NewInMemoryStore()})
+}